@@ -17,6 +17,8 @@ import (
 // Max size of the point cache.
 const maxPointCacheSize = 10_000
 
+var _ gpbft.BatchAggregate = (*aggregation)(nil)
+
 type aggregation struct {
 	mask   *bdn.Mask
 	scheme *bdn.Scheme
@@ -102,6 +104,35 @@ func (a *aggregation) VerifyAggregate(mask []int, msg []byte, signature []byte)
 	return a.scheme.Verify(aggPubKey, msg, signature)
 }
 
+// AggregatePublicKey returns the BDN aggregate of the public keys of the given signers, which
+// their aggregate signature is a plain BLS signature by.
+func (a *aggregation) AggregatePublicKey(mask []int) (_ gpbft.PubKey, _err error) {
+	defer func() {
+		if perr := recover(); perr != nil {
+			_err = fmt.Errorf("panicked aggregating public keys: %v\n%s",
+				perr, string(debug.Stack()))
+			log.Error(_err)
+		}
+	}()
+
+	bdnMask := a.mask.Clone()
+	for _, bit := range mask {
+		if err := bdnMask.SetBit(bit, true); err != nil {
+			return nil, err
+		}
+	}
+
+	aggPubKey, err := a.scheme.AggregatePublicKeys(bdnMask)
+	if err != nil {
+		return nil, fmt.Errorf("aggregating public keys: %w", err)
+	}
+	pubKey, err := aggPubKey.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("marshaling public key: %w", err)
+	}
+	return pubKey, nil
+}
+
 func (v *Verifier) Aggregate(pubkeys []gpbft.PubKey) (_agg gpbft.Aggregate, _err error) {
 	defer func() {
 		if perr := recover(); perr != nil {
@@ -18,6 +18,7 @@ var metrics = struct {
 	decompressPoint metric.Int64Counter
 	verify          metric.Int64Counter
 	verifyAggregate metric.Int64Histogram
+	verifyBatch     metric.Int64Histogram
 	aggregate       metric.Int64Histogram
 }{
 	decompressPoint: measurements.Must(meter.Int64Counter(
@@ -32,6 +33,10 @@ var metrics = struct {
 		"f3_blssig_verify_aggregate",
 		metric.WithDescription("Number of aggregate signatures verified."),
 	)),
+	verifyBatch: measurements.Must(meter.Int64Histogram(
+		"f3_blssig_verify_batch",
+		metric.WithDescription("Number of signatures verified in a batch."),
+	)),
 	aggregate: measurements.Must(meter.Int64Histogram(
 		"f3_blssig_aggregate",
 		metric.WithDescription("Number of signatures aggregated."),
@@ -3,6 +3,7 @@ package blssig
 import (
 	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"runtime/debug"
 	"sync"
@@ -16,9 +17,16 @@ import (
 	"github.com/filecoin-project/go-f3/internal/measurements"
 )
 
+var (
+	_ gpbft.Verifier      = (*Verifier)(nil)
+	_ gpbft.BatchVerifier = (*Verifier)(nil)
+)
+
 type Verifier struct {
+	suite    *bls12381.SuiteBLS12381
 	scheme   *bdn.Scheme
 	keyGroup kyber.Group
+	sigGroup kyber.Group
 
 	mu         sync.RWMutex
 	pointCache map[string]kyber.Point
@@ -27,8 +35,10 @@ type Verifier struct {
 func VerifierWithKeyOnG1() *Verifier {
 	suite := bls12381.NewSuiteBLS12381()
 	return &Verifier{
+		suite:    suite,
 		scheme:   bdn.NewSchemeOnG2(suite),
 		keyGroup: suite.G1(),
+		sigGroup: suite.G2(),
 	}
 }
 
@@ -102,3 +112,74 @@ func (v *Verifier) Verify(pubKey gpbft.PubKey, msg, sig []byte) (_err error) {
 
 	return v.scheme.Verify(point, msg, sig)
 }
+
+// VerifyBatch verifies the given signatures at once by checking a random linear combination of
+// them: for random scalars r_i, the batch is valid if
+//
+//	e(g1, Σ r_i·σ_i) == Π e(Σ_{j: m_j = m} r_j·pk_j, H(m))
+//
+// over the distinct messages m, which takes a single multi-pairing with one pairing per distinct
+// message. An invalid signature makes the check fail with overwhelming probability, without
+// telling which one it is.
+func (v *Verifier) VerifyBatch(pubKeys []gpbft.PubKey, msgs [][]byte, sigs [][]byte) (_err error) {
+	defer func() {
+		status := measurements.AttrStatusSuccess
+		if _err != nil {
+			status = measurements.AttrStatusError
+		}
+		if perr := recover(); perr != nil {
+			_err = fmt.Errorf("panicked validating batch of %d signatures: %v\n%s",
+				len(sigs), perr, string(debug.Stack()))
+			log.Error(_err)
+			status = measurements.AttrStatusPanic
+		}
+		metrics.verifyBatch.Record(context.TODO(), int64(len(sigs)), metric.WithAttributes(status))
+	}()
+
+	switch {
+	case len(pubKeys) != len(sigs) || len(msgs) != len(sigs):
+		return fmt.Errorf("lengths of pubkeys, messages and sigs do not match %d, %d != %d",
+			len(pubKeys), len(msgs), len(sigs))
+	case len(sigs) == 0:
+		return nil
+	case len(sigs) == 1:
+		return v.Verify(pubKeys[0], msgs[0], sigs[0])
+	}
+
+	random := v.suite.RandomStream()
+	aggSig := v.sigGroup.Point().Null()
+	keysByMsg := make(map[string]kyber.Point)
+	for i := range sigs {
+		pubKey, err := v.pubkeyToPoint(pubKeys[i])
+		if err != nil {
+			return fmt.Errorf("unmarshalling public key %d: %w", i, err)
+		}
+		sig := v.sigGroup.Point()
+		if err := sig.UnmarshalBinary(sigs[i]); err != nil {
+			return fmt.Errorf("unmarshalling signature %d: %w", i, err)
+		}
+		r := v.sigGroup.Scalar().Pick(random)
+		aggSig = aggSig.Add(aggSig, v.sigGroup.Point().Mul(r, sig))
+		pubKey = v.keyGroup.Point().Mul(r, pubKey)
+		if key, found := keysByMsg[string(msgs[i])]; found {
+			keysByMsg[string(msgs[i])] = key.Add(key, pubKey)
+		} else {
+			keysByMsg[string(msgs[i])] = pubKey
+		}
+	}
+
+	g1s := make([]kyber.Point, 0, len(keysByMsg)+1)
+	g2s := make([]kyber.Point, 0, len(keysByMsg)+1)
+	for msg, key := range keysByMsg {
+		hashed := v.sigGroup.Point().(kyber.HashablePoint).Hash([]byte(msg))
+		g1s = append(g1s, key)
+		g2s = append(g2s, hashed)
+	}
+	base := v.keyGroup.Point().Base()
+	g1s = append(g1s, base.Neg(base))
+	g2s = append(g2s, aggSig)
+	if !v.suite.ValidatePairings(g1s, g2s) {
+		return errors.New("invalid batch of signatures")
+	}
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"go.dedis.ch/kyber/v4/sign/bdn"
 
+	"github.com/filecoin-project/go-f3/gpbft"
 	bls12381 "github.com/filecoin-project/go-f3/internal/gnark"
 )
 
@@ -31,3 +32,31 @@ func BenchmarkBLSSigning(b *testing.B) {
 		require.NoError(b, err)
 	}
 }
+
+func BenchmarkBLSVerifyBatch(b *testing.B) {
+	var (
+		blsSuit   = bls12381.NewSuiteBLS12381()
+		blsSchema = bdn.NewSchemeOnG2(blsSuit)
+		ctx       = context.Background()
+		verifier  = VerifierWithKeyOnG1()
+	)
+	const batchSize = 64
+	pubKeys := make([]gpbft.PubKey, batchSize)
+	msgs := make([][]byte, batchSize)
+	sigs := make([][]byte, batchSize)
+	for i := range sigs {
+		privKey, pubKey := blsSchema.NewKeyPair(blsSuit.RandomStream())
+		pubKeyB, err := pubKey.MarshalBinary()
+		require.NoError(b, err)
+		pubKeys[i] = pubKeyB
+		msgs[i] = []byte{byte(i % 4)}
+		sigs[i], err = SignerWithKeyOnG1(pubKeyB, privKey).Sign(ctx, pubKeyB, msgs[i])
+		require.NoError(b, err)
+	}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		err := verifier.VerifyBatch(pubKeys, msgs, sigs)
+		require.NoError(b, err)
+	}
+}
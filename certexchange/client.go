@@ -16,6 +16,8 @@ import (
 	cid "github.com/ipfs/go-cid"
 	"github.com/libp2p/go-libp2p/core/host"
 	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	cbg "github.com/whyrusleeping/cbor-gen"
 	"go.opentelemetry.io/otel/metric"
 )
 
@@ -25,6 +27,13 @@ import (
 // 2. <100 bytes per entry (key + id + power)
 const maxPowerTableSize = 1024 * 1024
 
+// Requester requests finality certificates from certificate exchange peers. It is implemented by
+// Client, over libp2p streams, and by HTTPClient, over HTTP.
+type Requester interface {
+	Request(ctx context.Context, p peer.ID, req *Request) (*ResponseHeader, <-chan *certs.FinalityCertificate, error)
+	RequestV2(ctx context.Context, p peer.ID, req *RequestV2) (*ResponseHeaderV2, <-chan *certs.FinalityCertificate, error)
+}
+
 // Client is a libp2p certificate exchange client for requesting finality certificates from specific
 // peers.
 type Client struct {
@@ -42,7 +51,69 @@ func (c *Client) withDeadline(ctx context.Context) (context.Context, context.Can
 
 // Request finality certificates from the specified peer. Returned finality certificates start at
 // the requested instance number and are sequential, but are otherwise unvalidated.
-func (c *Client) Request(ctx context.Context, p peer.ID, req *Request) (_rh *ResponseHeader, _ch <-chan *certs.FinalityCertificate, _err error) {
+func (c *Client) Request(ctx context.Context, p peer.ID, req *Request) (*ResponseHeader, <-chan *certs.FinalityCertificate, error) {
+	var resp ResponseHeader
+	ch, err := c.request(ctx, p, FetchProtocolName(c.NetworkName), req, req.FirstInstance, req.Limit, func(br *io.LimitedReader) error {
+		if req.IncludePowerTable {
+			br.N = maxPowerTableSize
+		}
+		return resp.UnmarshalCBOR(br)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, ch, nil
+}
+
+// RequestV2 requests finality certificates from the specified peer, along with the power table
+// checkpoints and the status of the response, using the protocol named by FetchProtocolNameV2.
+// Returned finality certificates start at the requested instance number and are sequential, and
+// returned checkpoints are at the requested interval, but both are otherwise unvalidated.
+func (c *Client) RequestV2(ctx context.Context, p peer.ID, req *RequestV2) (*ResponseHeaderV2, <-chan *certs.FinalityCertificate, error) {
+	var resp ResponseHeaderV2
+	ch, err := c.request(ctx, p, FetchProtocolNameV2(c.NetworkName), req, req.FirstInstance, req.Limit, func(br *io.LimitedReader) error {
+		if req.IncludePowerTable || req.PowerTableCheckpointInterval > 0 {
+			br.N = maxPowerTableSize
+		}
+		if err := resp.UnmarshalCBOR(br); err != nil {
+			return err
+		}
+		return checkPowerTableCheckpoints(req, resp.PowerTableCheckpoints)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, ch, nil
+}
+
+// checkPowerTableCheckpoints sanity checks that the given checkpoints are at the requested
+// interval, and within the requested range.
+func checkPowerTableCheckpoints(req *RequestV2, checkpoints []PowerTableCheckpoint) error {
+	if req.PowerTableCheckpointInterval == 0 {
+		if len(checkpoints) > 0 {
+			return errors.New("received unrequested power table checkpoints")
+		}
+		return nil
+	}
+	for i, checkpoint := range checkpoints {
+		expected := req.FirstInstance + uint64(i+1)*req.PowerTableCheckpointInterval
+		if checkpoint.Instance != expected {
+			return fmt.Errorf("received power table checkpoint at instance %d, expected %d", checkpoint.Instance, expected)
+		}
+		if checkpoint.Instance-req.FirstInstance >= req.Limit {
+			return fmt.Errorf("received power table checkpoint at instance %d beyond the requested limit", checkpoint.Instance)
+		}
+		if !checkpoint.PowerTable.Defined() {
+			return fmt.Errorf("received undefined power table checkpoint at instance %d", checkpoint.Instance)
+		}
+	}
+	return nil
+}
+
+// request sends the given request over the given protocol, reads the response header with the
+// given function, and streams the finality certificates that follow it.
+func (c *Client) request(ctx context.Context, p peer.ID, proto protocol.ID, req cbg.CBORMarshaler, firstInstance, limit uint64,
+	readHeader func(*io.LimitedReader) error) (_ch <-chan *certs.FinalityCertificate, _err error) {
 	defer func() {
 		if perr := recover(); perr != nil {
 			_err = fmt.Errorf("panicked requesting certificates from peer %s: %v\n%s", p, perr, string(debug.Stack()))
@@ -62,13 +133,13 @@ func (c *Client) Request(ctx context.Context, p peer.ID, req *Request) (_rh *Res
 		metrics.requestLatency.Record(ctx, time.Since(requestStart).Seconds(), metric.WithAttributes(
 			measurements.Status(ctx, _err),
 			measurements.AttrDialSucceeded.Bool(dialSucceeded),
+			attrTransport.String(transportLibp2p),
 		))
 	}(time.Now())
 
-	proto := FetchProtocolName(c.NetworkName)
 	stream, err := c.Host.NewStream(ctx, p, proto)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 	dialSucceeded = true
 
@@ -87,13 +158,13 @@ func (c *Client) Request(ctx context.Context, p peer.ID, req *Request) (_rh *Res
 
 	if err := req.MarshalCBOR(bw); err != nil {
 		log.Debugw("failed to marshal certificate exchange request to peer", "peer", p, "error", err)
-		return nil, nil, err
+		return nil, err
 	}
 	if err := bw.Flush(); err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 	if err := stream.CloseWrite(); err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
 	responseStart := time.Now()
@@ -101,23 +172,19 @@ func (c *Client) Request(ctx context.Context, p peer.ID, req *Request) (_rh *Res
 		if cancel != nil {
 			metrics.totalResponseTime.Record(ctx, time.Since(responseStart).Seconds(), metric.WithAttributes(
 				measurements.Status(ctx, _err),
+				attrTransport.String(transportLibp2p),
 			))
 		}
 	}()
 
-	var resp ResponseHeader
-	if req.IncludePowerTable {
-		br.N = maxPowerTableSize
-	}
-	err = resp.UnmarshalCBOR(br)
-	if err != nil {
+	if err := readHeader(br); err != nil {
 		log.Debugw("failed to unmarshal certificate exchange response header from peer", "peer", p, "error", err)
-		return nil, nil, err
+		return nil, err
 	}
 
 	// If we aren't expecting any certificates, return immediately. We may _only_ want the power
 	// table.
-	if req.Limit == 0 {
+	if limit == 0 {
 		// Reset immediately instead of waiting for it to get run async (better cleanup
 		// behavior).
 		if unbindReset() {
@@ -126,12 +193,10 @@ func (c *Client) Request(ctx context.Context, p peer.ID, req *Request) (_rh *Res
 
 		ch := make(chan *certs.FinalityCertificate)
 		close(ch)
-		return &resp, ch, nil
+		return ch, nil
 	}
 
 	ch := make(chan *certs.FinalityCertificate, 1)
-	// copy this in case the caller decides to re-use the request object...
-	request := *req
 
 	// Copy/replace the cancel func so exiting the request doesn't cancel it.
 	cancelReq := cancel
@@ -145,6 +210,7 @@ func (c *Client) Request(ctx context.Context, p peer.ID, req *Request) (_rh *Res
 
 			metrics.totalResponseTime.Record(ctx, time.Since(responseStart).Seconds(), metric.WithAttributes(
 				measurements.Status(ctx, _err),
+				attrTransport.String(transportLibp2p),
 			))
 
 			// Reset immediately instead of waiting for it to get run async (better cleanup
@@ -156,7 +222,7 @@ func (c *Client) Request(ctx context.Context, p peer.ID, req *Request) (_rh *Res
 			cancelReq()
 			close(ch)
 		}()
-		for i := uint64(0); i < request.Limit; i++ {
+		for i := uint64(0); i < limit; i++ {
 			cert := new(certs.FinalityCertificate)
 
 			// We'll read at most 1MiB per certificate. They generally shouldn't be that
@@ -172,7 +238,7 @@ func (c *Client) Request(ctx context.Context, p peer.ID, req *Request) (_rh *Res
 				return err
 			}
 			// One quick sanity check. The rest will be validated by the caller.
-			if cert.GPBFTInstance != request.FirstInstance+i {
+			if cert.GPBFTInstance != firstInstance+i {
 				log.Warnw("received out-of-order certificate from peer", "peer", p)
 				return errors.New("out of order certificates received")
 			}
@@ -185,9 +251,229 @@ func (c *Client) Request(ctx context.Context, p peer.ID, req *Request) (_rh *Res
 		}
 		return nil
 	}() //nolint:errcheck
-	return &resp, ch, nil
+	return ch, nil
+}
+
+// The maximum number of times a snapshot download is resumed after being interrupted.
+const maxSnapshotResumes = 5
+
+// RequestSnapshot requests a snapshot from the specified peer, streamed from the requested byte
+// offset onwards. The returned snapshot bytes are unvalidated, and the returned reader must be
+// closed by the caller. The client RequestTimeout applies to receiving the response header only,
+// as snapshots can be large.
+func (c *Client) RequestSnapshot(ctx context.Context, p peer.ID, req *SnapshotRequest) (_rh *SnapshotResponseHeader, _rc io.ReadCloser, _err error) {
+	defer func() {
+		if perr := recover(); perr != nil {
+			_err = fmt.Errorf("panicked requesting snapshot from peer %s: %v\n%s", p, perr, string(debug.Stack()))
+			log.Error(_err)
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer func() {
+		if _err != nil {
+			cancel()
+		}
+	}()
+
+	stream, err := c.Host.NewStream(ctx, p, SnapshotProtocolName(c.NetworkName))
+	if err != nil {
+		return nil, nil, err
+	}
+	// Reset the stream if the context is canceled, or once the caller closes the snapshot.
+	unbindReset := context.AfterFunc(ctx, func() { _ = stream.Reset() })
+	defer func() {
+		if _err != nil && unbindReset() {
+			_ = stream.Reset()
+		}
+	}()
+
+	if c.RequestTimeout > 0 {
+		// Not all transports support deadlines.
+		_ = stream.SetDeadline(time.Now().Add(c.RequestTimeout))
+	}
+
+	bw := bufio.NewWriter(stream)
+	if err := req.MarshalCBOR(bw); err != nil {
+		log.Debugw("failed to marshal snapshot request to peer", "peer", p, "error", err)
+		return nil, nil, err
+	}
+	if err := bw.Flush(); err != nil {
+		return nil, nil, err
+	}
+	if err := stream.CloseWrite(); err != nil {
+		return nil, nil, err
+	}
+
+	br := bufio.NewReader(stream)
+	var resp SnapshotResponseHeader
+	if err := resp.UnmarshalCBOR(&io.LimitedReader{R: br, N: 100}); err != nil {
+		log.Debugw("failed to unmarshal snapshot response header from peer", "peer", p, "error", err)
+		return nil, nil, err
+	}
+	_ = stream.SetDeadline(time.Time{})
+
+	return &resp, &snapshotStream{Reader: br, close: func() {
+		if unbindReset() {
+			_ = stream.Reset()
+		}
+		cancel()
+	}}, nil
 }
 
+// ErrPowerTableMismatch is returned when a peer responds with a power table other than the one
+// requested.
+var ErrPowerTableMismatch = errors.New("peer returned mismatching power table")
+
+// RequestPowerTable requests a single power table from the specified peer, either by CID or by
+// instance. When requested by CID, the returned power table is checked against it. Otherwise, it
+// is unvalidated, and should be checked against the CID committed to by the finality certificate
+// named in the response.
+func (c *Client) RequestPowerTable(ctx context.Context, p peer.ID, req *PowerTableRequest) (_ *PowerTableResponse, _err error) {
+	defer func() {
+		if perr := recover(); perr != nil {
+			_err = fmt.Errorf("panicked requesting power table from peer %s: %v\n%s", p, perr, string(debug.Stack()))
+			log.Error(_err)
+		}
+	}()
+
+	ctx, cancel := c.withDeadline(ctx)
+	defer cancel()
+
+	stream, err := c.Host.NewStream(ctx, p, PowerTableProtocolName(c.NetworkName))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if _err != nil {
+			_ = stream.Reset()
+		} else {
+			_ = stream.Close()
+		}
+	}()
+	defer context.AfterFunc(ctx, func() { _ = stream.Reset() })()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		// Not all transports support deadlines.
+		_ = stream.SetDeadline(deadline)
+	}
+
+	bw := bufio.NewWriter(stream)
+	if err := req.MarshalCBOR(bw); err != nil {
+		log.Debugw("failed to marshal power table request to peer", "peer", p, "error", err)
+		return nil, err
+	}
+	if err := bw.Flush(); err != nil {
+		return nil, err
+	}
+	if err := stream.CloseWrite(); err != nil {
+		return nil, err
+	}
+
+	var resp PowerTableResponse
+	if err := resp.UnmarshalCBOR(&io.LimitedReader{R: bufio.NewReader(stream), N: maxPowerTableSize}); err != nil {
+		log.Debugw("failed to unmarshal power table response from peer", "peer", p, "error", err)
+		return nil, err
+	}
+	if resp.Status != StatusOK {
+		return &resp, nil
+	}
+	if req.PowerTable != nil {
+		ptCid, err := certs.MakePowerTableCID(resp.PowerTable)
+		if err != nil {
+			return nil, fmt.Errorf("computing power table CID: %w", err)
+		}
+		if ptCid != *req.PowerTable {
+			return nil, fmt.Errorf("%w: expected %s, got %s", ErrPowerTableMismatch, *req.PowerTable, ptCid)
+		}
+	} else if resp.Instance != req.Instance {
+		return nil, fmt.Errorf("%w: requested instance %d, got %d", ErrPowerTableMismatch, req.Instance, resp.Instance)
+	}
+	return &resp, nil
+}
+
+type snapshotStream struct {
+	io.Reader
+	close func()
+}
+
+func (s *snapshotStream) Close() error {
+	s.close()
+	return nil
+}
+
+// OpenSnapshot requests a snapshot up to the given latest instance from the specified peer, or up
+// to the latest instance the peer has if LatestSnapshot is given. If the download is interrupted,
+// it is transparently resumed from the last byte received. The returned snapshot bytes are
+// unvalidated, and the returned reader must be closed by the caller.
+func (c *Client) OpenSnapshot(ctx context.Context, p peer.ID, latestInstance uint64) (*SnapshotResponseHeader, io.ReadCloser, error) {
+	head, body, err := c.RequestSnapshot(ctx, p, &SnapshotRequest{LatestInstance: latestInstance})
+	if err != nil {
+		return nil, nil, err
+	}
+	return head, &resumingSnapshotReader{
+		ctx:    ctx,
+		client: c,
+		peer:   p,
+		head:   *head,
+		body:   body,
+	}, nil
+}
+
+type resumingSnapshotReader struct {
+	ctx     context.Context
+	client  *Client
+	peer    peer.ID
+	head    SnapshotResponseHeader
+	body    io.ReadCloser
+	offset  uint64
+	resumes int
+}
+
+func (r *resumingSnapshotReader) Read(p []byte) (int, error) {
+	if r.body == nil {
+		return 0, errors.New("snapshot download failed to resume")
+	}
+	for {
+		n, err := r.body.Read(p)
+		r.offset += uint64(n)
+		if err == nil || errors.Is(err, io.EOF) || r.ctx.Err() != nil || r.resumes >= maxSnapshotResumes {
+			return n, err
+		}
+
+		log.Debugw("resuming interrupted snapshot download", "peer", r.peer, "offset", r.offset, "error", err)
+		_ = r.body.Close()
+		r.body = nil
+		r.resumes++
+		head, body, rerr := r.client.RequestSnapshot(r.ctx, r.peer, &SnapshotRequest{
+			LatestInstance: r.head.LatestInstance,
+			Offset:         r.offset,
+		})
+		if rerr != nil {
+			return n, fmt.Errorf("resuming snapshot download at offset %d after %w: %w", r.offset, err, rerr)
+		}
+		r.body = body
+		if *head != r.head {
+			return n, fmt.Errorf("peer %s changed snapshot while resuming: %+v != %+v", r.peer, *head, r.head)
+		}
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+func (r *resumingSnapshotReader) Close() error {
+	if r.body == nil {
+		// Already closed when resuming failed.
+		return nil
+	}
+	err := r.body.Close()
+	r.body = nil
+	return err
+}
+
+// FindInitialPowerTable finds a peer with the power table of the given CID and fetches it from
+// them, retrying every half EC period until found or the context is cancelled.
 func FindInitialPowerTable(ctx context.Context, c Client, powerTableCID cid.Cid, ecPeriod time.Duration) (gpbft.PowerEntries, error) {
 	request := Request{
 		FirstInstance:     0,
@@ -199,17 +485,21 @@ func FindInitialPowerTable(ctx context.Context, c Client, powerTableCID cid.Cid,
 	ticker := clk.Ticker(ecPeriod / 2)
 	defer ticker.Stop()
 
-	pollOne := func(ctx context.Context, p peer.ID) (gpbft.PowerEntries, bool) {
-		targetProtocol := FetchProtocolName(c.NetworkName)
-		if proto, err := c.Host.Peerstore().FirstSupportedProtocol(p, targetProtocol); err != nil ||
-			proto != targetProtocol {
-
+	// Fetch the power table directly from peers that support it, and fall back to probing the
+	// others with a request for the certificates from instance 0.
+	fetchOne := func(ctx context.Context, p peer.ID) (gpbft.PowerEntries, bool) {
+		resp, err := c.RequestPowerTable(ctx, p, &PowerTableRequest{PowerTable: &powerTableCID})
+		if err != nil {
+			log.Infow("requesting initial power table", "error", err, "peer", p)
 			return nil, false
 		}
-
-		ctx, cancel := context.WithCancel(ctx)
-		defer cancel()
-
+		if resp.Status != StatusOK {
+			log.Infow("peer does not have the initial power table", "peer", p, "status", resp.Status)
+			return nil, false
+		}
+		return resp.PowerTable, true
+	}
+	probeOne := func(ctx context.Context, p peer.ID) (gpbft.PowerEntries, bool) {
 		rh, _, err := c.Request(ctx, p, &request)
 		if err != nil {
 			log.Infow("requesting initial power table", "error", err, "peer", p)
@@ -226,6 +516,23 @@ func FindInitialPowerTable(ctx context.Context, c Client, powerTableCID cid.Cid,
 		}
 		return rh.PowerTable, true
 	}
+	pollOne := func(ctx context.Context, p peer.ID) (gpbft.PowerEntries, bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		powerTableProtocol := PowerTableProtocolName(c.NetworkName)
+		fetchProtocol := FetchProtocolName(c.NetworkName)
+		switch proto, err := c.Host.Peerstore().FirstSupportedProtocol(p, powerTableProtocol, fetchProtocol); {
+		case err != nil:
+			return nil, false
+		case proto == powerTableProtocol:
+			return fetchOne(ctx, p)
+		case proto == fetchProtocol:
+			return probeOne(ctx, p)
+		default:
+			return nil, false
+		}
+	}
 
 	for {
 		for _, p := range c.Host.Network().Peers() {
@@ -0,0 +1,357 @@
+package certexchange
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/filecoin-project/go-f3/certs"
+	"github.com/filecoin-project/go-f3/gpbft"
+	"github.com/filecoin-project/go-f3/internal/measurements"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	cbg "github.com/whyrusleeping/cbor-gen"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	contentTypeCBOR = "application/cbor"
+	contentTypeJSON = "application/json"
+
+	// The maximum size of a request body. Requests have no variable-length fields, so this is
+	// plenty, even encoded as JSON.
+	maxHTTPRequestSize = 1024
+)
+
+// HTTPHandler returns a handler that serves certificate requests over HTTP, for consumers that
+// cannot open libp2p streams. Requests are POSTed to the paths named by FetchProtocolName and
+// FetchProtocolNameV2, relative to wherever the handler is mounted, and are served with the same
+// semantics and limits as over libp2p. Version 1 requests over the limits are refused with
+// 429 Too Many Requests.
+//
+// Requests are encoded as CBOR, in which case the response body is exactly what is served over
+// libp2p: the response header followed by the finality certificates. Requests with the
+// application/json content type are encoded as JSON instead, and are responded to with a JSON
+// object holding the response Header and the Certificates.
+//
+// Requests are only served while the server is started.
+func (s *Server) HTTPHandler() http.Handler {
+	return http.HandlerFunc(s.serveHTTP)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	var v2 bool
+	switch r.URL.Path {
+	case string(FetchProtocolName(s.NetworkName)):
+	case string(FetchProtocolNameV2(s.NetworkName)):
+		v2 = true
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	var jsonEncoded bool
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid content type: %s", err), http.StatusBadRequest)
+			return
+		}
+		switch mediaType {
+		case contentTypeJSON:
+			jsonEncoded = true
+		case contentTypeCBOR:
+		default:
+			http.Error(w, fmt.Sprintf("%s: %s", http.StatusText(http.StatusUnsupportedMediaType), mediaType), http.StatusUnsupportedMediaType)
+			return
+		}
+	}
+
+	// Hold the read-lock for the duration of the request so shutdown can block on closing all
+	// request handlers. We use a try-lock because blocking means we're trying to shutdown.
+	if !s.runningLk.TryRLock() {
+		http.Error(w, "certificate exchange is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	defer s.runningLk.RUnlock()
+	if s.runningCtx == nil || s.runningCtx.Err() != nil {
+		http.Error(w, "certificate exchange is not running", http.StatusServiceUnavailable)
+		return
+	}
+
+	// Cancel the request if/when we shutdown the server.
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	defer context.AfterFunc(s.runningCtx, cancel)()
+
+	ctx, cancelTimeout := withDeadline(ctx, s.RequestTimeout)
+	defer cancelTimeout()
+
+	_ = s.serveHTTPRequest(ctx, w, r, v2, jsonEncoded)
+}
+
+func (s *Server) serveHTTPRequest(ctx context.Context, w http.ResponseWriter, r *http.Request, v2, jsonEncoded bool) (_err error) {
+	start := time.Now()
+	var resp *response
+	defer func() {
+		if perr := recover(); perr != nil {
+			_err = fmt.Errorf("panicked in server HTTP response: %v", perr)
+			log.Errorf("%s\n%s", _err, string(debug.Stack()))
+		}
+		resp.record(ctx, start, _err, v2, transportHTTP)
+	}()
+
+	release, reason, ok := s.limiter.acquire(remoteHost(r))
+	if !ok {
+		header := s.reject(ctx, v2, reason, transportHTTP)
+		if header == nil {
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			return errOverQuota
+		}
+		return (&response{header: *header}).writeHTTP(ctx, w, v2, jsonEncoded)
+	}
+	defer release()
+
+	body := io.LimitReader(r.Body, maxHTTPRequestSize)
+	var (
+		req   RequestV2
+		reqV1 Request
+		err   error
+	)
+	switch {
+	case jsonEncoded && v2:
+		err = json.NewDecoder(body).Decode(&req)
+	case jsonEncoded:
+		err = json.NewDecoder(body).Decode(&reqV1)
+	case v2:
+		err = req.UnmarshalCBOR(body)
+	default:
+		err = reqV1.UnmarshalCBOR(body)
+	}
+	if err != nil {
+		log.Debugf("failed to read HTTP request: %v", err)
+		http.Error(w, fmt.Sprintf("invalid request: %s", err), http.StatusBadRequest)
+		return err
+	}
+	if !v2 {
+		req = reqV1.v2()
+	}
+
+	if resp, err = s.respond(ctx, &req, v2, transportHTTP); err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return err
+	}
+	return resp.writeHTTP(ctx, w, v2, jsonEncoded)
+}
+
+// httpJSONResponse is a response served over HTTP to a JSON-encoded request.
+type httpJSONResponse struct {
+	Header       any
+	Certificates []*certs.FinalityCertificate
+}
+
+// writeHTTP writes the response out as the body of an HTTP response.
+func (r *response) writeHTTP(ctx context.Context, w http.ResponseWriter, v2, jsonEncoded bool) error {
+	var header cbg.CBORMarshaler = &r.header
+	if !v2 {
+		header = r.headerV1()
+	}
+
+	if jsonEncoded {
+		// JSON responses are a single document, so the certificates are collected first.
+		w.Header().Set("Content-Type", contentTypeJSON)
+		certificates := []*certs.FinalityCertificate{}
+		_ = r.writeCertificates(ctx, func(cert *certs.FinalityCertificate) error {
+			certificates = append(certificates, cert)
+			return nil
+		})
+		if err := json.NewEncoder(w).Encode(httpJSONResponse{Header: header, Certificates: certificates}); err != nil {
+			log.Debugf("failed to write HTTP response: %v", err)
+			r.served = 0
+			return err
+		}
+		return nil
+	}
+
+	w.Header().Set("Content-Type", contentTypeCBOR)
+	bw := bufio.NewWriter(w)
+	if err := header.MarshalCBOR(bw); err != nil {
+		log.Debugf("failed to write header to HTTP response: %v", err)
+		return err
+	}
+	if err := r.writeCertificates(ctx, func(cert *certs.FinalityCertificate) error {
+		if err := cert.MarshalCBOR(bw); err != nil {
+			log.Debugf("failed to write certificate to HTTP response: %v", err)
+			return err
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// remoteHost returns the host that made the request, to rate limit requests by.
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+var (
+	_ Requester = (*Client)(nil)
+	_ Requester = (*HTTPClient)(nil)
+)
+
+// ErrUnknownHTTPServer is returned when requesting certificates from a peer that the HTTPClient has
+// no server URL for.
+var ErrUnknownHTTPServer = errors.New("unknown certificate exchange HTTP server")
+
+// HTTPClient is a certificate exchange client for requesting finality certificates over HTTP, from
+// servers serving Server.HTTPHandler. Servers are named by peer IDs, so that HTTPClient can be used
+// interchangeably with Client, e.g. by polling.Poller.
+//
+// Unlike Client, HTTPClient receives all the finality certificates in a response before returning
+// them, at most as many as servers respond with at once.
+type HTTPClient struct {
+	// The base URLs of the servers, i.e. where their handlers are mounted, keyed by the peer ID
+	// they are named by.
+	Servers        map[peer.ID]string
+	NetworkName    gpbft.NetworkName
+	RequestTimeout time.Duration
+	// The HTTP client to make requests with. Defaults to http.DefaultClient.
+	HTTP *http.Client
+}
+
+// Request finality certificates from the specified server. Returned finality certificates start at
+// the requested instance number and are sequential, but are otherwise unvalidated.
+func (c *HTTPClient) Request(ctx context.Context, p peer.ID, req *Request) (*ResponseHeader, <-chan *certs.FinalityCertificate, error) {
+	var resp ResponseHeader
+	ch, err := c.request(ctx, p, FetchProtocolName(c.NetworkName), req, req.FirstInstance, req.Limit, func(br *io.LimitedReader) error {
+		if req.IncludePowerTable {
+			br.N = maxPowerTableSize
+		}
+		return resp.UnmarshalCBOR(br)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, ch, nil
+}
+
+// RequestV2 requests finality certificates from the specified server, along with the power table
+// checkpoints and the status of the response. Returned finality certificates start at the
+// requested instance number and are sequential, and returned checkpoints are at the requested
+// interval, but both are otherwise unvalidated.
+func (c *HTTPClient) RequestV2(ctx context.Context, p peer.ID, req *RequestV2) (*ResponseHeaderV2, <-chan *certs.FinalityCertificate, error) {
+	var resp ResponseHeaderV2
+	ch, err := c.request(ctx, p, FetchProtocolNameV2(c.NetworkName), req, req.FirstInstance, req.Limit, func(br *io.LimitedReader) error {
+		if req.IncludePowerTable || req.PowerTableCheckpointInterval > 0 {
+			br.N = maxPowerTableSize
+		}
+		if err := resp.UnmarshalCBOR(br); err != nil {
+			return err
+		}
+		return checkPowerTableCheckpoints(req, resp.PowerTableCheckpoints)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, ch, nil
+}
+
+// request posts the given request to the path named by the given protocol, reads the response
+// header with the given function, and then the finality certificates that follow it.
+func (c *HTTPClient) request(ctx context.Context, p peer.ID, proto protocol.ID, req cbg.CBORMarshaler, firstInstance, limit uint64,
+	readHeader func(*io.LimitedReader) error) (_ch <-chan *certs.FinalityCertificate, _err error) {
+	defer func() {
+		if perr := recover(); perr != nil {
+			_err = fmt.Errorf("panicked requesting certificates from HTTP server %s: %v\n%s", p, perr, string(debug.Stack()))
+			log.Error(_err)
+		}
+	}()
+
+	baseURL, ok := c.Servers[p]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownHTTPServer, p)
+	}
+
+	ctx, cancel := withDeadline(ctx, c.RequestTimeout)
+	defer cancel()
+
+	defer func(requestStart time.Time) {
+		metrics.totalResponseTime.Record(ctx, time.Since(requestStart).Seconds(), metric.WithAttributes(
+			measurements.Status(ctx, _err),
+			attrTransport.String(transportHTTP),
+		))
+	}(time.Now())
+
+	var body bytes.Buffer
+	if err := req.MarshalCBOR(&body); err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(baseURL, "/")+string(proto), &body)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", contentTypeCBOR)
+
+	httpResp, err := cmp.Or(c.HTTP, http.DefaultClient).Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = httpResp.Body.Close() }()
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("certificate exchange HTTP server %s responded with %s", p, httpResp.Status)
+	}
+
+	br := &io.LimitedReader{R: bufio.NewReader(httpResp.Body), N: 100}
+	if err := readHeader(br); err != nil {
+		log.Debugw("failed to unmarshal certificate exchange response header from HTTP server", "peer", p, "error", err)
+		return nil, err
+	}
+
+	var certificates []*certs.FinalityCertificate
+	for i := uint64(0); i < min(limit, maxResponseLen); i++ {
+		cert := new(certs.FinalityCertificate)
+		// We'll read at most 1MiB per certificate, as over libp2p.
+		br.N = maxPowerTableSize
+		if err := cert.UnmarshalCBOR(br); err == io.EOF {
+			break
+		} else if err != nil {
+			log.Debugw("failed to unmarshal certificate from HTTP server", "peer", p, "error", err)
+			return nil, err
+		}
+		// One quick sanity check. The rest will be validated by the caller.
+		if cert.GPBFTInstance != firstInstance+i {
+			log.Warnw("received out-of-order certificate from HTTP server", "peer", p)
+			return nil, errors.New("out of order certificates received")
+		}
+		certificates = append(certificates, cert)
+	}
+
+	ch := make(chan *certs.FinalityCertificate, len(certificates))
+	for _, cert := range certificates {
+		ch <- cert
+	}
+	close(ch)
+	return ch, nil
+}
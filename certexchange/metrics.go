@@ -9,12 +9,28 @@ import (
 
 var meter = otel.Meter("f3/certexchange")
 var attrWithPowerTable = attribute.Key("with-power-table")
+var attrPruned = attribute.Key("pruned")
+var attrProtocolVersion = attribute.Key("protocol-version")
+var attrRejectReason = attribute.Key("reason")
+var attrResponseStatus = attribute.Key("response-status")
+var attrTransport = attribute.Key("transport")
+
+const (
+	transportLibp2p = "libp2p"
+	transportHTTP   = "http"
+)
 
 var metrics = struct {
 	requestLatency     metric.Float64Histogram
 	totalResponseTime  metric.Float64Histogram
 	serveTime          metric.Float64Histogram
 	certificatesServed metric.Int64Histogram
+	requestsRejected   metric.Int64Counter
+
+	powerTableServeTime metric.Float64Histogram
+
+	snapshotServeTime   metric.Float64Histogram
+	snapshotBytesServed metric.Int64Counter
 }{
 	requestLatency: measurements.Must(meter.Float64Histogram(
 		"f3_certexchange_request_latency",
@@ -36,4 +52,24 @@ var metrics = struct {
 		metric.WithDescription("The number of certificates served (per request)."),
 		metric.WithUnit("{certificate}"),
 	)),
+	requestsRejected: measurements.Must(meter.Int64Counter(
+		"f3_certexchange_requests_rejected",
+		metric.WithDescription("The number of requests rejected for being over the server limits."),
+		metric.WithUnit("{request}"),
+	)),
+	powerTableServeTime: measurements.Must(meter.Float64Histogram(
+		"f3_certexchange_power_table_serve_time",
+		metric.WithDescription("The time spent serving power table requests."),
+		metric.WithUnit("s"),
+	)),
+	snapshotServeTime: measurements.Must(meter.Float64Histogram(
+		"f3_certexchange_snapshot_serve_time",
+		metric.WithDescription("The time spent serving snapshot requests."),
+		metric.WithUnit("s"),
+	)),
+	snapshotBytesServed: measurements.Must(meter.Int64Counter(
+		"f3_certexchange_snapshot_bytes_served",
+		metric.WithDescription("The number of snapshot bytes served."),
+		metric.WithUnit("By"),
+	)),
 }
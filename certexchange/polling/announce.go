@@ -0,0 +1,183 @@
+package polling
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/filecoin-project/go-f3/certexchange"
+	"github.com/filecoin-project/go-f3/certs"
+	"github.com/filecoin-project/go-f3/internal/psutil"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"go.opentelemetry.io/otel/metric"
+)
+
+var _ pubsub.ValidatorEx = (*Subscriber)(nil).validateAnnouncement
+
+// The number of buffered announcements, beyond which older ones are dropped.
+const announcementBufferSize = 16
+
+// announcement is a certificate announcement along with the peer that published it, and therefore
+// has the certificate.
+type announcement struct {
+	certexchange.Announcement
+	from peer.ID
+}
+
+// startAnnouncing joins the announcement topic, publishes every certificate stored from now on,
+// and returns the announcements of other peers. Announcing stops when the context is cancelled.
+func (s *Subscriber) startAnnouncing(ctx context.Context) (<-chan announcement, error) {
+	topicName := certexchange.AnnouncementTopicName(s.NetworkName)
+	if err := s.PubSub.RegisterTopicValidator(topicName, s.validateAnnouncement); err != nil {
+		return nil, fmt.Errorf("failed to register topic validator: %w", err)
+	}
+	topic, err := s.PubSub.Join(topicName, pubsub.WithTopicMessageIdFn(psutil.CertificateAnnouncementMessageIdFn))
+	if err != nil {
+		_ = s.PubSub.UnregisterTopicValidator(topicName)
+		return nil, fmt.Errorf("failed to join topic '%s': %w", topicName, err)
+	}
+	subscription, err := topic.Subscribe(pubsub.WithBufferSize(announcementBufferSize))
+	if err != nil {
+		_ = topic.Close()
+		_ = s.PubSub.UnregisterTopicValidator(topicName)
+		return nil, fmt.Errorf("failed to subscribe to topic '%s': %w", topicName, err)
+	}
+
+	announcements := make(chan announcement, announcementBufferSize)
+	certCh, closeCerts := s.Store.Subscribe()
+
+	s.wg.Add(2)
+	go func() {
+		defer s.wg.Done()
+		defer close(announcements)
+		for ctx.Err() == nil {
+			msg, err := subscription.Next(ctx)
+			if err != nil {
+				// The subscription only fails once it is canceled or the context is done, either
+				// of which ends it.
+				if ctx.Err() == nil {
+					log.Debugw("failed to read next certificate announcement", "err", err)
+				}
+				return
+			}
+			if msg.GetFrom() == s.Host.ID() {
+				continue
+			}
+			a := announcement{Announcement: msg.ValidatorData.(certexchange.Announcement), from: msg.GetFrom()}
+			select {
+			case announcements <- a:
+			default:
+				// We're busy fetching, so drop the oldest announcement for the latest one.
+				select {
+				case <-announcements:
+				default:
+				}
+				announcements <- a
+			}
+		}
+	}()
+	go func() {
+		defer s.wg.Done()
+		defer func() {
+			closeCerts()
+			subscription.Cancel()
+			_ = s.PubSub.UnregisterTopicValidator(topicName)
+			_ = topic.Close()
+		}()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case cert := <-certCh:
+				if err := s.announce(ctx, topic, cert); err != nil && ctx.Err() == nil {
+					log.Debugw("failed to announce certificate", "instance", cert.GPBFTInstance, "err", err)
+				}
+			}
+		}
+	}()
+	return announcements, nil
+}
+
+func (s *Subscriber) announce(ctx context.Context, topic *pubsub.Topic, cert *certs.FinalityCertificate) error {
+	certCid, err := certs.MakeCertificateCID(cert)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	a := certexchange.Announcement{Instance: cert.GPBFTInstance, Certificate: certCid}
+	if err := a.MarshalCBOR(&buf); err != nil {
+		return err
+	}
+	// Peers that stored the same certificate publish the same announcement, which pubsub then
+	// only propagates once.
+	return topic.Publish(ctx, buf.Bytes())
+}
+
+func (s *Subscriber) validateAnnouncement(_ context.Context, _ peer.ID, msg *pubsub.Message) pubsub.ValidationResult {
+	var a certexchange.Announcement
+	if err := a.UnmarshalCBOR(bytes.NewReader(msg.Data)); err != nil {
+		log.Debugw("failed to decode certificate announcement", "from", msg.GetFrom(), "err", err)
+		return pubsub.ValidationReject
+	}
+	if !a.Certificate.Defined() {
+		return pubsub.ValidationReject
+	}
+	// Certificates cannot be validated without fetching them, so only propagate announcements
+	// close to our latest instance. We still propagate those of the latest instance we have, as
+	// our peers may not have it yet.
+	var latest uint64
+	if cert := s.Store.Latest(); cert != nil {
+		latest = cert.GPBFTInstance
+	}
+	if a.Instance < latest || a.Instance > latest+parallelCatchUpThreshold {
+		return pubsub.ValidationIgnore
+	}
+	msg.ValidatorData = a
+	return pubsub.ValidationAccept
+}
+
+// fetchAnnounced fetches the announced certificate from the peer that announced it, returning the
+// progress made.
+func (s *Subscriber) fetchAnnounced(ctx context.Context, a announcement) (uint64, error) {
+	start := s.poller.NextInstance
+	if a.Instance < start {
+		// We already have it.
+		return 0, nil
+	}
+
+	res, err := s.poller.Poll(ctx, a.from)
+	if err != nil {
+		return 0, err
+	}
+	log.Debugf("fetched announced instance %d from %s, got %+v", a.Instance, a.from, res)
+	switch res.Status {
+	case PollMiss, PollHit, PollOverQuota:
+		s.peerTracker.peerSeen(a.from)
+		s.peerTracker.updateLatency(a.from, res.Latency)
+	case PollFailed:
+		s.peerTracker.recordFailure(a.from)
+	case PollIllegal:
+		s.peerTracker.recordInvalid(a.from)
+	default:
+		panic(fmt.Sprintf("unexpected polling.PollResult: %#v", res))
+	}
+
+	// Make sure the peer announced the certificate it served.
+	if a.Instance < s.poller.NextInstance {
+		cert, err := s.Store.Get(ctx, a.Instance)
+		if err != nil {
+			return 0, err
+		}
+		if certCid, err := certs.MakeCertificateCID(cert); err != nil {
+			return 0, err
+		} else if certCid != a.Certificate {
+			log.Warnw("peer announced a certificate that does not match the one finalized", "peer", a.from, "instance", a.Instance)
+			s.peerTracker.recordInvalid(a.from)
+		}
+	}
+	metrics.announcementsFetched.Add(ctx, 1, metric.WithAttributes(
+		attrMadeProgress.Bool(s.poller.NextInstance > start),
+	))
+	return s.poller.NextInstance - start, nil
+}
@@ -0,0 +1,76 @@
+package polling
+
+import (
+	"context"
+	"errors"
+
+	"github.com/filecoin-project/go-f3/certexchange"
+	"github.com/filecoin-project/go-f3/certs"
+	"github.com/filecoin-project/go-f3/certstore"
+)
+
+// backfill fetches the next certificates to backfill the store with from one of the suggested
+// peers, walking forwards from BackfillTo. The first peer to serve them also serves the power
+// table at BackfillTo, which is only trusted if it matches BackfillPowerTable. It returns the
+// number of certificates backfilled, which is zero if no peer served valid ones.
+func (s *Subscriber) backfill(ctx context.Context) (uint64, error) {
+	first := s.Store.FirstInstance()
+	if first <= s.BackfillTo {
+		return 0, nil
+	}
+	next := s.BackfillTo
+	if s.backfilling != nil {
+		next = s.backfilling.Next()
+	}
+
+	for _, p := range s.peerTracker.suggestPeers(ctx) {
+		head, ch, err := s.RequestV2(ctx, p, &certexchange.RequestV2{
+			FirstInstance:     next,
+			Limit:             min(first-next, maxRequestLength),
+			IncludePowerTable: s.backfilling == nil,
+		})
+		if err != nil {
+			// The peer may only support the version 1 protocol, so don't hold it against them.
+			log.Debugw("failed to request certificates to backfill", "peer", p, "error", err)
+			continue
+		}
+		var certificates []*certs.FinalityCertificate
+		for cert := range ch {
+			certificates = append(certificates, cert)
+		}
+		if head.Status != certexchange.StatusOK || len(certificates) == 0 {
+			// Likely pruned, or over quota.
+			log.Debugw("peer did not serve the certificates to backfill", "peer", p, "status", head.Status, "received", len(certificates))
+			continue
+		}
+
+		if s.backfilling == nil {
+			if len(head.PowerTable) == 0 {
+				log.Debugw("peer did not serve the power table to backfill from", "peer", p)
+				continue
+			}
+			if ptCid, err := certs.MakePowerTableCID(head.PowerTable); err != nil || ptCid != s.BackfillPowerTable {
+				log.Warnw("peer served an untrusted power table to backfill from", "peer", p, "instance", next, "cid", ptCid)
+				s.peerTracker.recordInvalid(p)
+				continue
+			}
+			backfilling, err := s.Store.StartBackfill(s.SignatureVerifier, s.NetworkName, next, head.PowerTable)
+			if err != nil {
+				return 0, err
+			}
+			s.backfilling = backfilling
+		}
+
+		err = s.backfilling.Add(ctx, certificates...)
+		if errors.Is(err, certstore.ErrInvalidCertificate) {
+			log.Warnw("peer served invalid certificates to backfill", "peer", p, "from", next, "error", err)
+			s.peerTracker.recordInvalid(p)
+			continue
+		} else if err != nil {
+			return 0, err
+		}
+		metrics.certificatesBackfilled.Add(ctx, int64(len(certificates)))
+		return uint64(len(certificates)), nil
+	}
+	return 0, nil
+}
@@ -3,17 +3,48 @@ package polling
 import (
 	"context"
 	"slices"
+	"sync"
+	"time"
 
 	"github.com/libp2p/go-libp2p/core/event"
 	"github.com/libp2p/go-libp2p/core/host"
 	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/peerstore"
 	"github.com/libp2p/go-libp2p/core/protocol"
+	"github.com/libp2p/go-libp2p/core/routing"
 
 	"github.com/filecoin-project/go-f3/certexchange"
 	"github.com/filecoin-project/go-f3/gpbft"
+	"github.com/filecoin-project/go-f3/internal/clock"
 )
 
-func discoverPeers(ctx context.Context, h host.Host, nn gpbft.NetworkName) (<-chan peer.ID, error) {
+const (
+	// How often to look up the providers of the certificate exchange protocol.
+	providerDiscoveryInterval = 10 * time.Minute
+	// How often to advertise this node as a provider. Provider records expire after a day or two.
+	providerAdvertiseInterval = 12 * time.Hour
+	// The maximum number of providers to look up at once.
+	maxDiscoveredProviders = 100
+)
+
+// Discovery finds certificate exchange peers for a Subscriber to poll.
+type Discovery interface {
+	// Discover streams the peers it finds on the given network until the context is cancelled,
+	// and then closes the channel.
+	Discover(ctx context.Context, h host.Host, nn gpbft.NetworkName) (<-chan peer.ID, error)
+}
+
+var (
+	_ Discovery = ConnectedPeers{}
+	_ Discovery = StaticPeers(nil)
+	_ Discovery = (*ProviderDiscovery)(nil)
+)
+
+// ConnectedPeers discovers the peers we are connected to that support the certificate exchange
+// protocol, as they are identified. This is the default discovery.
+type ConnectedPeers struct{}
+
+func (ConnectedPeers) Discover(ctx context.Context, h host.Host, nn gpbft.NetworkName) (<-chan peer.ID, error) {
 	out := make(chan peer.ID, 256)
 	discoveryEvents, err := h.EventBus().Subscribe([]any{
 		new(event.EvtPeerIdentificationCompleted),
@@ -81,3 +112,109 @@ fillInitialPeers:
 	}()
 	return out, nil
 }
+
+// StaticPeers discovers a fixed set of peers, whether or not we are connected to them. This is
+// useful for nodes that can't find peers otherwise, e.g. light clients that don't participate in
+// gossip, or nodes behind restrictive networks.
+type StaticPeers []peer.AddrInfo
+
+func (s StaticPeers) Discover(ctx context.Context, h host.Host, _ gpbft.NetworkName) (<-chan peer.ID, error) {
+	out := make(chan peer.ID, len(s))
+	for _, p := range s {
+		if p.ID == h.ID() {
+			continue
+		}
+		h.Peerstore().AddAddrs(p.ID, p.Addrs, peerstore.PermanentAddrTTL)
+		out <- p.ID
+	}
+	go func() {
+		defer close(out)
+		<-ctx.Done()
+	}()
+	return out, nil
+}
+
+// ProviderDiscovery discovers the peers that advertise themselves as certificate exchange peers in
+// a content routing system, such as the DHT, under the key given by certexchange.ProviderKey.
+type ProviderDiscovery struct {
+	Routing routing.ContentRouting
+	// If set, this node is advertised as a certificate exchange peer as well. Only nodes that
+	// serve certificates should be advertised.
+	Advertise bool
+}
+
+func (d *ProviderDiscovery) Discover(ctx context.Context, h host.Host, nn gpbft.NetworkName) (<-chan peer.ID, error) {
+	key, err := certexchange.ProviderKey(nn)
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan peer.ID, maxDiscoveredProviders)
+	go func() {
+		defer close(out)
+
+		clk := clock.GetClock(ctx)
+		ticker := clk.Ticker(providerDiscoveryInterval)
+		defer ticker.Stop()
+
+		var lastAdvertised time.Time
+		for ctx.Err() == nil {
+			if d.Advertise && (lastAdvertised.IsZero() || clk.Since(lastAdvertised) >= providerAdvertiseInterval) {
+				if err := d.Routing.Provide(ctx, key, true); err != nil {
+					log.Debugw("failed to advertise as a certificate exchange provider", "error", err)
+				} else {
+					lastAdvertised = clk.Now()
+				}
+			}
+			for p := range d.Routing.FindProvidersAsync(ctx, key, maxDiscoveredProviders) {
+				if p.ID == h.ID() {
+					continue
+				}
+				h.Peerstore().AddAddrs(p.ID, p.Addrs, peerstore.TempAddrTTL)
+				select {
+				case out <- p.ID:
+				case <-ctx.Done():
+				}
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return out, nil
+}
+
+// discoverPeers merges the peers found by all the given discoveries, or by ConnectedPeers if none
+// are given.
+func discoverPeers(ctx context.Context, h host.Host, nn gpbft.NetworkName, discovery []Discovery) (<-chan peer.ID, error) {
+	switch len(discovery) {
+	case 0:
+		return ConnectedPeers{}.Discover(ctx, h, nn)
+	case 1:
+		return discovery[0].Discover(ctx, h, nn)
+	}
+
+	out := make(chan peer.ID, 256)
+	var wg sync.WaitGroup
+	for _, d := range discovery {
+		found, err := d.Discover(ctx, h, nn)
+		if err != nil {
+			return nil, err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range found {
+				select {
+				case out <- p:
+				case <-ctx.Done():
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out, nil
+}
@@ -0,0 +1,95 @@
+package polling_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/go-f3/certexchange"
+	"github.com/filecoin-project/go-f3/certexchange/polling"
+
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p/core/peer"
+	mocknetwork "github.com/libp2p/go-libp2p/p2p/net/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaticPeers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mocknet := mocknetwork.New()
+	h1, err := mocknet.GenPeer()
+	require.NoError(t, err)
+	h2, err := mocknet.GenPeer()
+	require.NoError(t, err)
+
+	static := polling.StaticPeers{
+		{ID: h1.ID()},
+		{ID: h2.ID(), Addrs: h2.Addrs()},
+	}
+	found, err := static.Discover(ctx, h1, polling.TestNetworkName)
+	require.NoError(t, err)
+
+	// We're never found ourselves, but the other peer is found without being connected to.
+	require.Equal(t, h2.ID(), <-found)
+	require.Equal(t, h2.Addrs(), h1.Peerstore().Addrs(h2.ID()))
+
+	cancel()
+	_, ok := <-found
+	require.False(t, ok)
+}
+
+type fakeContentRouting struct {
+	provided  chan cid.Cid
+	providers []peer.AddrInfo
+}
+
+func (f *fakeContentRouting) Provide(_ context.Context, key cid.Cid, _ bool) error {
+	f.provided <- key
+	return nil
+}
+
+func (f *fakeContentRouting) FindProvidersAsync(_ context.Context, _ cid.Cid, _ int) <-chan peer.AddrInfo {
+	out := make(chan peer.AddrInfo, len(f.providers))
+	for _, p := range f.providers {
+		out <- p
+	}
+	close(out)
+	return out
+}
+
+func TestProviderDiscovery(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mocknet := mocknetwork.New()
+	h1, err := mocknet.GenPeer()
+	require.NoError(t, err)
+	h2, err := mocknet.GenPeer()
+	require.NoError(t, err)
+
+	routing := &fakeContentRouting{
+		provided:  make(chan cid.Cid, 1),
+		providers: []peer.AddrInfo{{ID: h1.ID()}, {ID: h2.ID(), Addrs: h2.Addrs()}},
+	}
+	discovery := &polling.ProviderDiscovery{Routing: routing, Advertise: true}
+	found, err := discovery.Discover(ctx, h1, polling.TestNetworkName)
+	require.NoError(t, err)
+
+	expectedKey, err := certexchange.ProviderKey(polling.TestNetworkName)
+	require.NoError(t, err)
+	select {
+	case key := <-routing.provided:
+		require.Equal(t, expectedKey, key)
+	case <-time.After(time.Second):
+		t.Fatal("expected to be advertised as a provider")
+	}
+	select {
+	case p := <-found:
+		require.Equal(t, h2.ID(), p)
+	case <-time.After(time.Second):
+		t.Fatal("expected to discover the other provider")
+	}
+	require.Equal(t, h2.Addrs(), h1.Peerstore().Addrs(h2.ID()))
+}
@@ -16,6 +16,9 @@ var metrics = struct {
 	peersPolled              metric.Int64Histogram
 	peersRequiredPerPoll     metric.Int64Histogram
 	pollEfficiency           metric.Float64Histogram
+	announcementsFetched     metric.Int64Counter
+	certificatesBackfilled   metric.Int64Counter
+	predictionError          metric.Float64Histogram
 }{
 	activePeers: measurements.Must(meter.Int64Gauge(
 		"f3_certexchange_polling_active_peers",
@@ -51,6 +54,22 @@ var metrics = struct {
 		"f3_certexchange_polling_poll_efficiency",
 		metric.WithDescription("The fraction of requests necessary to make progress."),
 	)),
+	announcementsFetched: measurements.Must(meter.Int64Counter(
+		"f3_certexchange_polling_announcements_fetched",
+		metric.WithDescription("The number of announced certificates fetched from the announcing peer."),
+		metric.WithUnit("{announcement}"),
+	)),
+	certificatesBackfilled: measurements.Must(meter.Int64Counter(
+		"f3_certexchange_polling_certificates_backfilled",
+		metric.WithDescription("The number of certificates backfilled below the first instance of the store."),
+		metric.WithUnit("{certificate}"),
+	)),
+	predictionError: measurements.Must(meter.Float64Histogram(
+		"f3_certexchange_polling_prediction_error",
+		metric.WithDescription("The time between when the schedule hint expected the next certificate and when it was received, negative if received early."),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(-30, -10, -5, -2, -1, -0.5, 0, 0.5, 1, 2, 5, 10, 30, 60, 120, 300),
+	)),
 }
 
 var attrMadeProgress = attribute.Key("made-progress")
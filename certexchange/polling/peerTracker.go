@@ -4,6 +4,7 @@ import (
 	"cmp"
 	"container/heap"
 	"context"
+	"fmt"
 	"math/rand"
 	"slices"
 	"time"
@@ -31,6 +32,11 @@ const (
 
 	// EWMA alpha for latency tracking (0-1, smaller numbers favor newer readings)
 	latencyAlpha = 0.7
+
+	// Persisted peers not seen for this long are forgotten when restored.
+	peerRecordMaxAge = 7 * 24 * time.Hour
+	// How often to persist peer records, if at all.
+	peerRecordSaveInterval = 5 * time.Minute
 )
 
 type peerState int
@@ -42,6 +48,35 @@ const (
 	peerActive
 )
 
+func (s peerState) String() string {
+	switch s {
+	case peerEvil:
+		return "evil"
+	case peerInactive:
+		return "inactive"
+	case peerDeactivating:
+		return "deactivating"
+	case peerActive:
+		return "active"
+	default:
+		return fmt.Sprintf("peerState(%d)", int(s))
+	}
+}
+
+// PeerRecord is a snapshot of what is known about a certificate exchange peer.
+type PeerRecord struct {
+	ID peer.ID
+	// One of "active", "deactivating", "inactive" or "evil".
+	State string
+	// The hits and misses in the sliding window of recent requests.
+	Hits, Misses int
+	// The number of sequential failures since the last successful request.
+	SequentialFailures int
+	// The moving average of request latency, or zero if unknown.
+	Latency  time.Duration
+	LastSeen time.Time
+}
+
 // TODO: Track latency and connectedness.
 type peerRecord struct {
 	id peer.ID
@@ -57,6 +92,9 @@ type peerRecord struct {
 	state        peerState
 	lastSeen     time.Time
 	latency      time.Duration
+	// Whether the peer was restored from a persisted record and hasn't been seen since. Its
+	// addresses aren't persisted, so it's only activated once seen again.
+	restored bool
 }
 
 type backoffHeap []*backoffRecord
@@ -261,6 +299,10 @@ func (t *peerTracker) peerSeen(p peer.ID) {
 		t.maybeGc()
 	} else {
 		r.lastSeen = now
+		if r.restored {
+			r.restored = false
+			t.reactivate(p)
+		}
 	}
 }
 
@@ -376,6 +418,62 @@ trimLoop:
 	t.active = t.active[:activePeers]
 }
 
+// records returns a snapshot of all tracked peers, from best to worst.
+func (t *peerTracker) records() []PeerRecord {
+	peers := make([]*peerRecord, 0, len(t.peers))
+	for _, r := range t.peers {
+		peers = append(peers, r)
+	}
+	slices.SortFunc(peers, func(a, b *peerRecord) int {
+		return b.Cmp(a)
+	})
+	records := make([]PeerRecord, len(peers))
+	for i, r := range peers {
+		records[i] = PeerRecord{
+			ID:                 r.id,
+			State:              r.state.String(),
+			Hits:               r.hits,
+			Misses:             r.misses,
+			SequentialFailures: r.sequentialFailures,
+			Latency:            r.latency,
+			LastSeen:           r.lastSeen,
+		}
+	}
+	return records
+}
+
+// restore tracks the given peers, as previously returned by records, unless they were last seen
+// more than maxAge ago or are tracked already. Peer addresses aren't persisted, so peers are
+// restored as inactive, and those that aren't evil become active once seen again, to then be
+// picked according to their record.
+func (t *peerTracker) restore(records []PeerRecord, maxAge time.Duration) {
+	now := t.clock.Now()
+	for _, rec := range records {
+		if now.Sub(rec.LastSeen) > maxAge {
+			continue
+		}
+		if _, ok := t.peers[rec.ID]; ok {
+			continue
+		}
+		r := &peerRecord{
+			id:                 rec.ID,
+			sequentialFailures: max(rec.SequentialFailures, 0),
+			hits:               min(max(rec.Hits, 0), hitMissSlidingWindow),
+			misses:             min(max(rec.Misses, 0), hitMissSlidingWindow),
+			latency:            max(rec.Latency, 0),
+			lastSeen:           rec.LastSeen,
+			restored:           true,
+		}
+		if rec.State == peerEvil.String() {
+			r.state = peerEvil
+		} else {
+			r.state = peerInactive
+		}
+		t.peers[r.id] = r
+	}
+	t.maybeGc()
+}
+
 // Process all changes to peers (rank, possibly GC, drop bad peers, etc.).
 func (t *peerTracker) processPeerChanges(ctx context.Context) {
 	t.maybeGc()
@@ -2,7 +2,9 @@ package polling
 
 import (
 	"context"
+	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/filecoin-project/go-f3/internal/clock"
 	"github.com/libp2p/go-libp2p/core/peer"
@@ -241,3 +243,59 @@ func TestPeerTracker(t *testing.T) {
 	}
 
 }
+
+func TestPeerTrackerRestore(t *testing.T) {
+	clk := clock.NewMock()
+	pt := newPeerTracker(clk)
+
+	peers := make([]peer.ID, 4)
+	for i := range peers {
+		peers[i] = test.RandPeerIDFatal(t)
+		pt.peerSeen(peers[i])
+	}
+	pt.recordHit(peers[0])
+	pt.recordHit(peers[0])
+	pt.updateLatency(peers[0], time.Second)
+	pt.recordMiss(peers[1])
+	pt.recordInvalid(peers[2])
+
+	records := pt.records()
+	require.Len(t, records, len(peers))
+	require.Equal(t, peers[0], records[0].ID)
+	require.Equal(t, 2, records[0].Hits)
+	require.Equal(t, time.Second, records[0].Latency)
+	require.Equal(t, "evil", records[len(records)-1].State)
+
+	// Records survive encoding, as they're persisted as JSON.
+	b, err := json.Marshal(records)
+	require.NoError(t, err)
+	var decoded []PeerRecord
+	require.NoError(t, json.Unmarshal(b, &decoded))
+
+	// Forget stale peers.
+	clk.Add(peerRecordMaxAge / 2)
+	for i := range decoded {
+		if decoded[i].ID == peers[3] {
+			decoded[i].LastSeen = clk.Now().Add(-2 * peerRecordMaxAge)
+		}
+	}
+
+	restored := newPeerTracker(clk)
+	restored.restore(decoded, peerRecordMaxAge)
+	require.Len(t, restored.peers, 3)
+	require.NotContains(t, restored.peers, peers[3])
+
+	// Evil peers stay evil, and the rest are only picked once seen again, according to their
+	// record.
+	require.Equal(t, peerEvil, restored.peers[peers[2]].state)
+	require.Empty(t, restored.active)
+	for _, p := range peers[:3] {
+		restored.peerSeen(p)
+	}
+	require.NotContains(t, restored.active, peers[2])
+	require.Equal(t, peers[0], restored.suggestPeers(context.Background())[0])
+	best := restored.records()[0]
+	require.Equal(t, peers[0], best.ID)
+	require.Equal(t, 2, best.Hits)
+	require.Equal(t, time.Second, best.Latency)
+}
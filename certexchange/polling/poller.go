@@ -2,7 +2,9 @@
 package polling
 
 import (
+	"cmp"
 	"context"
+	"slices"
 	"time"
 
 	"github.com/filecoin-project/go-f3/certexchange"
@@ -15,8 +17,9 @@ import (
 
 // A Poller will poll specific peers on-demand to try to advance the current GPBFT instance.
 type Poller struct {
-	*certexchange.Client
+	certexchange.Requester
 
+	NetworkName       gpbft.NetworkName
 	Store             *certstore.Store
 	SignatureVerifier gpbft.Verifier
 	PowerTable        gpbft.PowerEntries
@@ -25,7 +28,9 @@ type Poller struct {
 }
 
 // NewPoller constructs a new certificate poller and initializes it from the passed certificate store.
-func NewPoller(ctx context.Context, client *certexchange.Client, store *certstore.Store, verifier gpbft.Verifier) (*Poller, error) {
+// The client is either a certexchange.Client, to poll peers over libp2p, or a
+// certexchange.HTTPClient, to poll servers over HTTP.
+func NewPoller(ctx context.Context, client certexchange.Requester, nn gpbft.NetworkName, store *certstore.Store, verifier gpbft.Verifier) (*Poller, error) {
 	var nextInstance uint64
 	if latest := store.Latest(); latest != nil {
 		nextInstance = latest.GPBFTInstance + 1
@@ -35,7 +40,8 @@ func NewPoller(ctx context.Context, client *certexchange.Client, store *certstor
 		return nil, err
 	}
 	return &Poller{
-		Client:            client,
+		Requester:         client,
+		NetworkName:       nn,
 		Store:             store,
 		SignatureVerifier: verifier,
 		NextInstance:      nextInstance,
@@ -54,6 +60,8 @@ type PollResult struct {
 	NewCertificates uint64
 	// Total certificates received.
 	ReceivedCertificates uint64
+	// The pending instance the peer claimed to be at in its latest response.
+	PendingInstance uint64
 }
 
 type PollStatus int
@@ -63,6 +71,8 @@ const (
 	PollHit
 	PollFailed
 	PollIllegal
+	// The peer refused to serve us for now, being over its limits.
+	PollOverQuota
 )
 
 func (p PollStatus) GoString() string {
@@ -94,9 +104,10 @@ func (p *Poller) CatchUp(ctx context.Context) (uint64, error) {
 }
 
 // Poll polls a specific peer, possibly multiple times, in order to advance the instance as much as
-// possible. It returns:
+// possible. If the peer is too far ahead to efficiently catch up with from it alone, Poll returns
+// after the first response, and the caller should continue with CatchUpFrom. It returns:
 //
-// 1. A PollResult indicating the outcome: miss, hit, failed, illegal.
+// 1. A PollResult indicating the outcome: miss, hit, failed, illegal, over quota.
 // 2. An error if something went wrong internally (e.g., the certificate store returned an error).
 func (p *Poller) Poll(ctx context.Context, peer peer.ID) (*PollResult, error) {
 	res := new(PollResult)
@@ -113,7 +124,7 @@ func (p *Poller) Poll(ctx context.Context, peer peer.ID) (*PollResult, error) {
 		}
 
 		start := p.clock.Now()
-		resp, ch, err := p.Request(ctx, peer, &certexchange.Request{
+		resp, ch, err := p.RequestV2(ctx, peer, &certexchange.RequestV2{
 			FirstInstance:     p.NextInstance,
 			Limit:             maxRequestLength,
 			IncludePowerTable: false,
@@ -124,6 +135,24 @@ func (p *Poller) Poll(ctx context.Context, peer peer.ID) (*PollResult, error) {
 			res.Error = err
 			return res, nil
 		}
+		res.PendingInstance = resp.PendingInstance
+
+		// The peer is busy rather than faulty, so don't hold it against it.
+		if resp.Status == certexchange.StatusOverQuota {
+			if res.ReceivedCertificates == 0 {
+				res.Status = PollOverQuota
+			}
+			return res, nil
+		}
+
+		// Unlike an empty response, this tells us the peer won't ever have the certificates we
+		// need, rather than not having them yet.
+		if resp.Status == certexchange.StatusPruned && p.NextInstance < resp.PendingInstance {
+			if res.ReceivedCertificates == 0 {
+				res.Status = PollMiss
+			}
+			return res, nil
+		}
 
 		// If they're caught up, record it as a hit. Otherwise, if they have nothing
 		// to give us, move on.
@@ -159,9 +188,11 @@ func (p *Poller) Poll(ctx context.Context, peer peer.ID) (*PollResult, error) {
 		}
 
 		// Try again if they're claiming to have more instances (and gave me at
-		// least one).
+		// least one), unless there are enough of them to fetch from several peers at once.
 		if resp.PendingInstance <= p.NextInstance {
 			return res, nil
+		} else if resp.PendingInstance > p.NextInstance+parallelCatchUpThreshold {
+			return res, nil
 		} else if res.ReceivedCertificates == 0 {
 			res.Status = PollFailed
 			// If they give me no certificates but claim to have more, treat this as a
@@ -171,3 +202,182 @@ func (p *Poller) Poll(ctx context.Context, peer peer.ID) (*PollResult, error) {
 
 	}
 }
+
+// CatchUpResult is the outcome of catching up from several peers at once.
+type CatchUpResult struct {
+	// The peers that failed to respond to a request.
+	Failed []peer.ID
+	// The peers that served invalid certificates.
+	Invalid []peer.ID
+
+	// NewCertificates certificates we didn't yet have. Excludes certificates we received through some other
+	// channel while catching up.
+	NewCertificates uint64
+	// Total certificates received.
+	ReceivedCertificates uint64
+}
+
+// A range of instances to request from a single peer.
+type catchUpChunk struct {
+	first, limit uint64
+}
+
+type catchUpResponse struct {
+	chunk        catchUpChunk
+	peer         peer.ID
+	certificates []*certs.FinalityCertificate
+	err          error
+}
+
+// CatchUpFrom fetches the certificates up to, but excluding, the given pending instance from the
+// given peers in parallel. The missing range is split into chunks requested from different peers.
+// Each chunk is validated as soon as the power table it starts at is known, i.e. once the chunks
+// before it have been validated, and its certificates are stored in order.
+//
+// Chunks that could not be fetched or validated are requested again from the remaining peers.
+// Peers that fail or serve invalid certificates are not asked again, and are reported in the
+// result. CatchUpFrom returns once the pending instance is reached, or no peer can help further.
+func (p *Poller) CatchUpFrom(ctx context.Context, peers []peer.ID, pending uint64) (*CatchUpResult, error) {
+	res := new(CatchUpResult)
+
+	// Cancel this context on exit to abandon requests still in flight.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if _, err := p.CatchUp(ctx); err != nil {
+		return nil, err
+	}
+
+	var (
+		idle      = slices.Clone(peers)
+		excluded  = make(map[peer.ID]struct{})
+		retries   []catchUpChunk
+		cursor    = p.NextInstance
+		received  = make(map[uint64]*catchUpResponse)
+		responses = make(chan *catchUpResponse, maxParallelRequests)
+		inFlight  int
+	)
+	nextChunk := func() (catchUpChunk, bool) {
+		if len(retries) > 0 {
+			chunk := retries[0]
+			retries = retries[1:]
+			return chunk, true
+		}
+		// Don't get too far ahead of validation, as fetched chunks are held in memory until then.
+		if cursor < pending && cursor < p.NextInstance+catchUpWindow {
+			chunk := catchUpChunk{first: cursor, limit: min(maxRequestLength, pending-cursor)}
+			cursor += chunk.limit
+			return chunk, true
+		}
+		return catchUpChunk{}, false
+	}
+	retry := func(chunk catchUpChunk) {
+		i, _ := slices.BinarySearchFunc(retries, chunk.first, func(c catchUpChunk, first uint64) int {
+			return cmp.Compare(c.first, first)
+		})
+		retries = slices.Insert(retries, i, chunk)
+	}
+	exclude := func(id peer.ID) {
+		excluded[id] = struct{}{}
+		idle = slices.DeleteFunc(idle, func(other peer.ID) bool { return other == id })
+	}
+
+	for {
+		for inFlight < maxParallelRequests && len(idle) > 0 {
+			chunk, ok := nextChunk()
+			if !ok {
+				break
+			}
+			peer := idle[0]
+			idle = idle[1:]
+			inFlight++
+			go func() { responses <- p.fetchChunk(ctx, peer, chunk) }()
+		}
+		if inFlight == 0 {
+			// Either we're done, or no peer is left to ask.
+			return res, nil
+		}
+
+		var resp *catchUpResponse
+		select {
+		case resp = <-responses:
+			inFlight--
+		case <-ctx.Done():
+			return res, ctx.Err()
+		}
+
+		switch {
+		case resp.err != nil:
+			log.Debugw("failed to fetch certificates to catch up", "peer", resp.peer, "first", resp.chunk.first, "error", resp.err)
+			res.Failed = append(res.Failed, resp.peer)
+			exclude(resp.peer)
+			retry(resp.chunk)
+			continue
+		case len(resp.certificates) == 0:
+			// The peer doesn't have these certificates (yet), so it won't have later ones either.
+			exclude(resp.peer)
+			retry(resp.chunk)
+			continue
+		}
+		res.ReceivedCertificates += uint64(len(resp.certificates))
+		if received := uint64(len(resp.certificates)); received < resp.chunk.limit {
+			retry(catchUpChunk{first: resp.chunk.first + received, limit: resp.chunk.limit - received})
+			resp.chunk.limit = received
+		}
+		received[resp.chunk.first] = resp
+		if _, ok := excluded[resp.peer]; !ok {
+			idle = append(idle, resp.peer)
+		}
+
+		// Validate and store the chunks we can, in order.
+		for {
+			resp, ok := received[p.NextInstance]
+			if !ok {
+				break
+			}
+			delete(received, p.NextInstance)
+
+			next, _, pt, err := certs.ValidateFinalityCertificates(
+				p.SignatureVerifier, p.NetworkName, p.PowerTable, p.NextInstance, nil,
+				resp.certificates...,
+			)
+			// Store the certificates that validated, even if some did not.
+			for _, cert := range resp.certificates[:next-p.NextInstance] {
+				if l := p.Store.Latest(); l == nil || cert.GPBFTInstance > l.GPBFTInstance {
+					if err := p.Store.Put(ctx, cert); err != nil {
+						return nil, err
+					}
+					res.NewCertificates++
+				}
+			}
+			if next > p.NextInstance {
+				p.NextInstance = next
+				p.PowerTable = pt
+			}
+			if err != nil {
+				log.Warnw("received invalid certificates while catching up", "peer", resp.peer, "instance", next, "error", err)
+				res.Invalid = append(res.Invalid, resp.peer)
+				exclude(resp.peer)
+				retry(catchUpChunk{first: next, limit: resp.chunk.first + resp.chunk.limit - next})
+				break
+			}
+		}
+	}
+}
+
+// fetchChunk requests the certificates in the given chunk from the given peer.
+func (p *Poller) fetchChunk(ctx context.Context, peer peer.ID, chunk catchUpChunk) *catchUpResponse {
+	resp := &catchUpResponse{chunk: chunk, peer: peer}
+	_, ch, err := p.RequestV2(ctx, peer, &certexchange.RequestV2{
+		FirstInstance: chunk.first,
+		Limit:         chunk.limit,
+	})
+	if err != nil {
+		resp.err = err
+		return resp
+	}
+	for cert := range ch {
+		resp.certificates = append(resp.certificates, cert)
+	}
+	return resp
+}
@@ -3,6 +3,7 @@ package polling_test
 import (
 	"context"
 	"math/rand"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/filecoin-project/go-f3/certexchange"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/ipfs/go-datastore"
 	ds_sync "github.com/ipfs/go-datastore/sync"
+	"github.com/libp2p/go-libp2p/core/peer"
 	mocknetwork "github.com/libp2p/go-libp2p/p2p/net/mock"
 	"github.com/stretchr/testify/require"
 )
@@ -62,7 +64,7 @@ func TestPoller(t *testing.T) {
 		NetworkName: polling.TestNetworkName,
 	}
 
-	poller, err := polling.NewPoller(ctx, &client, clientCs, backend)
+	poller, err := polling.NewPoller(ctx, &client, polling.TestNetworkName, clientCs, backend)
 	require.NoError(t, err)
 
 	require.NoError(t, mocknet.ConnectAllButSelf())
@@ -142,3 +144,277 @@ func TestPoller(t *testing.T) {
 		require.Equal(t, polling.PollFailed, res.Status)
 	}
 }
+
+func TestPollerHTTP(t *testing.T) {
+	backend := signing.NewFakeBackend()
+	rng := rand.New(rand.NewSource(1234))
+
+	cg := polling.MakeCertificates(t, rng, backend)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	serverDs := ds_sync.MutexWrap(datastore.NewMapDatastore())
+	serverCs, err := certstore.CreateStore(ctx, serverDs, 0, cg.PowerTable)
+	require.NoError(t, err)
+	for cg.NextInstance < 300 {
+		require.NoError(t, serverCs.Put(ctx, cg.MakeCertificate()))
+	}
+
+	server := certexchange.Server{
+		NetworkName: polling.TestNetworkName,
+		Store:       serverCs,
+	}
+	require.NoError(t, server.Start(ctx))
+	t.Cleanup(func() { require.NoError(t, server.Stop(context.Background())) })
+	httpServer := httptest.NewServer(server.HTTPHandler())
+	t.Cleanup(httpServer.Close)
+
+	clientDs := ds_sync.MutexWrap(datastore.NewMapDatastore())
+	clientCs, err := certstore.CreateStore(ctx, clientDs, 0, cg.PowerTable)
+	require.NoError(t, err)
+
+	const serverID peer.ID = "http-server"
+	client := certexchange.HTTPClient{
+		Servers:     map[peer.ID]string{serverID: httpServer.URL},
+		NetworkName: polling.TestNetworkName,
+	}
+	poller, err := polling.NewPoller(ctx, &client, polling.TestNetworkName, clientCs, backend)
+	require.NoError(t, err)
+
+	// Polls over HTTP just like over libp2p, across several requests.
+	res, err := poller.Poll(ctx, serverID)
+	require.NoError(t, err)
+	require.Equal(t, polling.PollHit, res.Status)
+	require.Equal(t, cg.NextInstance, poller.NextInstance)
+	require.Equal(t, cg.NextInstance-1, clientCs.Latest().GPBFTInstance)
+
+	// And catches evil servers the same way.
+	badCert := cg.MakeCertificate()
+	badCert.Signature = []byte("bad sig")
+	require.NoError(t, serverCs.Put(ctx, badCert))
+
+	res, err = poller.Poll(ctx, serverID)
+	require.NoError(t, err)
+	require.Equal(t, polling.PollIllegal, res.Status)
+}
+
+func TestPollerPruned(t *testing.T) {
+	backend := signing.NewFakeBackend()
+	rng := rand.New(rand.NewSource(1234))
+
+	cg := polling.MakeCertificates(t, rng, backend)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Certificates are pruned at the granularity of the stored power tables, every 1440
+	// instances.
+	serverDs := ds_sync.MutexWrap(datastore.NewMapDatastore())
+	serverCs, err := certstore.CreateStore(ctx, serverDs, 0, cg.PowerTable, certstore.WithRetention(10))
+	require.NoError(t, err)
+	for cg.NextInstance < 1500 {
+		require.NoError(t, serverCs.Put(ctx, cg.MakeCertificate()))
+	}
+	require.NoError(t, serverCs.Prune(ctx))
+	require.NotZero(t, serverCs.PrunedBefore())
+
+	server := certexchange.Server{
+		NetworkName: polling.TestNetworkName,
+		Store:       serverCs,
+	}
+	require.NoError(t, server.Start(ctx))
+	t.Cleanup(func() { require.NoError(t, server.Stop(context.Background())) })
+	httpServer := httptest.NewServer(server.HTTPHandler())
+	t.Cleanup(httpServer.Close)
+
+	clientDs := ds_sync.MutexWrap(datastore.NewMapDatastore())
+	clientCs, err := certstore.CreateStore(ctx, clientDs, 0, cg.PowerTable)
+	require.NoError(t, err)
+
+	const serverID peer.ID = "http-server"
+	client := certexchange.HTTPClient{
+		Servers:     map[peer.ID]string{serverID: httpServer.URL},
+		NetworkName: polling.TestNetworkName,
+	}
+	poller, err := polling.NewPoller(ctx, &client, polling.TestNetworkName, clientCs, backend)
+	require.NoError(t, err)
+
+	// The server has pruned the certificates we need, which is a miss rather than a failure.
+	res, err := poller.Poll(ctx, serverID)
+	require.NoError(t, err)
+	require.Equal(t, polling.PollMiss, res.Status)
+	require.Zero(t, res.ReceivedCertificates)
+	require.Zero(t, poller.NextInstance)
+}
+
+func TestPollerOverQuota(t *testing.T) {
+	backend := signing.NewFakeBackend()
+	rng := rand.New(rand.NewSource(1234))
+
+	cg := polling.MakeCertificates(t, rng, backend)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	serverDs := ds_sync.MutexWrap(datastore.NewMapDatastore())
+	serverCs, err := certstore.CreateStore(ctx, serverDs, 0, cg.PowerTable)
+	require.NoError(t, err)
+	for cg.NextInstance < 30 {
+		require.NoError(t, serverCs.Put(ctx, cg.MakeCertificate()))
+	}
+
+	server := certexchange.Server{
+		NetworkName:              polling.TestNetworkName,
+		Store:                    serverCs,
+		MaxCertificatesPerMinute: 10,
+	}
+	require.NoError(t, server.Start(ctx))
+	t.Cleanup(func() { require.NoError(t, server.Stop(context.Background())) })
+	httpServer := httptest.NewServer(server.HTTPHandler())
+	t.Cleanup(httpServer.Close)
+
+	clientDs := ds_sync.MutexWrap(datastore.NewMapDatastore())
+	clientCs, err := certstore.CreateStore(ctx, clientDs, 0, cg.PowerTable)
+	require.NoError(t, err)
+
+	const serverID peer.ID = "http-server"
+	client := certexchange.HTTPClient{
+		Servers:     map[peer.ID]string{serverID: httpServer.URL},
+		NetworkName: polling.TestNetworkName,
+	}
+	poller, err := polling.NewPoller(ctx, &client, polling.TestNetworkName, clientCs, backend)
+	require.NoError(t, err)
+
+	// We get what the server's budget allows for.
+	res, err := poller.Poll(ctx, serverID)
+	require.NoError(t, err)
+	require.Equal(t, polling.PollHit, res.Status)
+	require.Equal(t, uint64(10), res.ReceivedCertificates)
+
+	// Then the server is over quota, which isn't a failure.
+	res, err = poller.Poll(ctx, serverID)
+	require.NoError(t, err)
+	require.Equal(t, polling.PollOverQuota, res.Status)
+	require.Zero(t, res.ReceivedCertificates)
+	require.Equal(t, uint64(10), poller.NextInstance)
+}
+
+func TestPollerCatchUpFrom(t *testing.T) {
+	backend := signing.NewFakeBackend()
+	rng := rand.New(rand.NewSource(1234))
+
+	cg := polling.MakeCertificates(t, rng, backend)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mocknet := mocknetwork.New()
+
+	clientHost, err := mocknet.GenPeer()
+	require.NoError(t, err)
+
+	// Two honest servers, one that serves a certificate with a bad signature, and one that
+	// doesn't serve certificates at all.
+	const certCount = 1500
+	const badInstance = 600
+	stores := make([]*certstore.Store, 3)
+	for i := range stores {
+		ds := ds_sync.MutexWrap(datastore.NewMapDatastore())
+		stores[i], err = certstore.CreateStore(ctx, ds, 0, cg.PowerTable)
+		require.NoError(t, err)
+	}
+	for cg.NextInstance < certCount {
+		cert := cg.MakeCertificate()
+		require.NoError(t, stores[0].Put(ctx, cert))
+		if cert.GPBFTInstance == badInstance {
+			badCert := *cert
+			badCert.Signature = []byte("bad sig")
+			require.NoError(t, stores[1].Put(ctx, &badCert))
+		} else {
+			require.NoError(t, stores[1].Put(ctx, cert))
+		}
+		require.NoError(t, stores[2].Put(ctx, cert))
+	}
+
+	// The order of the peers determines which chunks they're asked for first: the evil peer is
+	// asked for the second chunk, which contains the bad certificate.
+	peers := make([]peer.ID, 4)
+	for i := range peers {
+		h, err := mocknet.GenPeer()
+		require.NoError(t, err)
+		peers[i] = h.ID()
+
+		var store *certstore.Store
+		switch i {
+		case 0:
+			store = stores[0]
+		case 1:
+			store = stores[1]
+		case 2:
+			// Doesn't serve certificates.
+			continue
+		case 3:
+			store = stores[2]
+		}
+		server := certexchange.Server{
+			NetworkName: polling.TestNetworkName,
+			Host:        h,
+			Store:       store,
+		}
+		require.NoError(t, server.Start(ctx))
+		t.Cleanup(func() { require.NoError(t, server.Stop(context.Background())) })
+	}
+
+	require.NoError(t, mocknet.LinkAll())
+
+	clientDs := ds_sync.MutexWrap(datastore.NewMapDatastore())
+	clientCs, err := certstore.CreateStore(ctx, clientDs, 0, cg.PowerTable)
+	require.NoError(t, err)
+
+	client := certexchange.Client{
+		Host:        clientHost,
+		NetworkName: polling.TestNetworkName,
+	}
+
+	poller, err := polling.NewPoller(ctx, &client, polling.TestNetworkName, clientCs, backend)
+	require.NoError(t, err)
+
+	require.NoError(t, mocknet.ConnectAllButSelf())
+
+	// Polling a peer that far ahead stops after the first response.
+	{
+		res, err := poller.Poll(ctx, peers[0])
+		require.NoError(t, err)
+		require.Equal(t, polling.PollHit, res.Status)
+		require.EqualValues(t, certCount, res.PendingInstance)
+		require.Less(t, poller.NextInstance, uint64(certCount))
+	}
+
+	// Catching up from all peers gets us the rest, and reports the peers that misbehaved.
+	{
+		next := poller.NextInstance
+		res, err := poller.CatchUpFrom(ctx, peers, certCount)
+		require.NoError(t, err)
+		require.Equal(t, []peer.ID{peers[1]}, res.Invalid)
+		require.Equal(t, []peer.ID{peers[2]}, res.Failed)
+		require.EqualValues(t, certCount-next, res.NewCertificates)
+		require.EqualValues(t, certCount, poller.NextInstance)
+
+		latest := clientCs.Latest()
+		require.NotNil(t, latest)
+		require.EqualValues(t, certCount-1, latest.GPBFTInstance)
+		cert, err := clientCs.Get(ctx, badInstance)
+		require.NoError(t, err)
+		require.NotEqual(t, []byte("bad sig"), cert.Signature)
+	}
+
+	// Catching up when there's nothing left to fetch does nothing.
+	{
+		res, err := poller.CatchUpFrom(ctx, peers, certCount)
+		require.NoError(t, err)
+		require.Zero(t, res.ReceivedCertificates)
+		require.Empty(t, res.Failed)
+		require.Empty(t, res.Invalid)
+	}
+}
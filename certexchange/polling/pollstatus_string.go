@@ -12,11 +12,12 @@ func _() {
 	_ = x[PollHit-1]
 	_ = x[PollFailed-2]
 	_ = x[PollIllegal-3]
+	_ = x[PollOverQuota-4]
 }
 
-const _PollStatus_name = "PollMissPollHitPollFailedPollIllegal"
+const _PollStatus_name = "PollMissPollHitPollFailedPollIllegalPollOverQuota"
 
-var _PollStatus_index = [...]uint8{0, 8, 15, 25, 36}
+var _PollStatus_index = [...]uint8{0, 8, 15, 25, 36, 49}
 
 func (i PollStatus) String() string {
 	if i < 0 || i >= PollStatus(len(_PollStatus_index)-1) {
@@ -2,21 +2,45 @@ package polling
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/filecoin-project/go-f3/internal/measurements"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
 	"github.com/libp2p/go-libp2p/core/peer"
 	"go.opentelemetry.io/otel/metric"
 
 	"github.com/filecoin-project/go-f3/certexchange"
+	"github.com/filecoin-project/go-f3/certs"
 	"github.com/filecoin-project/go-f3/certstore"
 	"github.com/filecoin-project/go-f3/gpbft"
 	"github.com/filecoin-project/go-f3/internal/clock"
 )
 
-const maxRequestLength = 256
+const (
+	maxRequestLength = 256
+	// The number of instances a peer must be ahead of us by for us to catch up from several
+	// peers at once, rather than from one peer at a time.
+	parallelCatchUpThreshold = 4 * maxRequestLength
+	// The maximum number of requests in flight while catching up from several peers at once.
+	maxParallelRequests = 8
+	// How far ahead of the latest validated instance to fetch certificates while catching up.
+	catchUpWindow = 2 * maxParallelRequests * maxRequestLength
+	// How long after the time given by the schedule hint to poll, to leave the next certificate
+	// time to propagate.
+	scheduleHintMargin = time.Second
+)
+
+// ScheduleHint estimates when the finality certificate following the given one will be available,
+// returning false if it can't tell.
+type ScheduleHint func(ctx context.Context, latest *certs.FinalityCertificate) (time.Time, bool)
 
 // A polling Subscriber will continuously poll the network for new finality certificates.
 type Subscriber struct {
@@ -27,11 +51,39 @@ type Subscriber struct {
 	InitialPollInterval time.Duration
 	MaximumPollInterval time.Duration
 	MinimumPollInterval time.Duration
-
-	peerTracker *peerTracker
-	poller      *Poller
-	discoverCh  <-chan peer.ID
-	clock       clock.Clock
+	// If set, certificates are announced on the topic named by
+	// certexchange.AnnouncementTopicName as soon as they are stored, and certificates announced
+	// by other peers are fetched from them right away. Polling continues regardless, but only
+	// makes network requests when announcements don't keep us up to date.
+	PubSub *pubsub.PubSub
+	// If set, the records of certificate exchange peers are saved to the datastore periodically
+	// and on stop, and restored on start, so that the subscriber doesn't have to learn which
+	// peers are useful all over again. Peers not seen for a week are forgotten.
+	Datastore datastore.Datastore
+	// If set, the certificates missing below the first instance of the store, e.g. because it was
+	// bootstrapped from a snapshot, are fetched from peers walking forwards from BackfillTo,
+	// typically the initial instance of the network, where the power table is trusted to have the
+	// CID BackfillPowerTable. The certificates are validated starting from that power table, and
+	// only become part of the store once they lead up to its first instance. The store must not
+	// prune certificates.
+	Backfill           bool
+	BackfillTo         uint64
+	BackfillPowerTable cid.Cid
+	// The mechanisms to find peers to poll with. Defaults to ConnectedPeers if empty.
+	Discovery []Discovery
+	// If set, the next poll is scheduled just after the time the next certificate is expected
+	// at, rather than at the interval predicted from past progress, for as long as that time is
+	// in the future. Polls remain at most MaximumPollInterval apart.
+	ScheduleHint ScheduleHint
+
+	peerTracker   *peerTracker
+	peerRecords   atomic.Pointer[[]PeerRecord]
+	lastSaved     time.Time
+	poller        *Poller
+	backfilling   *certstore.Backfill
+	discoverCh    <-chan peer.ID
+	announcements <-chan announcement
+	clock         clock.Clock
 
 	wg   sync.WaitGroup
 	stop context.CancelFunc
@@ -44,17 +96,39 @@ func (s *Subscriber) Start(startCtx context.Context) error {
 
 	var err error
 
+	if s.Backfill && !s.BackfillPowerTable.Defined() {
+		cancel()
+		return errors.New("backfilling requires the CID of the power table to backfill from")
+	}
+
 	s.peerTracker = newPeerTracker(s.clock)
-	s.poller, err = NewPoller(startCtx, &s.Client, s.Store, s.SignatureVerifier)
+	if s.Datastore != nil {
+		if err := s.loadPeerRecords(startCtx); err != nil {
+			cancel()
+			return err
+		}
+		s.lastSaved = s.clock.Now()
+	}
+	s.updatePeerRecords(startCtx)
+	s.poller, err = NewPoller(startCtx, &s.Client, s.NetworkName, s.Store, s.SignatureVerifier)
 	if err != nil {
 		return err
 	}
 
-	s.discoverCh, err = discoverPeers(ctx, s.Host, s.NetworkName)
+	s.discoverCh, err = discoverPeers(ctx, s.Host, s.NetworkName, s.Discovery)
 	if err != nil {
+		cancel()
 		return err
 	}
 
+	if s.PubSub != nil {
+		s.announcements, err = s.startAnnouncing(ctx)
+		if err != nil {
+			cancel()
+			return err
+		}
+	}
+
 	s.wg.Add(1)
 	go func() {
 		defer func() {
@@ -63,6 +137,11 @@ func (s *Subscriber) Start(startCtx context.Context) error {
 			// and wait for discovery to exit.
 			for range s.discoverCh {
 			}
+			if s.Datastore != nil {
+				if err := s.savePeerRecords(context.Background(), s.peerTracker.records()); err != nil {
+					log.Errorw("failed to save certificate exchange peer records", "err", err)
+				}
+			}
 
 			// then we're done
 			s.wg.Done()
@@ -95,10 +174,61 @@ func (s *Subscriber) run(ctx context.Context) error {
 		s.MaximumPollInterval,
 	)
 
+	// Backfill as fast as peers serve certificates, and retry as rarely as we poll at most when
+	// they don't.
+	var (
+		backfillTimer *clock.Timer
+		backfillC     <-chan time.Time
+	)
+	if s.Backfill {
+		backfillTimer = s.clock.Timer(0)
+		defer backfillTimer.Stop()
+		backfillC = backfillTimer.C
+	}
+
+	// The progress made by fetching announced certificates since we last polled.
+	var announcedProgress uint64
+	// The instance and time at which the schedule hint last expected a certificate, to measure
+	// how far off it was once the certificate is received.
+	var (
+		expectedInstance uint64
+		expectedAt       time.Time
+	)
+	recordPredictionError := func() {
+		if !expectedAt.IsZero() && s.poller.NextInstance > expectedInstance {
+			metrics.predictionError.Record(ctx, s.clock.Since(expectedAt).Seconds())
+			expectedAt = time.Time{}
+		}
+	}
 	for ctx.Err() == nil {
 		select {
 		case p := <-s.discoverCh:
 			s.peerTracker.peerSeen(p)
+		case a, ok := <-s.announcements:
+			if !ok {
+				s.announcements = nil
+				continue
+			}
+			progress, err := s.fetchAnnounced(ctx, a)
+			if err != nil {
+				return err
+			}
+			announcedProgress += progress
+			recordPredictionError()
+		case <-backfillC:
+			backfilled, err := s.backfill(ctx)
+			switch {
+			case err != nil:
+				log.Errorw("stopped backfilling finality certificates", "error", err)
+				backfillC = nil
+			case s.Store.FirstInstance() <= s.BackfillTo:
+				log.Infow("finished backfilling finality certificates", "to", s.BackfillTo)
+				backfillC = nil
+			case backfilled > 0:
+				backfillTimer.Reset(0)
+			default:
+				backfillTimer.Reset(s.MaximumPollInterval)
+			}
 		case pollTime := <-timer.C:
 			// First, see if we made progress locally. If we have, update
 			// interval prediction based on that local progress. If our interval
@@ -109,6 +239,8 @@ func (s *Subscriber) run(ctx context.Context) error {
 			if err != nil {
 				return err
 			}
+			progress += announcedProgress
+			announcedProgress = 0
 			// Otherwise, poll the network.
 			var offset time.Duration
 			if progress == 0 {
@@ -126,14 +258,22 @@ func (s *Subscriber) run(ctx context.Context) error {
 				}
 			}
 
+			recordPredictionError()
+
 			nextInterval := predictor.update(progress)
 			nextPollTime := pollTime.Add(nextInterval)
 			delay := max(s.clock.Until(nextPollTime), 0)
 			delay += max(offset, delay/2) // Offset the delay by at most half the predicted interval.
 			log.Debugf("predicted interval is %s (waiting %s)", nextInterval, delay)
+			if at, ok := s.scheduleHint(ctx); ok {
+				delay = min(s.clock.Until(at)+scheduleHintMargin, s.MaximumPollInterval)
+				expectedInstance, expectedAt = s.poller.NextInstance, at
+				log.Debugf("next certificate expected at %s (waiting %s)", at, delay)
+			}
 			timer.Reset(delay)
 
 			metrics.predictedPollingInterval.Record(ctx, delay.Seconds())
+			s.updatePeerRecords(ctx)
 		case <-ctx.Done():
 			return ctx.Err()
 		}
@@ -141,6 +281,24 @@ func (s *Subscriber) run(ctx context.Context) error {
 	return ctx.Err()
 }
 
+// scheduleHint returns when the next certificate is expected according to the schedule hint, if
+// any, and only if that is in the future. Otherwise, either the hint is wrong or the certificate
+// is late, and we're better off polling at the predicted interval.
+func (s *Subscriber) scheduleHint(ctx context.Context) (time.Time, bool) {
+	if s.ScheduleHint == nil {
+		return time.Time{}, false
+	}
+	latest := s.Store.Latest()
+	if latest == nil {
+		return time.Time{}, false
+	}
+	at, ok := s.ScheduleHint(ctx, latest)
+	if !ok || !at.After(s.clock.Now()) {
+		return time.Time{}, false
+	}
+	return at, true
+}
+
 // Polls peers for new certificates, returning:
 //
 //  1. The total progress made (including certificates not received from polled peers).
@@ -195,6 +353,8 @@ func (s *Subscriber) poll(ctx context.Context) (_progress uint64, _new bool, _er
 			s.peerTracker.updateLatency(peer, res.Latency)
 		case PollFailed:
 			s.peerTracker.recordFailure(peer)
+		case PollOverQuota:
+			// Try again later, without holding it against the peer.
 		case PollIllegal:
 			s.peerTracker.recordInvalid(peer)
 		default:
@@ -209,6 +369,23 @@ func (s *Subscriber) poll(ctx context.Context) (_progress uint64, _new bool, _er
 
 		newCertificatesReceived += res.NewCertificates
 		certificatesReceived += res.ReceivedCertificates
+
+		// If we're far behind, fetch the rest from all the peers we've chosen at once.
+		if res.PendingInstance > s.poller.NextInstance+parallelCatchUpThreshold {
+			cres, err := s.poller.CatchUpFrom(ctx, peers, res.PendingInstance)
+			if err != nil {
+				return start - s.poller.NextInstance, newCertificatesReceived > 0, err
+			}
+			log.Debugf("caught up from %d peers to instance %d, got %+v", len(peers), s.poller.NextInstance, cres)
+			for _, p := range cres.Failed {
+				s.peerTracker.recordFailure(p)
+			}
+			for _, p := range cres.Invalid {
+				s.peerTracker.recordInvalid(p)
+			}
+			newCertificatesReceived += cres.NewCertificates
+			certificatesReceived += cres.ReceivedCertificates
+		}
 	}
 
 	// If we received any certificates, record which peers had them and which peers didn't. This
@@ -237,3 +414,55 @@ func (s *Subscriber) poll(ctx context.Context) (_progress uint64, _new bool, _er
 
 	return start - s.poller.NextInstance, newCertificatesReceived > 0, nil
 }
+
+// PeerRecords returns a snapshot of the certificate exchange peers tracked by the subscriber, from
+// best to worst. The snapshot is refreshed every time the subscriber polls.
+func (s *Subscriber) PeerRecords() []PeerRecord {
+	if records := s.peerRecords.Load(); records != nil {
+		return slices.Clone(*records)
+	}
+	return nil
+}
+
+var peerRecordsKey = datastore.NewKey("/peers")
+
+// updatePeerRecords refreshes the snapshot of peer records, and saves it if it's time to.
+func (s *Subscriber) updatePeerRecords(ctx context.Context) {
+	records := s.peerTracker.records()
+	s.peerRecords.Store(&records)
+	if s.Datastore != nil && s.clock.Since(s.lastSaved) >= peerRecordSaveInterval {
+		if err := s.savePeerRecords(ctx, records); err != nil {
+			log.Warnw("failed to save certificate exchange peer records", "err", err)
+		}
+		s.lastSaved = s.clock.Now()
+	}
+}
+
+func (s *Subscriber) loadPeerRecords(ctx context.Context) error {
+	b, err := s.Datastore.Get(ctx, peerRecordsKey)
+	if errors.Is(err, datastore.ErrNotFound) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("loading certificate exchange peer records: %w", err)
+	}
+	var records []PeerRecord
+	if err := json.Unmarshal(b, &records); err != nil {
+		// Not worth failing over, we'll just learn about our peers again.
+		log.Warnw("ignoring corrupt certificate exchange peer records", "err", err)
+		return nil
+	}
+	s.peerTracker.restore(records, peerRecordMaxAge)
+	log.Debugw("restored certificate exchange peer records", "saved", len(records), "restored", len(s.peerTracker.peers))
+	return nil
+}
+
+func (s *Subscriber) savePeerRecords(ctx context.Context, records []PeerRecord) error {
+	b, err := json.Marshal(records)
+	if err != nil {
+		return fmt.Errorf("encoding certificate exchange peer records: %w", err)
+	}
+	if err := s.Datastore.Put(ctx, peerRecordsKey, b); err != nil {
+		return fmt.Errorf("saving certificate exchange peer records: %w", err)
+	}
+	return nil
+}
@@ -9,12 +9,14 @@ import (
 
 	"github.com/filecoin-project/go-f3/certexchange"
 	"github.com/filecoin-project/go-f3/certexchange/polling"
+	"github.com/filecoin-project/go-f3/certs"
 	"github.com/filecoin-project/go-f3/certstore"
 	"github.com/filecoin-project/go-f3/internal/clock"
 	"github.com/filecoin-project/go-f3/sim/signing"
 
 	"github.com/ipfs/go-datastore"
 	ds_sync "github.com/ipfs/go-datastore/sync"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
 	mocknetwork "github.com/libp2p/go-libp2p/p2p/net/mock"
 	"github.com/stretchr/testify/require"
 )
@@ -117,3 +119,250 @@ func TestSubscriber(t *testing.T) {
 		}
 	}
 }
+
+func TestSubscriberScheduleHint(t *testing.T) {
+	backend := signing.NewFakeBackend()
+	rng := rand.New(rand.NewSource(1234))
+
+	cg := polling.MakeCertificates(t, rng, backend)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx, clk := clock.WithMockClock(ctx)
+	defer cancel()
+
+	mocknet := mocknetwork.New()
+
+	clientHost, err := mocknet.GenPeer()
+	require.NoError(t, err)
+	serverHost, err := mocknet.GenPeer()
+	require.NoError(t, err)
+
+	serverDs := ds_sync.MutexWrap(datastore.NewMapDatastore())
+	serverCs, err := certstore.CreateStore(ctx, serverDs, 0, cg.PowerTable)
+	require.NoError(t, err)
+	require.NoError(t, serverCs.Put(ctx, cg.MakeCertificate()))
+
+	server := certexchange.Server{
+		NetworkName: polling.TestNetworkName,
+		Host:        serverHost,
+		Store:       serverCs,
+	}
+	require.NoError(t, mocknet.LinkAll())
+	require.NoError(t, server.Start(ctx))
+	t.Cleanup(func() { require.NoError(t, server.Stop(context.Background())) })
+
+	clientDs := ds_sync.MutexWrap(datastore.NewMapDatastore())
+	clientCs, err := certstore.CreateStore(ctx, clientDs, 0, cg.PowerTable)
+	require.NoError(t, err)
+
+	// Expect a certificate every 10 minutes, far longer than the predicted interval.
+	const certificateInterval = 10 * time.Minute
+	expectedAt := func(instance uint64) time.Time {
+		return time.Unix(0, 0).Add(time.Duration(instance) * certificateInterval)
+	}
+	subscriber := polling.Subscriber{
+		Client: certexchange.Client{
+			Host:        clientHost,
+			NetworkName: polling.TestNetworkName,
+		},
+		Store:               clientCs,
+		SignatureVerifier:   backend,
+		MinimumPollInterval: time.Millisecond,
+		MaximumPollInterval: time.Hour,
+		InitialPollInterval: time.Second,
+		ScheduleHint: func(_ context.Context, latest *certs.FinalityCertificate) (time.Time, bool) {
+			return expectedAt(latest.GPBFTInstance + 1), true
+		},
+	}
+
+	require.NoError(t, subscriber.Start(ctx))
+	t.Cleanup(func() { require.NoError(t, subscriber.Stop(context.Background())) })
+
+	require.NoError(t, mocknet.ConnectAllButSelf())
+
+	waitForInstance := func(instance uint64) time.Time {
+		require.Eventually(t, func() bool {
+			if latest := clientCs.Latest(); latest != nil && latest.GPBFTInstance == instance {
+				return true
+			}
+			clk.Add(5 * time.Second)
+			return false
+		}, 10*time.Second, 10*time.Millisecond)
+		return clk.Now()
+	}
+
+	waitForInstance(0)
+	for instance := uint64(1); instance <= 3; instance++ {
+		require.NoError(t, serverCs.Put(ctx, cg.MakeCertificate()))
+		// The certificate is available right away, but we only poll for it once it's expected.
+		receivedAt := waitForInstance(instance)
+		require.False(t, receivedAt.Before(expectedAt(instance)))
+		require.Less(t, receivedAt.Sub(expectedAt(instance)), time.Minute)
+	}
+}
+
+func TestSubscriberBackfill(t *testing.T) {
+	backend := signing.NewFakeBackend()
+	rng := rand.New(rand.NewSource(1234))
+
+	cg := polling.MakeCertificates(t, rng, backend)
+	certificates := make([]*certs.FinalityCertificate, 600)
+	for i := range certificates {
+		certificates[i] = cg.MakeCertificate()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx, clk := clock.WithMockClock(ctx)
+	defer cancel()
+
+	mocknet := mocknetwork.New()
+
+	clientHost, err := mocknet.GenPeer()
+	require.NoError(t, err)
+
+	servers := make([]*certexchange.Server, 3)
+	for i := range servers {
+		h, err := mocknet.GenPeer()
+		require.NoError(t, err)
+
+		ds := ds_sync.MutexWrap(datastore.NewMapDatastore())
+		cs, err := certstore.CreateStore(ctx, ds, 0, cg.PowerTable)
+		require.NoError(t, err)
+		for _, cert := range certificates {
+			require.NoError(t, cs.Put(ctx, cert))
+		}
+
+		servers[i] = &certexchange.Server{
+			NetworkName: polling.TestNetworkName,
+			Host:        h,
+			Store:       cs,
+		}
+	}
+
+	require.NoError(t, mocknet.LinkAll())
+
+	for _, server := range servers {
+		require.NoError(t, server.Start(ctx))
+		t.Cleanup(func() { require.NoError(t, server.Stop(context.Background())) })
+	}
+
+	// The client starts from a later instance, as if bootstrapped from a snapshot, and backfills
+	// from the initial power table it trusts.
+	initialPowerTableCid, err := certs.MakePowerTableCID(cg.PowerTable)
+	require.NoError(t, err)
+	clientDs := ds_sync.MutexWrap(datastore.NewMapDatastore())
+	clientCs, err := certstore.CreateStore(ctx, clientDs, 500, cg.PowerTable)
+	require.NoError(t, err)
+	for _, cert := range certificates[500:] {
+		require.NoError(t, clientCs.Put(ctx, cert))
+	}
+
+	subscriber := polling.Subscriber{
+		Client: certexchange.Client{
+			Host:        clientHost,
+			NetworkName: polling.TestNetworkName,
+		},
+		Store:               clientCs,
+		SignatureVerifier:   backend,
+		MinimumPollInterval: time.Millisecond,
+		MaximumPollInterval: time.Second,
+		InitialPollInterval: 100 * time.Millisecond,
+		Backfill:            true,
+		BackfillPowerTable:  initialPowerTableCid,
+	}
+
+	require.NoError(t, subscriber.Start(ctx))
+	t.Cleanup(func() { require.NoError(t, subscriber.Stop(context.Background())) })
+
+	require.NoError(t, mocknet.ConnectAllButSelf())
+
+	require.Eventually(t, func() bool {
+		if clientCs.FirstInstance() == 0 {
+			return true
+		}
+		clk.Add(time.Second)
+		return false
+	}, 10*time.Second, time.Millisecond)
+
+	for i, expected := range certificates[:500] {
+		cert, err := clientCs.Get(ctx, uint64(i))
+		require.NoError(t, err)
+		expectedCid, err := certs.MakeCertificateCID(expected)
+		require.NoError(t, err)
+		certCid, err := certs.MakeCertificateCID(cert)
+		require.NoError(t, err)
+		require.Equal(t, expectedCid, certCid)
+	}
+}
+
+func TestSubscriberAnnouncements(t *testing.T) {
+	backend := signing.NewFakeBackend()
+	rng := rand.New(rand.NewSource(1234))
+
+	cg := polling.MakeCertificates(t, rng, backend)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mocknet := mocknetwork.New()
+
+	// Poll rarely enough that the client can only keep up through announcements.
+	subscribers := make([]*polling.Subscriber, 2)
+	for i := range subscribers {
+		h, err := mocknet.GenPeer()
+		require.NoError(t, err)
+		ps, err := pubsub.NewGossipSub(ctx, h, pubsub.WithFloodPublish(true))
+		require.NoError(t, err)
+
+		ds := ds_sync.MutexWrap(datastore.NewMapDatastore())
+		cs, err := certstore.CreateStore(ctx, ds, 0, cg.PowerTable)
+		require.NoError(t, err)
+
+		server := &certexchange.Server{
+			NetworkName: polling.TestNetworkName,
+			Host:        h,
+			Store:       cs,
+		}
+		require.NoError(t, server.Start(ctx))
+		t.Cleanup(func() { require.NoError(t, server.Stop(context.Background())) })
+
+		subscribers[i] = &polling.Subscriber{
+			Client: certexchange.Client{
+				Host:        h,
+				NetworkName: polling.TestNetworkName,
+			},
+			Store:               cs,
+			SignatureVerifier:   backend,
+			MinimumPollInterval: time.Hour,
+			MaximumPollInterval: time.Hour,
+			InitialPollInterval: time.Hour,
+			PubSub:              ps,
+		}
+	}
+
+	require.NoError(t, mocknet.LinkAll())
+	for _, subscriber := range subscribers {
+		require.NoError(t, subscriber.Start(ctx))
+		t.Cleanup(func() { require.NoError(t, subscriber.Stop(context.Background())) })
+	}
+	require.NoError(t, mocknet.ConnectAllButSelf())
+
+	serverCs, clientCs := subscribers[0].Store, subscribers[1].Store
+	caughtUp := func() bool {
+		latest := clientCs.Latest()
+		return latest != nil && latest.GPBFTInstance == cg.NextInstance-1
+	}
+
+	// Keep finalizing instances until the pubsub mesh has formed and announcements get through.
+	for i := 0; i < 100 && !caughtUp(); i++ {
+		require.NoError(t, serverCs.Put(ctx, cg.MakeCertificate()))
+		time.Sleep(100 * time.Millisecond)
+	}
+	require.True(t, caughtUp())
+
+	// From then on, every certificate is fetched as soon as it is announced.
+	for range 5 {
+		require.NoError(t, serverCs.Put(ctx, cg.MakeCertificate()))
+		require.Eventually(t, caughtUp, 10*time.Second, 10*time.Millisecond)
+	}
+}
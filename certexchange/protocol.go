@@ -4,16 +4,51 @@ import (
 	"math"
 
 	"github.com/filecoin-project/go-f3/gpbft"
+	"github.com/ipfs/go-cid"
 	"github.com/libp2p/go-libp2p/core/protocol"
+	"github.com/multiformats/go-multihash"
 )
 
 func FetchProtocolName(nn gpbft.NetworkName) protocol.ID {
 	return protocol.ID("/f3/certexch/get/1/" + string(nn))
 }
 
+func FetchProtocolNameV2(nn gpbft.NetworkName) protocol.ID {
+	return protocol.ID("/f3/certexch/get/2/" + string(nn))
+}
+
+// PowerTableProtocolName returns the name of the protocol over which single power tables are
+// fetched by CID or by instance.
+func PowerTableProtocolName(nn gpbft.NetworkName) protocol.ID {
+	return protocol.ID("/f3/certexch/powertable/1/" + string(nn))
+}
+
+func SnapshotProtocolName(nn gpbft.NetworkName) protocol.ID {
+	return protocol.ID("/f3/snapshot/1/" + string(nn))
+}
+
+// AnnouncementTopicName returns the name of the pubsub topic on which peers announce the
+// finality certificates they have just stored.
+func AnnouncementTopicName(nn gpbft.NetworkName) string {
+	return "/f3/certexch/announce/1/" + string(nn)
+}
+
+// ProviderKey returns the key under which certificate exchange peers may advertise themselves in
+// content routing systems, such as the DHT, for others to discover them.
+func ProviderKey(nn gpbft.NetworkName) (cid.Cid, error) {
+	digest, err := multihash.Sum([]byte(FetchProtocolName(nn)), multihash.SHA2_256, -1)
+	if err != nil {
+		return cid.Undef, err
+	}
+	return cid.NewCidV1(cid.Raw, digest), nil
+}
+
 // Request unlimited certificates.
 const NoLimit uint64 = math.MaxUint64
 
+// Request a snapshot up to the latest instance available.
+const LatestSnapshot uint64 = math.MaxUint64
+
 type Request struct {
 	// First instance to fetch.
 	FirstInstance uint64
@@ -31,3 +66,110 @@ type ResponseHeader struct {
 	// Power table, if requested, or empty.
 	PowerTable gpbft.PowerEntries
 }
+
+// RequestV2 extends Request with power table checkpoints. It is served over the protocol named by
+// FetchProtocolNameV2.
+type RequestV2 struct {
+	// First instance to fetch.
+	FirstInstance uint64
+	// Max number of instances to fetch. The server may respond with fewer certificates than
+	// requested, even if more are available.
+	Limit uint64
+	// Include the full power table needed to validate the first finality certificate.
+	// Checked by the user against their last finality certificate.
+	IncludePowerTable bool
+	// If non-zero, list the CIDs of the power tables needed to validate every
+	// PowerTableCheckpointInterval-th finality certificate in the response, after the first one.
+	// This allows sub-ranges of the response to be validated in parallel.
+	PowerTableCheckpointInterval uint64
+}
+
+// ResponseStatus describes how a request was served.
+type ResponseStatus uint64
+
+const (
+	// StatusOK indicates that the request was served as usual.
+	StatusOK ResponseStatus = iota
+	// StatusPruned indicates that the server has pruned the requested certificates, or power
+	// table, and responded without them.
+	StatusPruned
+	// StatusOverQuota indicates that the request is over the limits of the server, which
+	// responded without certificates. The request should be retried later, or elsewhere.
+	StatusOverQuota
+	// StatusNotFound indicates that the server does not have the requested power table.
+	StatusNotFound
+)
+
+// ResponseHeaderV2 extends ResponseHeader with the status of the response and the requested
+// power table checkpoints.
+type ResponseHeaderV2 struct {
+	// The next instance to be finalized. This is 0 when no instances have been finalized.
+	PendingInstance uint64
+	// Power table, if requested, or empty.
+	PowerTable gpbft.PowerEntries
+	// The status of the response.
+	Status ResponseStatus
+	// The CIDs of the power tables at every requested interval after the first instance, for as
+	// long as the response includes the finality certificate at that instance. Each is committed
+	// to by the finality certificate before it, and checked by the user against it.
+	PowerTableCheckpoints []PowerTableCheckpoint
+}
+
+// PowerTableCheckpoint is the CID of the power table needed to validate the finality certificate
+// at an instance.
+type PowerTableCheckpoint struct {
+	Instance   uint64
+	PowerTable cid.Cid
+}
+
+// PowerTableRequest asks for a single power table, served over the protocol named by
+// PowerTableProtocolName.
+type PowerTableRequest struct {
+	// The CID of the power table, as computed by certs.MakePowerTableCID. If nil, the power table
+	// used to validate the finality certificate at Instance is requested instead.
+	PowerTable *cid.Cid
+	// The instance of the power table, used only if PowerTable is nil.
+	Instance uint64
+}
+
+// PowerTableResponse carries the requested power table, if found.
+type PowerTableResponse struct {
+	// The status of the response. The power table is only included if the status is StatusOK.
+	Status ResponseStatus
+	// The instance of the finality certificate validated by the power table.
+	Instance uint64
+	// The power table, or empty.
+	PowerTable gpbft.PowerEntries
+	// A hint for verifying the power table: the CID of the finality certificate at the instance
+	// before, as computed by certs.MakeCertificateCID, which commits to the CID of the power
+	// table. It is nil if the power table is the first one known to the server, which is then
+	// only as trustworthy as the server.
+	Certificate *cid.Cid
+}
+
+type SnapshotRequest struct {
+	// Latest instance to include in the snapshot. If the server has not finalized this instance
+	// yet, the snapshot ends at the latest instance it has finalized instead.
+	LatestInstance uint64
+	// Byte offset into the snapshot from which to start streaming. Used to resume an interrupted
+	// download, in which case LatestInstance must be set to that of the interrupted download.
+	Offset uint64
+}
+
+// SnapshotResponseHeader precedes the snapshot bytes, streamed in the format written by
+// certstore.ExportSnapshot starting at the requested offset.
+type SnapshotResponseHeader struct {
+	// The first instance included in the snapshot.
+	FirstInstance uint64
+	// The latest instance included in the snapshot.
+	LatestInstance uint64
+}
+
+// Announcement is published on the topic named by AnnouncementTopicName once a peer has stored
+// a new finality certificate, so that others can fetch it from the peer right away.
+type Announcement struct {
+	// The instance of the finality certificate.
+	Instance uint64
+	// The CID of the finality certificate, as computed by certs.MakeCertificateCID.
+	Certificate cid.Cid
+}
@@ -1,7 +1,13 @@
 package certexchange_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -9,10 +15,12 @@ import (
 	"github.com/filecoin-project/go-f3/certs"
 	"github.com/filecoin-project/go-f3/certstore"
 	"github.com/filecoin-project/go-f3/gpbft"
+	"github.com/filecoin-project/go-f3/internal/clock"
 
 	cid "github.com/ipfs/go-cid"
 	"github.com/ipfs/go-datastore"
 	ds_sync "github.com/ipfs/go-datastore/sync"
+	"github.com/libp2p/go-libp2p/core/peer"
 	mocknetwork "github.com/libp2p/go-libp2p/p2p/net/mock"
 	"github.com/stretchr/testify/require"
 )
@@ -200,3 +208,551 @@ func TestClientServer(t *testing.T) {
 		require.EqualValues(t, pt, pt2)
 	}
 }
+
+func TestSnapshotClientServer(t *testing.T) {
+	mocknet := mocknetwork.New()
+	h1, err := mocknet.GenPeer()
+	require.NoError(t, err)
+	h2, err := mocknet.GenPeer()
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	require.NoError(t, mocknet.LinkAll())
+
+	ds := ds_sync.MutexWrap(datastore.NewMapDatastore())
+	pt, pcid := testPowerTable(10)
+	supp := gpbft.SupplementalData{PowerTable: pcid}
+
+	cs, err := certstore.CreateStore(ctx, ds, 0, pt)
+	require.NoError(t, err)
+
+	server := certexchange.Server{
+		NetworkName: testNetworkName,
+		Host:        h1,
+		Store:       cs,
+	}
+	client := certexchange.Client{
+		Host:        h2,
+		NetworkName: testNetworkName,
+	}
+
+	require.NoError(t, server.Start(ctx))
+	t.Cleanup(func() { require.NoError(t, server.Stop(context.Background())) })
+	require.NoError(t, mocknet.ConnectAllButSelf())
+
+	// Nothing to snapshot yet.
+	_, _, err = client.RequestSnapshot(ctx, h1.ID(), &certexchange.SnapshotRequest{LatestInstance: certexchange.LatestSnapshot})
+	require.Error(t, err)
+
+	for i := range uint64(5) {
+		require.NoError(t, cs.Put(ctx, &certs.FinalityCertificate{GPBFTInstance: i, SupplementalData: supp,
+			ECChain: &gpbft.ECChain{
+				TipSets: []*gpbft.TipSet{
+					{Epoch: 0, Key: gpbft.TipSetKey("tsk0"), PowerTable: pcid},
+				},
+			},
+		}))
+	}
+	var expected bytes.Buffer
+	_, _, err = cs.ExportSnapshot(ctx, 3, &expected)
+	require.NoError(t, err)
+
+	// The whole snapshot.
+	{
+		head, snapshot, err := client.RequestSnapshot(ctx, h1.ID(), &certexchange.SnapshotRequest{LatestInstance: 3})
+		require.NoError(t, err)
+		require.Equal(t, certexchange.SnapshotResponseHeader{FirstInstance: 0, LatestInstance: 3}, *head)
+		got, err := io.ReadAll(snapshot)
+		require.NoError(t, err)
+		require.NoError(t, snapshot.Close())
+		require.Equal(t, expected.Bytes(), got)
+	}
+
+	// Resuming from an offset.
+	{
+		const offset = 42
+		_, snapshot, err := client.RequestSnapshot(ctx, h1.ID(), &certexchange.SnapshotRequest{LatestInstance: 3, Offset: offset})
+		require.NoError(t, err)
+		got, err := io.ReadAll(snapshot)
+		require.NoError(t, err)
+		require.NoError(t, snapshot.Close())
+		require.Equal(t, expected.Bytes()[offset:], got)
+	}
+
+	// Requesting beyond the latest instance returns the latest snapshot.
+	{
+		head, snapshot, err := client.OpenSnapshot(ctx, h1.ID(), certexchange.LatestSnapshot)
+		require.NoError(t, err)
+		require.EqualValues(t, 4, head.LatestInstance)
+		got, err := io.ReadAll(snapshot)
+		require.NoError(t, err)
+		require.NoError(t, snapshot.Close())
+
+		var latest bytes.Buffer
+		_, _, err = cs.ExportLatestSnapshot(ctx, &latest)
+		require.NoError(t, err)
+		require.Equal(t, latest.Bytes(), got)
+	}
+}
+
+func TestClientServerV2(t *testing.T) {
+	mocknet := mocknetwork.New()
+	h1, err := mocknet.GenPeer()
+	require.NoError(t, err)
+	h2, err := mocknet.GenPeer()
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	require.NoError(t, mocknet.LinkAll())
+
+	ds := ds_sync.MutexWrap(datastore.NewMapDatastore())
+	pt, _ := testPowerTable(10)
+
+	// Enough certificates to prune up to the first stored power table after the initial one.
+	const certCount = 1445
+	cs, err := certstore.CreateStore(ctx, ds, 0, pt)
+	require.NoError(t, err)
+
+	// Change the power table with every certificate, so that checkpoints can be told apart.
+	powerTableCids := make([]cid.Cid, certCount)
+	for i := range powerTableCids {
+		delta := []certs.PowerTableDelta{{ParticipantID: 1, PowerDelta: gpbft.NewStoragePower(1)}}
+		pt, err = certs.ApplyPowerTableDiffs(pt, delta)
+		require.NoError(t, err)
+		powerTableCids[i], err = certs.MakePowerTableCID(pt)
+		require.NoError(t, err)
+		cert := &certs.FinalityCertificate{GPBFTInstance: uint64(i),
+			SupplementalData: gpbft.SupplementalData{PowerTable: powerTableCids[i]},
+			ECChain: &gpbft.ECChain{
+				TipSets: []*gpbft.TipSet{
+					{Epoch: 0, Key: gpbft.TipSetKey("tsk0"), PowerTable: powerTableCids[i]},
+				},
+			},
+			PowerTableDelta: delta,
+		}
+		require.NoError(t, cs.Put(ctx, cert))
+	}
+
+	server := &certexchange.Server{
+		NetworkName: testNetworkName,
+		Host:        h1,
+		Store:       cs,
+	}
+
+	client := certexchange.Client{
+		Host:        h2,
+		NetworkName: testNetworkName,
+	}
+
+	require.NoError(t, server.Start(ctx))
+	t.Cleanup(func() { require.NoError(t, server.Stop(context.Background())) })
+
+	require.NoError(t, mocknet.ConnectAllButSelf())
+
+	// Checkpoints every 3 instances, up to the last certificate in the response.
+	{
+		head, received, err := client.RequestV2(ctx, h1.ID(), &certexchange.RequestV2{
+			FirstInstance:                100,
+			Limit:                        10,
+			PowerTableCheckpointInterval: 3,
+		})
+		require.NoError(t, err)
+		require.EqualValues(t, certCount, head.PendingInstance)
+		require.Equal(t, certexchange.StatusOK, head.Status)
+		require.Equal(t, []certexchange.PowerTableCheckpoint{
+			{Instance: 103, PowerTable: powerTableCids[102]},
+			{Instance: 106, PowerTable: powerTableCids[105]},
+			{Instance: 109, PowerTable: powerTableCids[108]},
+		}, head.PowerTableCheckpoints)
+
+		expectInstance := uint64(100)
+		for c := range received {
+			require.Equal(t, expectInstance, c.GPBFTInstance)
+			expectInstance++
+		}
+		require.EqualValues(t, 110, expectInstance)
+	}
+
+	// No checkpoints unless asked for.
+	{
+		head, received, err := client.RequestV2(ctx, h1.ID(), &certexchange.RequestV2{
+			FirstInstance: 100,
+			Limit:         10,
+		})
+		require.NoError(t, err)
+		require.Empty(t, head.PowerTableCheckpoints)
+		require.Len(t, collectCertificates(received), 10)
+	}
+
+	// No checkpoints beyond the certificates in the response.
+	{
+		head, received, err := client.RequestV2(ctx, h1.ID(), &certexchange.RequestV2{
+			FirstInstance:                certCount - 5,
+			Limit:                        certexchange.NoLimit,
+			PowerTableCheckpointInterval: 4,
+		})
+		require.NoError(t, err)
+		require.Equal(t, []certexchange.PowerTableCheckpoint{
+			{Instance: certCount - 1, PowerTable: powerTableCids[certCount-2]},
+		}, head.PowerTableCheckpoints)
+		require.Len(t, collectCertificates(received), 5)
+	}
+
+	// Pruned certificates are reported as such.
+	require.NoError(t, server.Stop(ctx))
+	cs, err = certstore.OpenStore(ctx, ds, certstore.WithRetention(2))
+	require.NoError(t, err)
+	require.NoError(t, cs.Prune(ctx))
+	server = &certexchange.Server{
+		NetworkName: testNetworkName,
+		Host:        h1,
+		Store:       cs,
+	}
+	require.NoError(t, server.Start(ctx))
+	{
+		head, received, err := client.RequestV2(ctx, h1.ID(), &certexchange.RequestV2{
+			FirstInstance:                100,
+			Limit:                        10,
+			IncludePowerTable:            true,
+			PowerTableCheckpointInterval: 3,
+		})
+		require.NoError(t, err)
+		require.Equal(t, certexchange.StatusPruned, head.Status)
+		require.Nil(t, head.PowerTable)
+		require.Empty(t, head.PowerTableCheckpoints)
+		require.Empty(t, collectCertificates(received))
+	}
+	{
+		head, received, err := client.RequestV2(ctx, h1.ID(), &certexchange.RequestV2{
+			FirstInstance:     1440,
+			Limit:             2,
+			IncludePowerTable: true,
+		})
+		require.NoError(t, err)
+		require.Equal(t, certexchange.StatusOK, head.Status)
+		require.NotNil(t, head.PowerTable)
+		require.Len(t, collectCertificates(received), 2)
+	}
+
+	// The version 1 protocol keeps working alongside version 2.
+	{
+		head, received, err := client.Request(ctx, h1.ID(), &certexchange.Request{
+			FirstInstance: 1440,
+			Limit:         certexchange.NoLimit,
+		})
+		require.NoError(t, err)
+		require.EqualValues(t, certCount, head.PendingInstance)
+		require.Len(t, collectCertificates(received), certCount-1440)
+	}
+}
+
+func TestPowerTableClientServer(t *testing.T) {
+	mocknet := mocknetwork.New()
+	h1, err := mocknet.GenPeer()
+	require.NoError(t, err)
+	h2, err := mocknet.GenPeer()
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	require.NoError(t, mocknet.LinkAll())
+
+	ds := ds_sync.MutexWrap(datastore.NewMapDatastore())
+	pt, ptCid := testPowerTable(10)
+	cs, err := certstore.CreateStore(ctx, ds, 0, pt)
+	require.NoError(t, err)
+
+	// Change the power table with every certificate.
+	const certCount = 10
+	powerTables := []gpbft.PowerEntries{pt}
+	certificates := make([]*certs.FinalityCertificate, certCount)
+	for i := range certificates {
+		delta := []certs.PowerTableDelta{{ParticipantID: 1, PowerDelta: gpbft.NewStoragePower(1)}}
+		next, err := certs.ApplyPowerTableDiffs(powerTables[i], delta)
+		require.NoError(t, err)
+		nextCid, err := certs.MakePowerTableCID(next)
+		require.NoError(t, err)
+		powerTables = append(powerTables, next)
+		certificates[i] = &certs.FinalityCertificate{GPBFTInstance: uint64(i),
+			SupplementalData: gpbft.SupplementalData{PowerTable: nextCid},
+			ECChain: &gpbft.ECChain{
+				TipSets: []*gpbft.TipSet{
+					{Epoch: 0, Key: gpbft.TipSetKey("tsk0"), PowerTable: nextCid},
+				},
+			},
+			PowerTableDelta: delta,
+		}
+		require.NoError(t, cs.Put(ctx, certificates[i]))
+	}
+
+	server := &certexchange.Server{
+		NetworkName: testNetworkName,
+		Host:        h1,
+		Store:       cs,
+	}
+	client := certexchange.Client{
+		Host:        h2,
+		NetworkName: testNetworkName,
+	}
+
+	require.NoError(t, server.Start(ctx))
+	t.Cleanup(func() { require.NoError(t, server.Stop(context.Background())) })
+	require.NoError(t, mocknet.ConnectAllButSelf())
+
+	// The initial power table, which no certificate commits to.
+	{
+		resp, err := client.RequestPowerTable(ctx, h1.ID(), &certexchange.PowerTableRequest{PowerTable: &ptCid})
+		require.NoError(t, err)
+		require.Equal(t, certexchange.StatusOK, resp.Status)
+		require.Zero(t, resp.Instance)
+		require.Equal(t, pt, resp.PowerTable)
+		require.Nil(t, resp.Certificate)
+	}
+
+	// The latest power table, committed to by the latest certificate.
+	{
+		latestCid := certificates[certCount-1].SupplementalData.PowerTable
+		resp, err := client.RequestPowerTable(ctx, h1.ID(), &certexchange.PowerTableRequest{PowerTable: &latestCid})
+		require.NoError(t, err)
+		require.Equal(t, certexchange.StatusOK, resp.Status)
+		require.EqualValues(t, certCount, resp.Instance)
+		require.Equal(t, powerTables[certCount], resp.PowerTable)
+		expectedCertCid, err := certs.MakeCertificateCID(certificates[certCount-1])
+		require.NoError(t, err)
+		require.Equal(t, &expectedCertCid, resp.Certificate)
+	}
+
+	// A power table by instance, which can be checked against the certificate in the hint.
+	{
+		resp, err := client.RequestPowerTable(ctx, h1.ID(), &certexchange.PowerTableRequest{Instance: 5})
+		require.NoError(t, err)
+		require.Equal(t, certexchange.StatusOK, resp.Status)
+		require.EqualValues(t, 5, resp.Instance)
+		require.Equal(t, powerTables[5], resp.PowerTable)
+		expectedCertCid, err := certs.MakeCertificateCID(certificates[4])
+		require.NoError(t, err)
+		require.Equal(t, &expectedCertCid, resp.Certificate)
+	}
+
+	// Power tables the server does not have.
+	{
+		_, otherCid := testPowerTable(5)
+		resp, err := client.RequestPowerTable(ctx, h1.ID(), &certexchange.PowerTableRequest{PowerTable: &otherCid})
+		require.NoError(t, err)
+		require.Equal(t, certexchange.StatusNotFound, resp.Status)
+		require.Empty(t, resp.PowerTable)
+
+		resp, err = client.RequestPowerTable(ctx, h1.ID(), &certexchange.PowerTableRequest{Instance: certCount + 1})
+		require.NoError(t, err)
+		require.Equal(t, certexchange.StatusNotFound, resp.Status)
+	}
+}
+
+func TestServerRateLimits(t *testing.T) {
+	mocknet := mocknetwork.New()
+	h1, err := mocknet.GenPeer()
+	require.NoError(t, err)
+	h2, err := mocknet.GenPeer()
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx, clk := clock.WithMockClock(ctx)
+
+	require.NoError(t, mocknet.LinkAll())
+
+	ds := ds_sync.MutexWrap(datastore.NewMapDatastore())
+	pt, pcid := testPowerTable(10)
+	supp := gpbft.SupplementalData{PowerTable: pcid}
+
+	cs, err := certstore.CreateStore(ctx, ds, 0, pt)
+	require.NoError(t, err)
+	for i := range 20 {
+		cert := &certs.FinalityCertificate{GPBFTInstance: uint64(i), SupplementalData: supp,
+			ECChain: &gpbft.ECChain{
+				TipSets: []*gpbft.TipSet{
+					{Epoch: 0, Key: gpbft.TipSetKey("tsk0"), PowerTable: pcid},
+				},
+			},
+		}
+		require.NoError(t, cs.Put(ctx, cert))
+	}
+
+	server := &certexchange.Server{
+		NetworkName:              testNetworkName,
+		Host:                     h1,
+		Store:                    cs,
+		PeerRequestRate:          1,
+		PeerRequestBurst:         2,
+		MaxCertificatesPerMinute: 15,
+	}
+	client := certexchange.Client{
+		Host:        h2,
+		NetworkName: testNetworkName,
+	}
+
+	require.NoError(t, server.Start(ctx))
+	t.Cleanup(func() { require.NoError(t, server.Stop(context.Background())) })
+	require.NoError(t, mocknet.ConnectAllButSelf())
+
+	request := func() (*certexchange.ResponseHeaderV2, []*certs.FinalityCertificate) {
+		head, received, err := client.RequestV2(ctx, h1.ID(), &certexchange.RequestV2{Limit: 10})
+		require.NoError(t, err)
+		require.EqualValues(t, 20, head.PendingInstance)
+		return head, collectCertificates(received)
+	}
+
+	// The first response uses up most of the certificate budget, and the second gets the rest.
+	head, received := request()
+	require.Equal(t, certexchange.StatusOK, head.Status)
+	require.Len(t, received, 10)
+	head, received = request()
+	require.Equal(t, certexchange.StatusOK, head.Status)
+	require.Len(t, received, 5)
+
+	// The peer has used up its burst of requests.
+	head, received = request()
+	require.Equal(t, certexchange.StatusOverQuota, head.Status)
+	require.Empty(t, received)
+
+	// The peer may make another request a second later, but the certificate budget has barely
+	// refilled.
+	clk.Add(time.Second)
+	head, received = request()
+	require.Equal(t, certexchange.StatusOverQuota, head.Status)
+	require.Empty(t, received)
+
+	// Version 1 requests over quota are reset.
+	_, _, err = client.Request(ctx, h1.ID(), &certexchange.Request{Limit: 10})
+	require.Error(t, err)
+
+	// Everything refills in a minute.
+	clk.Add(time.Minute)
+	head, received = request()
+	require.Equal(t, certexchange.StatusOK, head.Status)
+	require.Len(t, received, 10)
+}
+
+func collectCertificates(ch <-chan *certs.FinalityCertificate) []*certs.FinalityCertificate {
+	var result []*certs.FinalityCertificate
+	for cert := range ch {
+		result = append(result, cert)
+	}
+	return result
+}
+
+func TestHTTPClientServer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ds := ds_sync.MutexWrap(datastore.NewMapDatastore())
+	pt, pcid := testPowerTable(10)
+	supp := gpbft.SupplementalData{PowerTable: pcid}
+
+	cs, err := certstore.CreateStore(ctx, ds, 0, pt)
+	require.NoError(t, err)
+	for i := range uint64(10) {
+		require.NoError(t, cs.Put(ctx, &certs.FinalityCertificate{GPBFTInstance: i, SupplementalData: supp,
+			ECChain: &gpbft.ECChain{
+				TipSets: []*gpbft.TipSet{
+					{Epoch: 0, Key: gpbft.TipSetKey("tsk0"), PowerTable: pcid},
+				},
+			},
+		}))
+	}
+
+	// Serve over HTTP only.
+	server := certexchange.Server{
+		NetworkName: testNetworkName,
+		Store:       cs,
+	}
+	httpServer := httptest.NewServer(server.HTTPHandler())
+	t.Cleanup(httpServer.Close)
+
+	const serverID peer.ID = "http-server"
+	client := certexchange.HTTPClient{
+		Servers:     map[peer.ID]string{serverID: httpServer.URL},
+		NetworkName: testNetworkName,
+	}
+
+	// Not served until started.
+	_, _, err = client.Request(ctx, serverID, &certexchange.Request{Limit: 1})
+	require.ErrorContains(t, err, "503")
+
+	require.NoError(t, server.Start(ctx))
+	t.Cleanup(func() { require.NoError(t, server.Stop(context.Background())) })
+
+	{
+		head, certs, err := client.Request(ctx, serverID, &certexchange.Request{
+			FirstInstance:     2,
+			Limit:             certexchange.NoLimit,
+			IncludePowerTable: true,
+		})
+		require.NoError(t, err)
+		require.EqualValues(t, 10, head.PendingInstance)
+		require.EqualValues(t, pt, head.PowerTable)
+
+		expectInstance := uint64(2)
+		for c := range certs {
+			require.Equal(t, expectInstance, c.GPBFTInstance)
+			expectInstance++
+		}
+		require.EqualValues(t, 10, expectInstance)
+	}
+
+	{
+		head, certs, err := client.RequestV2(ctx, serverID, &certexchange.RequestV2{
+			FirstInstance:                0,
+			Limit:                        5,
+			PowerTableCheckpointInterval: 2,
+		})
+		require.NoError(t, err)
+		require.EqualValues(t, 10, head.PendingInstance)
+		require.Equal(t, certexchange.StatusOK, head.Status)
+		require.Equal(t, []certexchange.PowerTableCheckpoint{
+			{Instance: 2, PowerTable: pcid},
+			{Instance: 4, PowerTable: pcid},
+		}, head.PowerTableCheckpoints)
+		var received int
+		for range certs {
+			received++
+		}
+		require.Equal(t, 5, received)
+	}
+
+	// JSON requests are responded to in JSON.
+	{
+		resp, err := http.Post(httpServer.URL+string(certexchange.FetchProtocolNameV2(testNetworkName)),
+			"application/json", strings.NewReader(`{"FirstInstance": 8, "Limit": 10}`))
+		require.NoError(t, err)
+		defer func() { _ = resp.Body.Close() }()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+		var body struct {
+			Header       certexchange.ResponseHeaderV2
+			Certificates []*certs.FinalityCertificate
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		require.EqualValues(t, 10, body.Header.PendingInstance)
+		require.Len(t, body.Certificates, 2)
+		require.EqualValues(t, 8, body.Certificates[0].GPBFTInstance)
+		require.EqualValues(t, 9, body.Certificates[1].GPBFTInstance)
+	}
+
+	// Other networks and unknown servers aren't served.
+	{
+		otherClient := client
+		otherClient.NetworkName = "othernet"
+		_, _, err := otherClient.Request(ctx, serverID, &certexchange.Request{Limit: 1})
+		require.ErrorContains(t, err, "404")
+
+		_, _, err = client.Request(ctx, "unknown", &certexchange.Request{Limit: 1})
+		require.ErrorIs(t, err, certexchange.ErrUnknownHTTPServer)
+	}
+}
@@ -0,0 +1,112 @@
+package certexchange
+
+import (
+	"sync"
+	"time"
+
+	"github.com/filecoin-project/go-f3/internal/clock"
+	lru "github.com/hashicorp/golang-lru/v2"
+)
+
+// The maximum number of peers to track request rates for. Peers beyond it evict the least
+// recently seen ones, which then start over with a full bucket.
+const maxRateLimitedPeers = 1024
+
+// tokenBucket holds up to burst tokens, refilled at rate tokens per second.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// take takes up to n tokens from the bucket and returns how many it took.
+func (b *tokenBucket) take(now time.Time, rate float64, burst, n uint64) uint64 {
+	if b.last.IsZero() {
+		b.tokens = float64(burst)
+	} else if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = min(b.tokens+elapsed*rate, float64(burst))
+	}
+	b.last = now
+	taken := min(n, uint64(b.tokens))
+	b.tokens -= float64(taken)
+	return taken
+}
+
+// rateLimiter enforces the limits of a Server.
+type rateLimiter struct {
+	clock clock.Clock
+
+	peerRate          float64
+	peerBurst         uint64
+	certificateBudget uint64
+	// Acquired per request, if concurrency is limited.
+	concurrency chan struct{}
+
+	mu           sync.Mutex
+	peers        *lru.Cache[string, *tokenBucket] // By peer ID, or by remote host over HTTP.
+	certificates tokenBucket
+}
+
+func newRateLimiter(clk clock.Clock, s *Server) *rateLimiter {
+	l := &rateLimiter{
+		clock:             clk,
+		peerRate:          s.PeerRequestRate,
+		peerBurst:         uint64(max(s.PeerRequestBurst, 1)),
+		certificateBudget: s.MaxCertificatesPerMinute,
+	}
+	if s.MaxConcurrentRequests > 0 {
+		l.concurrency = make(chan struct{}, s.MaxConcurrentRequests)
+	}
+	if l.peerRate > 0 {
+		// Only errors on non-positive sizes.
+		l.peers, _ = lru.New[string, *tokenBucket](maxRateLimitedPeers)
+	}
+	return l
+}
+
+// acquire reserves a slot for a request from the given peer, or remote host, returning a function to release
+// it, or false if the request is over the limits.
+func (l *rateLimiter) acquire(p string) (func(), rejectReason, bool) {
+	if l.peers != nil {
+		l.mu.Lock()
+		bucket, ok := l.peers.Get(p)
+		if !ok {
+			bucket = new(tokenBucket)
+			l.peers.Add(p, bucket)
+		}
+		taken := bucket.take(l.clock.Now(), l.peerRate, l.peerBurst, 1)
+		l.mu.Unlock()
+		if taken == 0 {
+			return nil, rejectPeerRate, false
+		}
+	}
+	if l.concurrency == nil {
+		return func() {}, "", true
+	}
+	select {
+	case l.concurrency <- struct{}{}:
+		return func() { <-l.concurrency }, "", true
+	default:
+		return nil, rejectConcurrency, false
+	}
+}
+
+// takeCertificates takes up to n certificates from the budget shared by all peers, and returns
+// how many may be served.
+func (l *rateLimiter) takeCertificates(n uint64) uint64 {
+	if l.certificateBudget == 0 {
+		return n
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.certificates.take(l.clock.Now(), float64(l.certificateBudget)/60, l.certificateBudget, n)
+}
+
+// rejectReason is the reason a request was rejected, recorded in metrics.
+type rejectReason string
+
+const (
+	rejectPeerRate     rejectReason = "peer-rate"
+	rejectConcurrency  rejectReason = "concurrency"
+	rejectCertificates rejectReason = "certificates"
+	rejectSnapshots    rejectReason = "snapshots"
+)
@@ -5,12 +5,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"runtime/debug"
 	"sync"
 	"time"
 
+	"github.com/filecoin-project/go-f3/certs"
 	"github.com/filecoin-project/go-f3/certstore"
 	"github.com/filecoin-project/go-f3/gpbft"
+	"github.com/filecoin-project/go-f3/internal/clock"
 	"github.com/filecoin-project/go-f3/internal/measurements"
 	"go.opentelemetry.io/otel/metric"
 
@@ -23,47 +26,74 @@ var log = logging.Logger("f3/certexchange")
 
 const maxResponseLen = 256
 
+const defaultMaxConcurrentSnapshots = 2
+
 // Server is libp2p a certificate exchange server.
 type Server struct {
 	// Request timeouts. If non-zero, requests will be canceled after the specified duration.
 	RequestTimeout time.Duration
-	NetworkName    gpbft.NetworkName
-	Host           host.Host
-	Store          *certstore.Store
+	// Snapshot request timeouts. If non-zero, snapshot requests will be canceled after the
+	// specified duration. Snapshots can be large, so this is separate from RequestTimeout.
+	SnapshotRequestTimeout time.Duration
+	NetworkName            gpbft.NetworkName
+	// The libp2p host to serve requests on. If nil, requests are only served over HTTP, through
+	// HTTPHandler.
+	Host  host.Host
+	Store *certstore.Store
+
+	// The maximum number of requests of any kind served at once. If non-zero, requests beyond
+	// it are rejected.
+	MaxConcurrentRequests int
+	// The rate of requests of any kind per second served to each peer. If non-zero, each peer
+	// may make up to PeerRequestBurst requests at once, and requests beyond the rate are
+	// rejected.
+	PeerRequestRate  float64
+	PeerRequestBurst int
+	// The maximum number of certificates served per minute, across all peers. If non-zero,
+	// responses are cut short once it is reached, and requests are rejected until the budget
+	// refills.
+	MaxCertificatesPerMinute uint64
+	// The maximum number of snapshots served at once, beyond which snapshot requests are reset.
+	// Defaults to 2 if zero.
+	MaxConcurrentSnapshots int
+
+	limiter   *rateLimiter
+	snapshots chan struct{}
 
 	// - held (read) by all active requests.
 	// - taken (write) on shutdown to block until said requests complete.
-	runningLk sync.RWMutex
-	stopFunc  context.CancelFunc
+	runningLk  sync.RWMutex
+	runningCtx context.Context
+	stopFunc   context.CancelFunc
 }
 
-func (s *Server) withDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
-	if s.RequestTimeout > 0 {
-		return context.WithTimeout(ctx, s.RequestTimeout)
+func withDeadline(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
 	}
 	return ctx, func() {}
 }
 
-func (s *Server) handleRequest(ctx context.Context, stream network.Stream) (_err error) {
+func (s *Server) handleRequest(ctx context.Context, stream network.Stream) error {
+	return s.serveRequest(ctx, stream, false)
+}
+
+func (s *Server) handleRequestV2(ctx context.Context, stream network.Stream) error {
+	return s.serveRequest(ctx, stream, true)
+}
+
+// serveRequest serves a request of either version of the certificate exchange protocol. Version 1
+// requests are served as version 2 requests without power table checkpoints, but with the version
+// 1 response header.
+func (s *Server) serveRequest(ctx context.Context, stream network.Stream, v2 bool) (_err error) {
 	start := time.Now()
-	servedPowerTable := false
-	internalError := false
+	var resp *response
 	defer func() {
 		if perr := recover(); perr != nil {
 			_err = fmt.Errorf("panicked in server response: %v", perr)
 			log.Errorf("%s\n%s", _err, string(debug.Stack()))
 		}
-		d := time.Since(start).Seconds()
-		if internalError {
-			metrics.serveTime.Record(ctx, d, metric.WithAttributes(
-				measurements.AttrStatusInternalError,
-			))
-		} else {
-			metrics.serveTime.Record(ctx, d, metric.WithAttributes(
-				measurements.Status(ctx, _err),
-				attrWithPowerTable.Bool(servedPowerTable),
-			))
-		}
+		resp.record(ctx, start, _err, v2, transportLibp2p)
 	}()
 
 	if deadline, ok := ctx.Deadline(); ok {
@@ -74,71 +104,426 @@ func (s *Server) handleRequest(ctx context.Context, stream network.Stream) (_err
 	br := bufio.NewReader(stream)
 	bw := bufio.NewWriter(stream)
 
-	// Request has no variable-length fields, so we don't need a limited reader.
-	var req Request
-	if err := req.UnmarshalCBOR(br); err != nil {
-		log.Debugf("failed to read request from stream: %v", err)
+	release, reason, ok := s.limiter.acquire(string(stream.Conn().RemotePeer()))
+	if !ok {
+		// Version 1 requests are simply reset.
+		header := s.reject(ctx, v2, reason, transportLibp2p)
+		if header == nil {
+			return errOverQuota
+		}
+		if err := header.MarshalCBOR(bw); err != nil {
+			log.Debugf("failed to write header to stream: %v", err)
+			return err
+		}
+		return bw.Flush()
+	}
+	defer release()
+
+	// Requests have no variable-length fields, so we don't need a limited reader.
+	var req RequestV2
+	if v2 {
+		if err := req.UnmarshalCBOR(br); err != nil {
+			log.Debugf("failed to read request from stream: %v", err)
+			return err
+		}
+	} else {
+		var reqV1 Request
+		if err := reqV1.UnmarshalCBOR(br); err != nil {
+			log.Debugf("failed to read request from stream: %v", err)
+			return err
+		}
+		req = reqV1.v2()
+	}
+
+	var err error
+	if resp, err = s.respond(ctx, &req, v2, transportLibp2p); err != nil {
+		return err
+	}
+
+	if v2 {
+		if err := resp.header.MarshalCBOR(bw); err != nil {
+			log.Debugf("failed to write header to stream: %v", err)
+			return err
+		}
+	} else {
+		if err := resp.headerV1().MarshalCBOR(bw); err != nil {
+			log.Debugf("failed to write header to stream: %v", err)
+			return err
+		}
+	}
+
+	if err := resp.writeCertificates(ctx, func(cert *certs.FinalityCertificate) error {
+		if err := cert.MarshalCBOR(bw); err != nil {
+			log.Debugf("failed to write certificate to stream: %v", err)
+			return err
+		}
+		return nil
+	}); err != nil {
 		return err
 	}
 
+	return bw.Flush()
+}
+
+// v2 returns the version 2 equivalent of the request, without power table checkpoints.
+func (r *Request) v2() RequestV2 {
+	return RequestV2{
+		FirstInstance:     r.FirstInstance,
+		Limit:             r.Limit,
+		IncludePowerTable: r.IncludePowerTable,
+	}
+}
+
+// response is a response to a certificate request of either version, however it is transported.
+type response struct {
+	header ResponseHeaderV2
+	// The certificates to write out after the header, from firstInstance on.
+	store         *certstore.Store
+	firstInstance uint64
+	count         uint64
+
+	servedPowerTable bool
+	// Whether the request could not be served in full because of pruning.
+	pruned        bool
+	internalError bool
+	// The number of certificates written out so far.
+	served int
+}
+
+func (r *response) headerV1() *ResponseHeader {
+	return &ResponseHeader{PendingInstance: r.header.PendingInstance, PowerTable: r.header.PowerTable}
+}
+
+// record records the metrics of serving the response, which is nil if the request failed before
+// it could be responded to.
+func (r *response) record(ctx context.Context, start time.Time, err error, v2 bool, transport string) {
+	d := time.Since(start).Seconds()
+	if r == nil {
+		metrics.serveTime.Record(ctx, d, metric.WithAttributes(
+			measurements.Status(ctx, err),
+			attrProtocolVersion.Int(protocolVersion(v2)),
+			attrTransport.String(transport),
+		))
+		return
+	}
+	if r.internalError {
+		metrics.serveTime.Record(ctx, d, metric.WithAttributes(
+			measurements.AttrStatusInternalError,
+			attrProtocolVersion.Int(protocolVersion(v2)),
+			attrTransport.String(transport),
+		))
+	} else {
+		metrics.serveTime.Record(ctx, d, metric.WithAttributes(
+			measurements.Status(ctx, err),
+			attrWithPowerTable.Bool(r.servedPowerTable),
+			attrPruned.Bool(r.pruned),
+			attrProtocolVersion.Int(protocolVersion(v2)),
+			attrTransport.String(transport),
+		))
+	}
+	metrics.certificatesServed.Record(ctx, int64(r.served),
+		metric.WithAttributes(
+			measurements.Status(ctx, err),
+			attrWithPowerTable.Bool(r.servedPowerTable),
+			attrPruned.Bool(r.pruned),
+			attrProtocolVersion.Int(protocolVersion(v2)),
+			attrTransport.String(transport),
+		),
+	)
+}
+
+// respond prepares the response to the given request: its header, listing the power table
+// checkpoints, and the range of certificates to write out after it with writeCertificates.
+func (s *Server) respond(ctx context.Context, req *RequestV2, v2 bool, transport string) (*response, error) {
 	limit := req.Limit
 	if limit > maxResponseLen {
 		limit = maxResponseLen
 	}
-	var resp ResponseHeader
+	resp := &response{store: s.Store, firstInstance: req.FirstInstance}
 	if latest := s.Store.Latest(); latest != nil {
-		resp.PendingInstance = latest.GPBFTInstance + 1
+		resp.header.PendingInstance = latest.GPBFTInstance + 1
 	}
 
-	if resp.PendingInstance >= req.FirstInstance && req.IncludePowerTable {
-		servedPowerTable = true
+	if resp.header.PendingInstance >= req.FirstInstance && req.IncludePowerTable {
 		pt, err := s.Store.GetPowerTable(ctx, req.FirstInstance)
-		if err != nil {
+		switch {
+		case errors.Is(err, certstore.ErrCertPruned):
+			// Respond without the power table; we no longer have it.
+			resp.pruned = true
+		case err != nil:
 			log.Errorf("failed to load power table: %v", err)
+			return nil, err
+		default:
+			resp.servedPowerTable = true
+			resp.header.PowerTable = pt
+		}
+	}
+
+	var overQuota bool
+	switch {
+	case req.FirstInstance < s.Store.PrunedBefore():
+		log.Debugw("requested finality certificates have been pruned", "firstInstance", req.FirstInstance)
+		resp.pruned = true
+	case resp.header.PendingInstance > req.FirstInstance && limit > 0:
+		// Only try to return up-to but not including the pending instance we just told the
+		// client about. Otherwise we could return instances _beyond_ that which is
+		// inconsistent and confusing.
+		resp.count = s.limiter.takeCertificates(min(limit, resp.header.PendingInstance-req.FirstInstance))
+		if resp.count == 0 {
+			overQuota = true
+			metrics.requestsRejected.Add(ctx, 1, metric.WithAttributes(
+				attrRejectReason.String(string(rejectCertificates)),
+				attrProtocolVersion.Int(protocolVersion(v2)),
+				attrTransport.String(transport),
+			))
+		}
+	}
+
+	// The power table at each checkpoint is committed to by the certificate before it, so only
+	// those certificates are loaded ahead of the header.
+	if interval := req.PowerTableCheckpointInterval; interval > 0 {
+		for i := interval; i < resp.count; i += interval {
+			cert, err := s.Store.Get(ctx, req.FirstInstance+i-1)
+			if errors.Is(err, certstore.ErrCertNotFound) || errors.Is(err, certstore.ErrCertPruned) {
+				// Serve up to the certificate we could not find.
+				resp.count = i - 1
+				resp.pruned = resp.pruned || errors.Is(err, certstore.ErrCertPruned)
+				break
+			} else if err != nil {
+				log.Errorf("failed to load finality certificate: %v", err)
+				return nil, err
+			}
+			resp.header.PowerTableCheckpoints = append(resp.header.PowerTableCheckpoints, PowerTableCheckpoint{
+				Instance:   req.FirstInstance + i,
+				PowerTable: cert.SupplementalData.PowerTable,
+			})
+		}
+	}
+
+	switch {
+	case overQuota:
+		resp.header.Status = StatusOverQuota
+	case resp.pruned:
+		resp.header.Status = StatusPruned
+	}
+	return resp, nil
+}
+
+// writeCertificates streams the certificates of the response out of the store, in order, stopping
+// early if the store runs out of them.
+func (r *response) writeCertificates(ctx context.Context, write func(*certs.FinalityCertificate) error) error {
+	if r.count == 0 {
+		return nil
+	}
+	for cert, err := range r.store.Iterate(ctx, r.firstInstance, r.firstInstance+r.count-1) {
+		if errors.Is(err, certstore.ErrCertNotFound) {
+			break
+		} else if errors.Is(err, certstore.ErrCertPruned) {
+			// Respond with what we have; the rest has been pruned.
+			log.Debugw("requested finality certificates have been pruned", "firstInstance", r.firstInstance)
+			r.pruned = true
+			break
+		} else if err != nil {
+			if ctx.Err() == nil {
+				log.Errorf("failed to load finality certificates: %v", err)
+				r.internalError = true
+			}
+			break
+		}
+		if err := write(cert); err != nil {
 			return err
 		}
-		resp.PowerTable = pt
+		r.served++
 	}
+	return nil
+}
 
-	if err := resp.MarshalCBOR(bw); err != nil {
-		log.Debugf("failed to write header to stream: %v", err)
-		return err
+// reject returns the response header to a request that is over the limits of the server. Version
+// 2 requests are told so in the response status, while version 1 requests can only be refused, for
+// which reject returns nil.
+func (s *Server) reject(ctx context.Context, v2 bool, reason rejectReason, transport string) *ResponseHeaderV2 {
+	metrics.requestsRejected.Add(ctx, 1, metric.WithAttributes(
+		attrRejectReason.String(string(reason)),
+		attrProtocolVersion.Int(protocolVersion(v2)),
+		attrTransport.String(transport),
+	))
+	if !v2 {
+		return nil
+	}
+	resp := &ResponseHeaderV2{Status: StatusOverQuota}
+	if latest := s.Store.Latest(); latest != nil {
+		resp.PendingInstance = latest.GPBFTInstance + 1
 	}
+	return resp
+}
+
+var errOverQuota = errors.New("request is over quota")
+
+func protocolVersion(v2 bool) int {
+	if v2 {
+		return 2
+	}
+	return 1
+}
 
-	certsServed := 0
+// The maximum size of a power table request, which only has a CID and an instance.
+const maxPowerTableRequestSize = 256
+
+func (s *Server) handlePowerTableRequest(ctx context.Context, stream network.Stream) (_err error) {
+	start := time.Now()
+	var status ResponseStatus
 	defer func() {
-		metrics.certificatesServed.Record(ctx, int64(certsServed),
-			metric.WithAttributes(
-				measurements.Status(ctx, _err),
-				attrWithPowerTable.Bool(servedPowerTable),
-			),
-		)
+		if perr := recover(); perr != nil {
+			_err = fmt.Errorf("panicked in server power table response: %v", perr)
+			log.Errorf("%s\n%s", _err, string(debug.Stack()))
+		}
+		metrics.powerTableServeTime.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(
+			measurements.Status(ctx, _err),
+			attrResponseStatus.Int(int(status)),
+		))
 	}()
 
-	if resp.PendingInstance > req.FirstInstance {
-		// Only try to return up-to but not including the pending instance we just told the
-		// client about. Otherwise we could return instances _beyond_ that which is
-		// inconsistent and confusing.
-		end := req.FirstInstance + limit
-		if end >= resp.PendingInstance {
-			end = resp.PendingInstance - 1
-		}
-
-		certs, err := s.Store.GetRange(ctx, req.FirstInstance, end)
-		if err == nil || errors.Is(err, certstore.ErrCertNotFound) {
-			for i := range certs {
-				if err := certs[i].MarshalCBOR(bw); err != nil {
-					log.Debugf("failed to write certificate to stream: %v", err)
-					return err
-				}
-				certsServed++
+	if deadline, ok := ctx.Deadline(); ok {
+		// Not all transports support deadlines.
+		_ = stream.SetDeadline(deadline)
+	}
+
+	var resp *PowerTableResponse
+	if release, reason, ok := s.limiter.acquire(string(stream.Conn().RemotePeer())); !ok {
+		metrics.requestsRejected.Add(ctx, 1, metric.WithAttributes(attrRejectReason.String(string(reason))))
+		resp = &PowerTableResponse{Status: StatusOverQuota}
+	} else {
+		defer release()
+
+		var req PowerTableRequest
+		if err := req.UnmarshalCBOR(io.LimitReader(stream, maxPowerTableRequestSize)); err != nil {
+			log.Debugf("failed to read power table request from stream: %v", err)
+			return err
+		}
+		var err error
+		if resp, err = s.lookupPowerTable(ctx, &req); err != nil {
+			if ctx.Err() == nil {
+				log.Errorf("failed to load power table: %v", err)
+			}
+			return err
+		}
+	}
+	status = resp.Status
+
+	bw := bufio.NewWriter(stream)
+	if err := resp.MarshalCBOR(bw); err != nil {
+		log.Debugf("failed to write power table response to stream: %v", err)
+		return err
+	}
+	return bw.Flush()
+}
+
+// lookupPowerTable finds the requested power table, along with the CID of the finality
+// certificate that commits to it, if any.
+func (s *Server) lookupPowerTable(ctx context.Context, req *PowerTableRequest) (*PowerTableResponse, error) {
+	resp := &PowerTableResponse{Instance: req.Instance}
+	var err error
+	if req.PowerTable != nil {
+		resp.Instance, resp.PowerTable, err = s.Store.GetPowerTableByCID(ctx, *req.PowerTable)
+	} else {
+		resp.PowerTable, err = s.Store.GetPowerTable(ctx, req.Instance)
+	}
+	switch {
+	case errors.Is(err, certstore.ErrCertPruned):
+		return &PowerTableResponse{Status: StatusPruned, Instance: resp.Instance}, nil
+	case errors.Is(err, certstore.ErrPowerTableNotFound):
+		return &PowerTableResponse{Status: StatusNotFound, Instance: resp.Instance}, nil
+	case err != nil:
+		return nil, err
+	}
+
+	if resp.Instance > s.Store.FirstInstance() {
+		prev, err := s.Store.Get(ctx, resp.Instance-1)
+		switch {
+		case errors.Is(err, certstore.ErrCertPruned):
+			// Pruned concurrently, so serve the power table without a hint.
+		case err != nil:
+			return nil, err
+		default:
+			certCid, err := certs.MakeCertificateCID(prev)
+			if err != nil {
+				return nil, err
 			}
-		} else if ctx.Err() == nil {
-			log.Errorf("failed to load finality certificates: %v", err)
-			internalError = true
+			resp.Certificate = &certCid
+		}
+	}
+	return resp, nil
+}
+
+func (s *Server) handleSnapshotRequest(ctx context.Context, stream network.Stream) (_err error) {
+	start := time.Now()
+	var bytesServed int64
+	defer func() {
+		if perr := recover(); perr != nil {
+			_err = fmt.Errorf("panicked in server snapshot response: %v", perr)
+			log.Errorf("%s\n%s", _err, string(debug.Stack()))
 		}
+		metrics.snapshotServeTime.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(
+			measurements.Status(ctx, _err),
+		))
+		metrics.snapshotBytesServed.Add(ctx, bytesServed)
+	}()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		// Not all transports support deadlines.
+		_ = stream.SetDeadline(deadline)
+	}
+
+	release, reason, ok := s.limiter.acquire(string(stream.Conn().RemotePeer()))
+	if !ok {
+		metrics.requestsRejected.Add(ctx, 1, metric.WithAttributes(attrRejectReason.String(string(reason))))
+		return errOverQuota
+	}
+	defer release()
+
+	select {
+	case s.snapshots <- struct{}{}:
+		defer func() { <-s.snapshots }()
+	default:
+		metrics.requestsRejected.Add(ctx, 1, metric.WithAttributes(attrRejectReason.String(string(rejectSnapshots))))
+		return errOverQuota
+	}
+
+	// Request has no variable-length fields, so we don't need a limited reader.
+	var req SnapshotRequest
+	if err := req.UnmarshalCBOR(bufio.NewReader(stream)); err != nil {
+		log.Debugf("failed to read snapshot request from stream: %v", err)
+		return err
+	}
+
+	latest := s.Store.Latest()
+	if latest == nil {
+		return errors.New("no finality certificates to snapshot")
+	}
+	if prunedBefore := s.Store.PrunedBefore(); prunedBefore > 0 {
+		// Snapshots start at the initial instance, which has been pruned.
+		return fmt.Errorf("cannot serve snapshots once pruned before instance %d: %w", prunedBefore, certstore.ErrCertPruned)
+	}
+	resp := SnapshotResponseHeader{
+		FirstInstance:  s.Store.FirstInstance(),
+		LatestInstance: min(req.LatestInstance, latest.GPBFTInstance),
+	}
+	if resp.LatestInstance < resp.FirstInstance {
+		return fmt.Errorf("requested snapshot up to instance %d precedes the first instance %d", resp.LatestInstance, resp.FirstInstance)
 	}
 
+	bw := bufio.NewWriter(stream)
+	if err := resp.MarshalCBOR(bw); err != nil {
+		log.Debugf("failed to write snapshot header to stream: %v", err)
+		return err
+	}
+	var err error
+	if bytesServed, err = s.Store.WriteSnapshotFrom(ctx, resp.LatestInstance, req.Offset, bw); err != nil {
+		if ctx.Err() == nil {
+			log.Debugf("failed to export snapshot to stream: %v", err)
+		}
+		return err
+	}
 	return bw.Flush()
 }
 
@@ -151,8 +536,27 @@ func (s *Server) Start(startCtx context.Context) error {
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
+	s.runningCtx = ctx
 	s.stopFunc = cancel
-	s.Host.SetStreamHandler(FetchProtocolName(s.NetworkName), func(stream network.Stream) {
+	s.limiter = newRateLimiter(clock.GetClock(startCtx), s)
+	maxSnapshots := s.MaxConcurrentSnapshots
+	if maxSnapshots <= 0 {
+		maxSnapshots = defaultMaxConcurrentSnapshots
+	}
+	s.snapshots = make(chan struct{}, maxSnapshots)
+	if s.Host == nil {
+		return nil
+	}
+	s.Host.SetStreamHandler(FetchProtocolName(s.NetworkName), s.streamHandler(ctx, s.RequestTimeout, s.handleRequest))
+	s.Host.SetStreamHandler(FetchProtocolNameV2(s.NetworkName), s.streamHandler(ctx, s.RequestTimeout, s.handleRequestV2))
+	s.Host.SetStreamHandler(PowerTableProtocolName(s.NetworkName), s.streamHandler(ctx, s.RequestTimeout, s.handlePowerTableRequest))
+	s.Host.SetStreamHandler(SnapshotProtocolName(s.NetworkName), s.streamHandler(ctx, s.SnapshotRequestTimeout, s.handleSnapshotRequest))
+	return nil
+}
+
+func (s *Server) streamHandler(ctx context.Context, timeout time.Duration,
+	handle func(context.Context, network.Stream) error) network.StreamHandler {
+	return func(stream network.Stream) {
 		// Hold the read-lock for the duration of the request so shutdown can block on
 		// closing all request handlers.
 		if !s.runningLk.TryRLock() {
@@ -172,17 +576,15 @@ func (s *Server) Start(startCtx context.Context) error {
 		// Kill the stream if/when we shutdown the server.
 		defer context.AfterFunc(ctx, func() { _ = stream.Reset() })()
 
-		ctx, cancel := s.withDeadline(ctx)
+		ctx, cancel := withDeadline(ctx, timeout)
 		defer cancel()
 
-		if err := s.handleRequest(ctx, stream); err != nil {
+		if err := handle(ctx, stream); err != nil {
 			_ = stream.Reset()
 		} else {
 			_ = stream.Close()
 		}
-
-	})
-	return nil
+	}
 }
 
 // Stop the server.
@@ -201,7 +603,14 @@ func (s *Server) Stop(stopCtx context.Context) error {
 		return nil
 	}
 	s.stopFunc = nil
+	s.runningCtx = nil
+	if s.Host == nil {
+		return nil
+	}
 	s.Host.RemoveStreamHandler(FetchProtocolName(s.NetworkName))
+	s.Host.RemoveStreamHandler(FetchProtocolNameV2(s.NetworkName))
+	s.Host.RemoveStreamHandler(PowerTableProtocolName(s.NetworkName))
+	s.Host.RemoveStreamHandler(SnapshotProtocolName(s.NetworkName))
 
 	return nil
 }
@@ -327,6 +327,15 @@ func ApplyPowerTableDiffsToMap(powerTableMap map[gpbft.ActorID]gpbft.PowerEntry,
 	return powerTableMap, nil
 }
 
+// MakeCertificateCID returns the DagCBOR-blake2b256 CID of the given finality certificate.
+func MakeCertificateCID(cert *FinalityCertificate) (cid.Cid, error) {
+	var buf bytes.Buffer
+	if err := cert.MarshalCBOR(&buf); err != nil {
+		return cid.Undef, fmt.Errorf("failed to serialize finality certificate: %w", err)
+	}
+	return gpbft.MakeCid(buf.Bytes()), nil
+}
+
 // MakePowerTableCID returns the DagCBOR-blake2b256 CID of the given power entries. This method does
 // not mutate, sort, validate, etc. the power entries.
 func MakePowerTableCID(pt gpbft.PowerEntries) (cid.Cid, error) {
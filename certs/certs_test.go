@@ -492,3 +492,52 @@ func makeJustification(t *testing.T, rng *rand.Rand, tsg *sim.TipSetGenerator, b
 
 	return j
 }
+
+func TestTipSetInclusionProof(t *testing.T) {
+	backend := signing.NewFakeBackend()
+	powerTable := randomPowerTable(backend, 100)
+	tableCid, err := certs.MakePowerTableCID(powerTable)
+	require.NoError(t, err)
+
+	rng := rand.New(rand.NewSource(1234))
+	tsg := sim.NewTipSetGenerator(rng.Uint64())
+	base := &gpbft.TipSet{Epoch: 0, Key: tsg.Sample(), PowerTable: tableCid}
+	justification := makeJustification(t, rng, tsg, backend, base, 0, powerTable, powerTable)
+	cert, err := certs.NewFinalityCertificate(nil, justification)
+	require.NoError(t, err)
+
+	for i, ts := range cert.ECChain.TipSets {
+		proof, err := certs.MakeTipSetInclusionProof(cert.ECChain, i)
+		require.NoError(t, err)
+		require.NoError(t, certs.VerifyTipSetInclusionProof(cert, ts, proof))
+
+		// Survives a round trip.
+		var buf bytes.Buffer
+		require.NoError(t, proof.MarshalCBOR(&buf))
+		var decoded certs.TipSetInclusionProof
+		require.NoError(t, decoded.UnmarshalCBOR(&buf))
+		require.Equal(t, proof, &decoded)
+		require.NoError(t, certs.VerifyTipSetInclusionProof(cert, ts, &decoded))
+
+		// Not valid for a different tipset.
+		other := *ts
+		other.Key = tsg.Sample()
+		require.Error(t, certs.VerifyTipSetInclusionProof(cert, &other, proof))
+
+		// Not valid at a different index.
+		moved := *proof
+		moved.Index++
+		require.Error(t, certs.VerifyTipSetInclusionProof(cert, ts, &moved))
+	}
+
+	_, err = certs.MakeTipSetInclusionProof(cert.ECChain, cert.ECChain.Len())
+	require.Error(t, err)
+
+	// Not valid against a different chain.
+	proof, err := certs.MakeTipSetInclusionProof(cert.ECChain, 0)
+	require.NoError(t, err)
+	other := makeJustification(t, rng, tsg, backend, base, 0, powerTable, powerTable)
+	otherCert, err := certs.NewFinalityCertificate(nil, other)
+	require.NoError(t, err)
+	require.Error(t, certs.VerifyTipSetInclusionProof(otherCert, cert.ECChain.Base(), proof))
+}
@@ -0,0 +1,74 @@
+package certs
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/filecoin-project/go-f3/gpbft"
+	"github.com/filecoin-project/go-f3/merkle"
+)
+
+// TipSetInclusionProof proves that a single tipset is part of the ECChain finalized by a
+// finality certificate, without the need to ship the whole chain. The proof is a merkle-proof
+// against the ECChain key, which is the root of the merkle-tree over the tipsets in the chain.
+type TipSetInclusionProof struct {
+	// The index of the tipset in the finalized ECChain, where index 0 is the base.
+	Index uint64
+	// The merkle-proof of the tipset at Index, from the leaf to the root.
+	Proof []merkle.Digest
+}
+
+// MakeTipSetInclusionProof constructs a proof that the tipset at the given index is included in
+// the given chain.
+func MakeTipSetInclusionProof(chain *gpbft.ECChain, index int) (*TipSetInclusionProof, error) {
+	if chain.IsZero() {
+		return nil, errors.New("cannot prove inclusion in an empty chain")
+	}
+	if index < 0 || index >= chain.Len() {
+		return nil, fmt.Errorf("tipset index %d out of range for chain of length %d", index, chain.Len())
+	}
+	values := make([][]byte, chain.Len())
+	for i, ts := range chain.TipSets {
+		values[i] = ts.MarshalForSigning()
+	}
+	_, proofs := merkle.TreeWithProofs(values)
+	return &TipSetInclusionProof{
+		Index: uint64(index),
+		Proof: proofs[index],
+	}, nil
+}
+
+// VerifyTipSetInclusionProof checks that the given tipset is included in the ECChain finalized by
+// the given finality certificate.
+//
+// Note that this function does not validate the certificate itself. The caller is expected to
+// have done so via `ValidateFinalityCertificates`.
+func VerifyTipSetInclusionProof(cert *FinalityCertificate, ts *gpbft.TipSet, proof *TipSetInclusionProof) error {
+	if cert == nil || cert.ECChain.IsZero() {
+		return errors.New("finality certificate must finalize a non-empty chain")
+	}
+	if err := proof.Verify(cert.ECChain.Key(), ts); err != nil {
+		return fmt.Errorf("instance %d: %w", cert.GPBFTInstance, err)
+	}
+	return nil
+}
+
+// Verify checks that the given tipset is included in the ECChain with the given key. The key is
+// what finality certificate signatures commit to, so this is sufficient for consumers that only
+// hold the key of a finalized chain.
+func (p *TipSetInclusionProof) Verify(key gpbft.ECChainKey, ts *gpbft.TipSet) error {
+	if p == nil {
+		return errors.New("inclusion proof must not be nil")
+	}
+	if err := ts.Validate(); err != nil {
+		return fmt.Errorf("invalid tipset: %w", err)
+	}
+	if p.Index >= math.MaxInt32 {
+		return fmt.Errorf("tipset index %d out of range", p.Index)
+	}
+	if valid, _ := merkle.VerifyProof(key, int(p.Index), ts.MarshalForSigning(), p.Proof); !valid {
+		return fmt.Errorf("tipset %s is not included at index %d of chain %x", ts, p.Index, key)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package certstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+)
+
+// Backend persists the encoded finality certificates of a Store, keyed by instance. Power tables,
+// indices and other metadata are always kept in the datastore of the Store.
+//
+// By default, certificates are kept in the datastore as one key per instance. Backends must be
+// safe for concurrent use.
+type Backend interface {
+	// Get returns the encoded certificate at the given instance, or an error wrapping
+	// ErrCertNotFound if there is none.
+	Get(ctx context.Context, instance uint64) ([]byte, error)
+	// Size returns the length of the encoded certificate at the given instance, or an error
+	// wrapping ErrCertNotFound if there is none.
+	Size(ctx context.Context, instance uint64) (int, error)
+	// Put stores the encoded certificate at the given instance, replacing any existing one.
+	Put(ctx context.Context, instance uint64, cert []byte) error
+	// Delete removes the certificate at the given instance, if any.
+	Delete(ctx context.Context, instance uint64) error
+	// DeleteAll removes all certificates.
+	DeleteAll(ctx context.Context) error
+	// Sync makes the certificates stored so far durable. The store syncs the backend before
+	// recording the instances of the stored certificates in its datastore.
+	Sync(ctx context.Context) error
+}
+
+var _ Backend = (*datastoreBackend)(nil)
+
+// datastoreBackend keeps each certificate under its own key in a datastore.
+type datastoreBackend struct {
+	ds datastore.Datastore
+}
+
+var certsPrefix = datastore.NewKey("/certs")
+
+func (b *datastoreBackend) Get(ctx context.Context, instance uint64) ([]byte, error) {
+	cert, err := b.ds.Get(ctx, b.key(instance))
+	if errors.Is(err, datastore.ErrNotFound) {
+		return nil, fmt.Errorf("cert at %d: %w", instance, ErrCertNotFound)
+	}
+	return cert, err
+}
+
+func (b *datastoreBackend) Size(ctx context.Context, instance uint64) (int, error) {
+	size, err := b.ds.GetSize(ctx, b.key(instance))
+	if errors.Is(err, datastore.ErrNotFound) {
+		return 0, fmt.Errorf("cert at %d: %w", instance, ErrCertNotFound)
+	}
+	return size, err
+}
+
+func (b *datastoreBackend) Put(ctx context.Context, instance uint64, cert []byte) error {
+	return b.ds.Put(ctx, b.key(instance), cert)
+}
+
+func (b *datastoreBackend) Delete(ctx context.Context, instance uint64) error {
+	return b.ds.Delete(ctx, b.key(instance))
+}
+
+// Sync is a no-op, as certificates are as durable as the rest of the datastore of the store.
+func (*datastoreBackend) Sync(context.Context) error { return nil }
+
+func (b *datastoreBackend) DeleteAll(ctx context.Context) error {
+	qr, err := b.ds.Query(ctx, query.Query{Prefix: certsPrefix.String(), KeysOnly: true})
+	if err != nil {
+		return fmt.Errorf("starting a query for certs: %w", err)
+	}
+	defer func() { _ = qr.Close() }()
+	for r := range qr.Next() {
+		if r.Error != nil {
+			return fmt.Errorf("querying certs: %w", r.Error)
+		}
+		if err := b.ds.Delete(ctx, datastore.NewKey(r.Key)); err != nil {
+			return fmt.Errorf("deleting cert: %w", err)
+		}
+	}
+	return nil
+}
+
+func (*datastoreBackend) key(instance uint64) datastore.Key {
+	return certsPrefix.ChildString(fmt.Sprintf("%016X", instance))
+}
@@ -0,0 +1,157 @@
+package certstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/filecoin-project/go-f3/certs"
+	"github.com/filecoin-project/go-f3/gpbft"
+)
+
+// ErrBackfillMismatch is returned when backfilled certificates, validated from a trusted anchor,
+// don't lead up to the first instance of the store, i.e. the store is at odds with the anchor.
+var ErrBackfillMismatch = errors.New("backfilled certificates do not lead up to the first instance")
+
+// Backfill fills in the history of a store that was bootstrapped from a later instance, e.g. from
+// a snapshot, with the certificates missing below its first instance.
+//
+// Certificates are only as trustworthy as the power table they are validated with, so backfilling
+// walks forwards from a trusted anchor: the power table at an earlier instance, typically the
+// initial power table of the network as committed to by its manifest. Every certificate is
+// validated in full, starting from the anchor, before it is written. The certificates become part
+// of the store once they reach its first instance and are found to lead up to it: applying their
+// power table deltas must yield the power table already trusted at the first instance, and their
+// finalized chain must lead up to the base of the first stored certificate. Only then is the first
+// instance of the store lowered to that of the anchor.
+//
+// A Backfill is not safe for concurrent use. Stores that prune certificates cannot be backfilled.
+type Backfill struct {
+	cs       *Store
+	verifier gpbft.Verifier
+	nn       gpbft.NetworkName
+
+	from, next uint64
+	powerTable gpbft.PowerEntries
+	base       *gpbft.TipSet
+}
+
+// StartBackfill starts backfilling the store from the given instance, anchored at the given power
+// table, which the caller must trust to be the one at that instance.
+func (cs *Store) StartBackfill(verifier gpbft.Verifier, nn gpbft.NetworkName, from uint64, powerTable gpbft.PowerEntries) (*Backfill, error) {
+	if cs.retention > 0 || cs.prunedBefore.Load() > 0 {
+		return nil, errors.New("cannot backfill a store that prunes certificates")
+	}
+	if cs.Latest() == nil {
+		return nil, errors.New("cannot backfill a store without certificates")
+	}
+	if first := cs.FirstInstance(); from >= first {
+		return nil, fmt.Errorf("cannot backfill from instance %d, at or after the first instance %d", from, first)
+	}
+	return &Backfill{
+		cs:         cs,
+		verifier:   verifier,
+		nn:         nn,
+		from:       from,
+		next:       from,
+		powerTable: powerTable,
+	}, nil
+}
+
+// Next returns the instance of the next certificate to add.
+func (b *Backfill) Next() uint64 {
+	return b.next
+}
+
+// Add validates the given certificates, which must start at the next instance and end at the
+// latest right before the first instance of the store, and writes them. Once they reach the first
+// instance, the backfill completes and the first instance of the store is lowered, unless the
+// certificates don't lead up to it, in which case Add returns an error wrapping
+// ErrBackfillMismatch. Certificates that fail validation are rejected with an error wrapping
+// ErrInvalidCertificate, and can be replaced by adding others.
+func (b *Backfill) Add(ctx context.Context, certificates ...*certs.FinalityCertificate) error {
+	if len(certificates) == 0 {
+		return nil
+	}
+	cs := b.cs
+	first := cs.FirstInstance()
+	if last := certificates[len(certificates)-1].GPBFTInstance; last >= first {
+		return fmt.Errorf("backfilled certificates must end before the first instance %d, but end at %d", first, last)
+	}
+
+	// Validate before taking the lock, as verifying signatures takes a while.
+	powerTables := make([]gpbft.PowerEntries, len(certificates))
+	next, base := b.powerTable, b.base
+	for i, cert := range certificates {
+		var err error
+		_, _, powerTables[i], err = certs.ValidateFinalityCertificates(b.verifier, b.nn, next, b.next+uint64(i), base, cert)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidCertificate, err)
+		}
+		next, base = powerTables[i], cert.ECChain.Head()
+	}
+	end := b.next + uint64(len(certificates))
+	if end == first {
+		trusted, err := cs.readPowerTable(ctx, first)
+		if err != nil {
+			return fmt.Errorf("loading trusted power table: %w", err)
+		}
+		trustedCid, err := certs.MakePowerTableCID(trusted)
+		if err != nil {
+			return err
+		}
+		if err := checkPowerTable(next, trustedCid); err != nil {
+			return fmt.Errorf("%w: power table at instance %d: %w", ErrBackfillMismatch, first, err)
+		}
+		firstCert, err := cs.Get(ctx, first)
+		if err != nil {
+			return fmt.Errorf("loading the first certificate: %w", err)
+		}
+		if !base.Equal(firstCert.ECChain.Base()) {
+			return fmt.Errorf("%w: finalized chain does not lead up to instance %d", ErrBackfillMismatch, first)
+		}
+	}
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if b.next == b.from {
+		if err := cs.putPowerTable(ctx, b.from, b.powerTable); err != nil {
+			return err
+		}
+	}
+	for i, cert := range certificates {
+		var buf bytes.Buffer
+		if err := cert.MarshalCBOR(&buf); err != nil {
+			return fmt.Errorf("marshalling cert instance %d: %w", cert.GPBFTInstance, err)
+		}
+		if err := cs.backend.Put(ctx, cert.GPBFTInstance, buf.Bytes()); err != nil {
+			return fmt.Errorf("putting the cert: %w", err)
+		}
+		if err := cs.putIndex(ctx, cert); err != nil {
+			return err
+		}
+		if (cert.GPBFTInstance+1)%cs.powerTableFrequency == 0 {
+			if err := cs.putPowerTable(ctx, cert.GPBFTInstance+1, powerTables[i]); err != nil {
+				return err
+			}
+		}
+	}
+	b.next, b.powerTable, b.base = end, next, base
+	if end < first {
+		return nil
+	}
+
+	// Write the certificates before lowering the first instance, so that the store remains
+	// consistent if interrupted.
+	if err := cs.backend.Sync(ctx); err != nil {
+		return fmt.Errorf("syncing certificates: %w", err)
+	}
+	if err := cs.writeInstanceNumber(ctx, certStoreFirstKey, b.from); err != nil {
+		return fmt.Errorf("writing first instance: %w", err)
+	}
+	cs.firstInstance.Store(b.from)
+	log.Infow("backfilled finality certificates", "from", b.from, "to", first-1)
+	return nil
+}
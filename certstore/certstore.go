@@ -6,51 +6,126 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"iter"
 	"math"
 	"sync"
+	"sync/atomic"
 
 	"github.com/filecoin-project/go-f3/certs"
 	"github.com/filecoin-project/go-f3/gpbft"
 
+	"github.com/ipfs/go-cid"
 	"github.com/ipfs/go-datastore"
 	"github.com/ipfs/go-datastore/namespace"
 	"github.com/ipfs/go-datastore/query"
+	logging "github.com/ipfs/go-log/v2"
 )
 
+var log = logging.Logger("f3/certstore")
+
 var ErrCertNotFound = errors.New("certificate not found")
 var ErrNotInitialized = errors.New("certstore is not initialized")
 
+// ErrCertPruned is returned when reading a certificate, or a power table, that has been pruned
+// according to the retention policy of the store.
+var ErrCertPruned = errors.New("certificate has been pruned")
+
+// ErrBackendMismatch is returned when opening a store with a backend other than the one its
+// certificates are stored in, e.g. after enabling certificate segments on an existing store.
+// Certificates are not migrated between backends.
+var ErrBackendMismatch = errors.New("certificates are stored in a different backend than the one configured")
+
+// ErrPowerTableNotFound is returned when looking up a power table that is not in the store.
+var ErrPowerTableNotFound = errors.New("power table not found")
+
 const defaultPowerTableFrequency = 60 * 24 // expected twice a day for Filecoin
 
 var (
 	certStoreLatestKey = datastore.NewKey("/latestCert")
 	certStoreFirstKey  = datastore.NewKey("/firstInstance")
+	certStorePrunedKey = datastore.NewKey("/prunedBefore")
+	// Holds the first instance of an unfinished prune, whose entries may be partially deleted.
+	certStorePruningKey = datastore.NewKey("/pruning")
 )
 
 // Store is responsible for storing and relaying information about new finality certificates
 type Store struct {
+	*options
+
 	mu                  sync.RWMutex
 	ds                  datastore.Datastore
-	firstInstance       uint64
+	backend             Backend
+	firstInstance       atomic.Uint64
 	powerTableFrequency uint64
 	subscribers         map[chan *certs.FinalityCertificate]struct{}
 	latestCertificate   *certs.FinalityCertificate
 
 	latestPowerTable gpbft.PowerEntries
+
+	// The first instance retained after pruning, or zero if nothing has been pruned.
+	prunedBefore atomic.Uint64
+	// Held while pruning.
+	pruneMu sync.Mutex
+
+	// The lifecycle of the background tasks of the store, which are stopped by Close.
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	// Closed once all the certificates in the store are indexed.
+	indexed chan struct{}
 }
 
-// Internal helper function to open a certificate store that may or may not have been created.
-func open(ctx context.Context, ds datastore.Datastore) (*Store, error) {
+func newStore(ds datastore.Datastore, opts *options) *Store {
 	cs := &Store{
+		options:             opts,
 		ds:                  namespace.Wrap(ds, datastore.NewKey("/certstore")),
+		backend:             opts.backend,
 		powerTableFrequency: defaultPowerTableFrequency,
 		subscribers:         make(map[chan *certs.FinalityCertificate]struct{}),
+		indexed:             make(chan struct{}),
+	}
+	cs.ctx, cs.cancel = context.WithCancel(context.Background())
+	if cs.backend == nil {
+		cs.backend = &datastoreBackend{ds: cs.ds}
+	}
+	return cs
+}
+
+// Discard deletes everything stored by the certificate store in the given datastore, whether or
+// not the store has been initialized. Unlike DeleteAll, this does not need the store to be
+// opened, which makes it suitable for cleaning up after creating or importing the store failed
+// part-way.
+func Discard(ctx context.Context, ds datastore.Datastore, o ...Option) error {
+	opts, err := newOptions(o...)
+	if err != nil {
+		return err
 	}
-	err := maybeContinueDelete(ctx, ds)
+	return newStore(ds, opts).DeleteAll(ctx)
+}
+
+// Internal helper function to open a certificate store that may or may not have been created.
+func open(ctx context.Context, ds datastore.Datastore, o ...Option) (*Store, error) {
+	opts, err := newOptions(o...)
 	if err != nil {
+		return nil, err
+	}
+	cs := newStore(ds, opts)
+	err = maybeContinueDelete(ctx, ds)
+	if err != nil {
+		return nil, fmt.Errorf("continuing deletion: %w", err)
+	}
+	if err := cs.continueDeleteAll(ctx); err != nil {
 		return nil, fmt.Errorf("continuing deletion: %w", err)
 	}
 
+	switch prunedBefore, err := cs.readInstanceNumber(ctx, certStorePrunedKey); {
+	case errors.Is(err, datastore.ErrNotFound):
+	case err != nil:
+		return nil, fmt.Errorf("determining pruned instances: %w", err)
+	default:
+		cs.prunedBefore.Store(prunedBefore)
+	}
+
 	latestInstance, err := cs.readInstanceNumber(ctx, certStoreLatestKey)
 	if errors.Is(err, datastore.ErrNotFound) {
 		return cs, nil
@@ -59,6 +134,11 @@ func open(ctx context.Context, ds datastore.Datastore) (*Store, error) {
 	}
 
 	cs.latestCertificate, err = cs.Get(ctx, latestInstance)
+	if errors.Is(err, ErrCertNotFound) && opts.backend != nil {
+		if _, derr := (&datastoreBackend{ds: cs.ds}).Get(ctx, latestInstance); derr == nil {
+			return nil, fmt.Errorf("loading latest cert: %w", ErrBackendMismatch)
+		}
+	}
 	if err != nil {
 		return nil, fmt.Errorf("loading latest cert: %w", err)
 	}
@@ -74,11 +154,24 @@ func open(ctx context.Context, ds datastore.Datastore) (*Store, error) {
 // function will return an error.
 //
 // The passed Datastore has to be thread safe.
-func OpenOrCreateStore(ctx context.Context, ds datastore.Datastore, firstInstance uint64, initialPowerTable gpbft.PowerEntries) (*Store, error) {
+func OpenOrCreateStore(ctx context.Context, ds datastore.Datastore, firstInstance uint64, initialPowerTable gpbft.PowerEntries, o ...Option) (*Store, error) {
+	cs, err := openOrCreateStore(ctx, ds, firstInstance, initialPowerTable, o...)
+	if err != nil {
+		return nil, err
+	}
+	if err := cs.maybeBuildIndex(ctx); err != nil {
+		return nil, err
+	}
+	return cs, nil
+}
+
+// openOrCreateStore is OpenOrCreateStore without indexing the certificates that are not indexed
+// yet, for stores that are only written to.
+func openOrCreateStore(ctx context.Context, ds datastore.Datastore, firstInstance uint64, initialPowerTable gpbft.PowerEntries, o ...Option) (*Store, error) {
 	if len(initialPowerTable) == 0 {
 		return nil, errors.New("cannot construct certificate store with an empty initial power table")
 	}
-	cs, err := open(ctx, ds)
+	cs, err := open(ctx, ds, o...)
 	if err != nil {
 		return nil, err
 	}
@@ -87,16 +180,20 @@ func OpenOrCreateStore(ctx context.Context, ds datastore.Datastore, firstInstanc
 		if firstInstance != dbFirstInstance {
 			return nil, fmt.Errorf("certificate store re-initialized with a different initial instance %d != %d", dbFirstInstance, firstInstance)
 		}
-		var buf bytes.Buffer
-		if err := initialPowerTable.MarshalCBOR(&buf); err != nil {
-			return nil, fmt.Errorf("failed to martial initial power table: %w", err)
-		}
-		pb, err := cs.ds.Get(ctx, cs.keyForPowerTable(firstInstance))
+		// Once pruned, the power table at the pruning horizon stands in for the initial one,
+		// which can no longer be checked.
+		pb, err := cs.ds.Get(ctx, cs.keyForPowerTable(max(firstInstance, cs.prunedBefore.Load())))
 		if err != nil {
 			return nil, fmt.Errorf("failed to load initial power table: %w", err)
 		}
-		if !bytes.Equal(buf.Bytes(), pb) {
-			return nil, errors.New("certificate store re-initialized with the wrong power table")
+		if cs.prunedBefore.Load() <= firstInstance {
+			var buf bytes.Buffer
+			if err := initialPowerTable.MarshalCBOR(&buf); err != nil {
+				return nil, fmt.Errorf("failed to martial initial power table: %w", err)
+			}
+			if !bytes.Equal(buf.Bytes(), pb) {
+				return nil, errors.New("certificate store re-initialized with the wrong power table")
+			}
 		}
 	} else if errors.Is(err, datastore.ErrNotFound) {
 		if err := cs.putPowerTable(ctx, firstInstance, initialPowerTable); err != nil {
@@ -105,10 +202,13 @@ func OpenOrCreateStore(ctx context.Context, ds datastore.Datastore, firstInstanc
 		if err := cs.writeInstanceNumber(ctx, certStoreFirstKey, firstInstance); err != nil {
 			return nil, fmt.Errorf("while recording the first instance: %w", err)
 		}
+		if err := cs.markIndexed(ctx); err != nil {
+			return nil, err
+		}
 	} else {
 		return nil, fmt.Errorf("failed to read initial instance number: %w", err)
 	}
-	cs.firstInstance = firstInstance
+	cs.firstInstance.Store(firstInstance)
 	if latest := cs.latestCertificate; latest != nil {
 		cs.latestPowerTable, err = cs.GetPowerTable(ctx, latest.GPBFTInstance+1)
 		if err != nil {
@@ -117,17 +217,16 @@ func OpenOrCreateStore(ctx context.Context, ds datastore.Datastore, firstInstanc
 	} else {
 		cs.latestPowerTable = initialPowerTable
 	}
-
 	return cs, nil
 }
 
 // CreateStore initializes a new certificate store. It will fail if the store already exists.
 // The passed Datastore has to be thread safe.
-func CreateStore(ctx context.Context, ds datastore.Datastore, firstInstance uint64, initialPowerTable gpbft.PowerEntries) (*Store, error) {
+func CreateStore(ctx context.Context, ds datastore.Datastore, firstInstance uint64, initialPowerTable gpbft.PowerEntries, o ...Option) (*Store, error) {
 	if len(initialPowerTable) == 0 {
 		return nil, errors.New("cannot construct certificate store with an empty initial power table")
 	}
-	cs, err := open(ctx, ds)
+	cs, err := open(ctx, ds, o...)
 	if err != nil {
 		return nil, err
 	}
@@ -140,7 +239,11 @@ func CreateStore(ctx context.Context, ds datastore.Datastore, firstInstance uint
 	if err := cs.writeInstanceNumber(ctx, certStoreFirstKey, firstInstance); err != nil {
 		return nil, fmt.Errorf("while recording the first instance: %w", err)
 	}
-	cs.firstInstance = firstInstance
+	if err := cs.markIndexed(ctx); err != nil {
+		return nil, err
+	}
+	close(cs.indexed)
+	cs.firstInstance.Store(firstInstance)
 	cs.latestPowerTable = initialPowerTable
 
 	return cs, nil
@@ -149,19 +252,33 @@ func CreateStore(ctx context.Context, ds datastore.Datastore, firstInstance uint
 // OpenStore opens an existing certificate store.
 // The passed Datastore has to be thread safe.
 // Returns ErrNotInitialized if the CertStore does not exist
-func OpenStore(ctx context.Context, ds datastore.Datastore) (*Store, error) {
-	cs, err := open(ctx, ds)
+func OpenStore(ctx context.Context, ds datastore.Datastore, o ...Option) (*Store, error) {
+	cs, err := openStore(ctx, ds, o...)
+	if err != nil {
+		return nil, err
+	}
+	if err := cs.maybeBuildIndex(ctx); err != nil {
+		return nil, err
+	}
+	return cs, nil
+}
+
+// openStore is OpenStore without indexing the certificates that are not indexed yet, for stores
+// that are only written to.
+func openStore(ctx context.Context, ds datastore.Datastore, o ...Option) (*Store, error) {
+	cs, err := open(ctx, ds, o...)
 	if err != nil {
 		return nil, err
 	}
-	cs.firstInstance, err = cs.readInstanceNumber(ctx, certStoreFirstKey)
+	firstInstance, err := cs.readInstanceNumber(ctx, certStoreFirstKey)
 	if errors.Is(err, datastore.ErrNotFound) {
 		return nil, ErrNotInitialized
 	}
 	if err != nil {
 		return nil, fmt.Errorf("getting first instance: %w", err)
 	}
-	latestPowerTable := cs.firstInstance
+	cs.firstInstance.Store(firstInstance)
+	latestPowerTable := cs.FirstInstance()
 	if latest := cs.latestCertificate; latest != nil {
 		latestPowerTable = latest.GPBFTInstance + 1
 	}
@@ -200,13 +317,38 @@ func (cs *Store) Latest() *certs.FinalityCertificate {
 	return cs.latestCertificate
 }
 
+// FirstInstance returns the first instance the store holds certificates for, which is past the
+// initial instance of the store once older certificates have been pruned.
+func (cs *Store) FirstInstance() uint64 {
+	return max(cs.firstInstance.Load(), cs.prunedBefore.Load())
+}
+
+// PrunedBefore returns the instance before which certificates have been pruned, or zero if none
+// have been pruned.
+func (cs *Store) PrunedBefore() uint64 {
+	return cs.prunedBefore.Load()
+}
+
+// Close stops pruning and indexing in the background, and waits for them to exit. Certificates are
+// no longer pruned as they are added once the store is closed, though Prune still prunes them.
+func (cs *Store) Close() error {
+	cs.cancel()
+	cs.pruneMu.Lock()
+	defer cs.pruneMu.Unlock()
+	cs.wg.Wait()
+	return nil
+}
+
 // Get returns the FinalityCertificate at the specified instance, or an error derived from
-// ErrCertNotFound.
+// ErrCertNotFound, or from ErrCertPruned if the certificate has been pruned.
 func (cs *Store) Get(ctx context.Context, instance uint64) (*certs.FinalityCertificate, error) {
-	b, err := cs.ds.Get(ctx, cs.keyForCert(instance))
+	if instance < cs.prunedBefore.Load() {
+		return nil, fmt.Errorf("cert at %d: %w", instance, ErrCertPruned)
+	}
+	b, err := cs.backend.Get(ctx, instance)
 
-	if errors.Is(err, datastore.ErrNotFound) {
-		return nil, fmt.Errorf("cert at %d: %w", instance, ErrCertNotFound)
+	if errors.Is(err, ErrCertNotFound) {
+		return nil, err
 	}
 	if err != nil {
 		return nil, fmt.Errorf("accessing cert in datastore: %w", err)
@@ -220,9 +362,11 @@ func (cs *Store) Get(ctx context.Context, instance uint64) (*certs.FinalityCerti
 }
 
 // GetRange returns a range of certs from start to end inclusive by instance numbers in the
-// increasing order. Only this order of traversal is supported.
+// increasing order. Only this order of traversal is supported. Use Iterate to stream large
+// ranges, or to traverse them in the decreasing order.
 //
-// If it encounters missing cert, it returns a wrapped ErrCertNotFound and the available certs.
+// If it encounters missing cert, it returns a wrapped ErrCertNotFound and the available certs. If
+// the range starts at a pruned cert, it returns a wrapped ErrCertPruned.
 func (cs *Store) GetRange(ctx context.Context, start uint64, end uint64) ([]certs.FinalityCertificate, error) {
 	if start > end {
 		return nil, fmt.Errorf("start is larger than end: %d > %d", start, end)
@@ -230,33 +374,75 @@ func (cs *Store) GetRange(ctx context.Context, start uint64, end uint64) ([]cert
 	if end-start >= math.MaxInt {
 		return nil, fmt.Errorf("range %d to %d is too large", start, end)
 	}
+	if start < cs.prunedBefore.Load() {
+		return nil, fmt.Errorf("cert at %d: %w", start, ErrCertPruned)
+	}
 
-	bCerts := make([][]byte, 0, end-start+1)
-
-	for i := start; i <= end; i++ {
-		b, err := cs.ds.Get(ctx, cs.keyForCert(i))
-		if errors.Is(err, datastore.ErrNotFound) {
-			break
+	var certs []certs.FinalityCertificate
+	for cert, err := range cs.Iterate(ctx, start, end) {
+		if errors.Is(err, ErrCertNotFound) {
+			return certs, err
 		}
 		if err != nil {
-			return nil, fmt.Errorf("accessing cert at %d for range request: %w", i, err)
+			return nil, fmt.Errorf("range request: %w", err)
 		}
-
-		bCerts = append(bCerts, b)
+		certs = append(certs, *cert)
 	}
+	return certs, nil
+}
 
-	certs := make([]certs.FinalityCertificate, len(bCerts))
-	for j, bCert := range bCerts {
-		err := certs[j].UnmarshalCBOR(bytes.NewReader(bCert))
+// Iterate returns an iterator over the certs from start to end inclusive by instance numbers. The
+// certs are read from the datastore one at a time as the iteration progresses, so that memory
+// usage does not grow with the length of the range. The certs are traversed in the increasing
+// order of instance if start is not larger than end, and in the decreasing order otherwise.
+//
+// Iteration stops at the first error, which is yielded with a nil cert. Reaching a missing cert
+// yields a wrapped ErrCertNotFound, and a pruned cert a wrapped ErrCertPruned.
+func (cs *Store) Iterate(ctx context.Context, start uint64, end uint64) iter.Seq2[*certs.FinalityCertificate, error] {
+	return func(yield func(*certs.FinalityCertificate, error) bool) {
+		err := cs.iterateRaw(ctx, start, end, func(instance uint64, b []byte) bool {
+			var cert certs.FinalityCertificate
+			if err := cert.UnmarshalCBOR(bytes.NewReader(b)); err != nil {
+				yield(nil, fmt.Errorf("unmarshalling cert at %d: %w", instance, err))
+				return false
+			}
+			return yield(&cert, nil)
+		})
 		if err != nil {
-			return nil, fmt.Errorf("unmarshalling a cert at j=%d, instance %d: %w", j, start+uint64(j), err)
+			yield(nil, err)
 		}
 	}
+}
 
-	if len(certs) < cap(bCerts) {
-		return certs, fmt.Errorf("cert at %d: %w", start+uint64(len(bCerts)), ErrCertNotFound)
+// iterateRaw calls fn with the encoded certs from start to end inclusive, in the order specified
+// by Iterate, until fn returns false.
+func (cs *Store) iterateRaw(ctx context.Context, start uint64, end uint64, fn func(instance uint64, b []byte) bool) error {
+	for i := start; ; {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if i < cs.prunedBefore.Load() {
+			return fmt.Errorf("cert at %d: %w", i, ErrCertPruned)
+		}
+		b, err := cs.backend.Get(ctx, i)
+		if errors.Is(err, ErrCertNotFound) {
+			return err
+		}
+		if err != nil {
+			return fmt.Errorf("accessing cert at %d: %w", i, err)
+		}
+		if !fn(i, b) {
+			return nil
+		}
+		switch {
+		case i == end:
+			return nil
+		case start <= end:
+			i++
+		default:
+			i--
+		}
 	}
-	return certs, nil
 }
 
 func (cs *Store) readPowerTable(ctx context.Context, instance uint64) (gpbft.PowerEntries, error) {
@@ -283,8 +469,11 @@ func (cs *Store) putPowerTable(ctx context.Context, instance uint64, powerTable
 
 // GetPowerTable returns the power table (committee) used to validate the specified instance.
 func (cs *Store) GetPowerTable(ctx context.Context, instance uint64) (gpbft.PowerEntries, error) {
-	if instance < cs.firstInstance {
-		return nil, fmt.Errorf("cannot return a power table before the first instance: %d", cs.firstInstance)
+	if instance < cs.firstInstance.Load() {
+		return nil, fmt.Errorf("cannot return a power table before the first instance: %d: %w", cs.firstInstance.Load(), ErrPowerTableNotFound)
+	}
+	if instance < cs.prunedBefore.Load() {
+		return nil, fmt.Errorf("power table at %d: %w", instance, ErrCertPruned)
 	}
 
 	// Copy a reference to both the latest cert and latest power table while holding
@@ -295,12 +484,12 @@ func (cs *Store) GetPowerTable(ctx context.Context, instance uint64) (gpbft.Powe
 	latestPowerTable := cs.latestPowerTable
 	cs.mu.RUnlock()
 
-	nextCertInstance := cs.firstInstance
+	nextCertInstance := cs.firstInstance.Load()
 	if latestCert != nil {
 		nextCertInstance = latestCert.GPBFTInstance + 1
 	}
 	if instance > nextCertInstance {
-		return nil, fmt.Errorf("cannot return future power table for instance %d > %d", instance, nextCertInstance)
+		return nil, fmt.Errorf("cannot return future power table for instance %d > %d: %w", instance, nextCertInstance, ErrPowerTableNotFound)
 	}
 	if instance == nextCertInstance && len(latestPowerTable) != 0 {
 		// Note that the latestPowerTable may be nil/empty, which indicates the certstore
@@ -314,8 +503,9 @@ func (cs *Store) GetPowerTable(ctx context.Context, instance uint64) (gpbft.Powe
 	// if it is. For now, YAGNI.
 
 	// We store every `powerTableFrequency` power tables. Find the nearest multiple smaller than
-	// the requested instance.
-	startInstance := max(instance-instance%cs.powerTableFrequency, cs.firstInstance)
+	// the requested instance, unless it was pruned, in which case start from the power table kept
+	// at the pruning horizon.
+	startInstance := max(instance-instance%cs.powerTableFrequency, cs.firstInstance.Load(), cs.prunedBefore.Load())
 
 	powerTable, err := cs.readPowerTable(ctx, startInstance)
 	if err != nil {
@@ -325,16 +515,15 @@ func (cs *Store) GetPowerTable(ctx context.Context, instance uint64) (gpbft.Powe
 		return powerTable, nil
 	}
 	// Load the power table diffs up till (but not including) the target instance.
-	certificates, err := cs.GetRange(ctx, startInstance, instance-1)
-	if err != nil {
-		return nil, err
+	deltas := make([]certs.PowerTableDiff, 0, instance-startInstance)
+	for cert, err := range cs.Iterate(ctx, startInstance, instance-1) {
+		if err != nil {
+			return nil, err
+		}
+		deltas = append(deltas, cert.PowerTableDelta)
 	}
 
 	// Apply the diffs and return the result.
-	deltas := make([]certs.PowerTableDiff, len(certificates))
-	for i := range certificates {
-		deltas[i] = certificates[i].PowerTableDelta
-	}
 	powerTable, err = certs.ApplyPowerTableDiffs(powerTable, deltas...)
 	if err != nil {
 		return nil, fmt.Errorf("applying power deltas: %w", err)
@@ -342,8 +531,49 @@ func (cs *Store) GetPowerTable(ctx context.Context, instance uint64) (gpbft.Powe
 	return powerTable, err
 }
 
-func (*Store) keyForCert(i uint64) datastore.Key {
-	return datastore.NewKey(fmt.Sprintf("/certs/%016X", i))
+// GetPowerTableByCID returns the power table with the given CID, along with the instance it is
+// used to validate, or an error wrapping ErrPowerTableNotFound. Only the power tables stored
+// every so often, the first one and the latest one are looked up, which covers the initial power
+// tables of networks and the power tables needed to resume validation from a checkpoint.
+func (cs *Store) GetPowerTableByCID(ctx context.Context, powerTableCid cid.Cid) (uint64, gpbft.PowerEntries, error) {
+	cs.mu.RLock()
+	latestCert := cs.latestCertificate
+	latestPowerTable := cs.latestPowerTable
+	cs.mu.RUnlock()
+
+	matches := func(powerTable gpbft.PowerEntries) (bool, error) {
+		ptCid, err := certs.MakePowerTableCID(powerTable)
+		if err != nil {
+			return false, fmt.Errorf("computing power table CID: %w", err)
+		}
+		return ptCid == powerTableCid, nil
+	}
+
+	first := cs.FirstInstance()
+	next := first
+	if latestCert != nil {
+		next = latestCert.GPBFTInstance + 1
+		if ok, err := matches(latestPowerTable); err != nil {
+			return 0, nil, err
+		} else if ok {
+			return next, latestPowerTable, nil
+		}
+	}
+	for instance := first; instance <= next; instance += cs.powerTableFrequency - instance%cs.powerTableFrequency {
+		powerTable, err := cs.readPowerTable(ctx, instance)
+		if errors.Is(err, datastore.ErrNotFound) {
+			// Either pruned concurrently, or not stored yet at the next instance.
+			continue
+		} else if err != nil {
+			return 0, nil, err
+		}
+		if ok, err := matches(powerTable); err != nil {
+			return 0, nil, err
+		} else if ok {
+			return instance, powerTable, nil
+		}
+	}
+	return 0, nil, fmt.Errorf("power table %s: %w", powerTableCid, ErrPowerTableNotFound)
 }
 
 func (*Store) keyForPowerTable(i uint64) datastore.Key {
@@ -356,8 +586,8 @@ func (*Store) keyForPowerTable(i uint64) datastore.Key {
 // 1. Before the initial instance that the certificate store was initialized with.
 // 2. More than one instance after the last certificate stored.
 func (cs *Store) Put(ctx context.Context, cert *certs.FinalityCertificate) error {
-	if cert.GPBFTInstance < cs.firstInstance {
-		return fmt.Errorf("certificate store only stores certificates on or after instance %d", cs.firstInstance)
+	if cert.GPBFTInstance < cs.firstInstance.Load() {
+		return fmt.Errorf("certificate store only stores certificates on or after instance %d", cs.firstInstance.Load())
 	}
 
 	// Basic validation just to make sure the certificate is sane. We don't do a full validation
@@ -372,7 +602,7 @@ func (cs *Store) Put(ctx context.Context, cert *certs.FinalityCertificate) error
 	cs.mu.Lock()
 	defer cs.mu.Unlock()
 
-	nextCert := cs.firstInstance
+	nextCert := cs.firstInstance.Load()
 	if latestCert := cs.latestCertificate; latestCert != nil {
 		nextCert = latestCert.GPBFTInstance + 1
 	}
@@ -415,9 +645,12 @@ func (cs *Store) Put(ctx context.Context, cert *certs.FinalityCertificate) error
 		return fmt.Errorf("marshalling cert instance %d: %w", cert.GPBFTInstance, err)
 	}
 
-	if err := cs.ds.Put(ctx, cs.keyForCert(cert.GPBFTInstance), buf.Bytes()); err != nil {
+	if err := cs.backend.Put(ctx, cert.GPBFTInstance, buf.Bytes()); err != nil {
 		return fmt.Errorf("putting the cert: %w", err)
 	}
+	if err := cs.putIndex(ctx, cert); err != nil {
+		return err
+	}
 
 	// The new power table is the power table to validate the _next_ instance.
 	if (cert.GPBFTInstance+1)%cs.powerTableFrequency == 0 {
@@ -427,6 +660,9 @@ func (cs *Store) Put(ctx context.Context, cert *certs.FinalityCertificate) error
 	}
 
 	// Finally, advance the latest instance pointer (always do this last) and publish.
+	if err := cs.backend.Sync(ctx); err != nil {
+		return fmt.Errorf("syncing the cert: %w", err)
+	}
 	if err := cs.writeInstanceNumber(ctx, certStoreLatestKey, cert.GPBFTInstance); err != nil {
 		return fmt.Errorf("putting recording the latest GPBFT instance: %w", err)
 	}
@@ -447,6 +683,97 @@ func (cs *Store) Put(ctx context.Context, cert *certs.FinalityCertificate) error
 	metrics.tipsetsPerInstance.Record(ctx, int64(len(cert.ECChain.Suffix())))
 	metrics.latestFinalizedEpoch.Record(ctx, cert.ECChain.Head().Epoch)
 
+	cs.maybePruneInBackground()
+
+	return nil
+}
+
+// maybePruneInBackground starts pruning in the background, unless the store retains all
+// certificates or is already pruning.
+func (cs *Store) maybePruneInBackground() {
+	if cs.retention == 0 || !cs.pruneMu.TryLock() {
+		return
+	}
+	if cs.ctx.Err() != nil {
+		// Closed.
+		cs.pruneMu.Unlock()
+		return
+	}
+	cs.wg.Add(1)
+	go func() {
+		defer cs.wg.Done()
+		defer cs.pruneMu.Unlock()
+		if err := cs.prune(cs.ctx); err != nil && cs.ctx.Err() == nil {
+			log.Errorw("failed to prune certificates", "error", err)
+		}
+	}()
+}
+
+// Prune deletes the certificates that fall outside the retention window of the store, along with
+// the power tables that are no longer needed. It is a no-op if the store retains all
+// certificates. Pruning happens in the background as certificates are added, so calling this is
+// only needed to prune eagerly.
+func (cs *Store) Prune(ctx context.Context) error {
+	if cs.retention == 0 {
+		return nil
+	}
+	cs.pruneMu.Lock()
+	defer cs.pruneMu.Unlock()
+	return cs.prune(ctx)
+}
+
+// Must be called with pruneMu held.
+func (cs *Store) prune(ctx context.Context) error {
+	latest := cs.Latest()
+	if latest == nil || latest.GPBFTInstance+1 < cs.firstInstance.Load()+cs.retention {
+		return nil
+	}
+	// Retain from the stored power table at or before the oldest certificate to retain, so that
+	// power tables can still be computed for every retained certificate.
+	horizon := latest.GPBFTInstance + 1 - cs.retention
+	pruneBefore := horizon - horizon%cs.powerTableFrequency
+	from := max(cs.firstInstance.Load(), cs.prunedBefore.Load())
+	// Resume from where an unfinished prune started, should it have crashed or failed mid-way.
+	switch pruning, err := cs.readInstanceNumber(ctx, certStorePruningKey); {
+	case errors.Is(err, datastore.ErrNotFound):
+	case err != nil:
+		return err
+	default:
+		from = min(from, pruning)
+	}
+	pruneBefore = max(pruneBefore, cs.prunedBefore.Load())
+	if pruneBefore <= from {
+		return nil
+	}
+
+	// The power table at the new horizon is kept, as the power tables of the retained
+	// certificates are computed from it. Never prune past it if it is missing.
+	if has, err := cs.ds.Has(ctx, cs.keyForPowerTable(pruneBefore)); err != nil {
+		return err
+	} else if !has {
+		return fmt.Errorf("power table at pruning horizon %d: %w", pruneBefore, ErrPowerTableNotFound)
+	}
+
+	// Record where pruning starts, then advance the horizon, before deleting anything, so that
+	// entries left over by a crash or a failed deletion are deleted by the next prune. Readers,
+	// including those of a reopened store, observe the new horizon right away, so that they
+	// never observe partially pruned state.
+	if err := cs.writeInstanceNumber(ctx, certStorePruningKey, from); err != nil {
+		return err
+	}
+	if err := cs.writeInstanceNumber(ctx, certStorePrunedKey, pruneBefore); err != nil {
+		return err
+	}
+	cs.prunedBefore.Store(pruneBefore)
+	for i := from; i < pruneBefore; i++ {
+		if err := cs.Delete(ctx, i); err != nil {
+			return fmt.Errorf("pruning instance %d: %w", i, err)
+		}
+	}
+	if err := cs.ds.Delete(ctx, certStorePruningKey); err != nil {
+		return fmt.Errorf("failed to finish pruning: %w", err)
+	}
+	metrics.prunedBefore.Record(ctx, int64(pruneBefore))
 	return nil
 }
 
@@ -516,12 +843,39 @@ func (cs *Store) DeleteAll(ctx context.Context) error {
 		return fmt.Errorf("creating a tombstone: %w", err)
 	}
 
+	return cs.continueDeleteAll(ctx)
+}
+
+// continueDeleteAll completes an interrupted DeleteAll, if any. The certificates are deleted from
+// the backend first, so that the tombstone outlives them.
+func (cs *Store) continueDeleteAll(ctx context.Context) error {
+	if ok, err := cs.ds.Has(ctx, tombstoneKey); err != nil {
+		return fmt.Errorf("checking tombstoneKey: %w", err)
+	} else if !ok {
+		return nil
+	}
+	if err := cs.backend.DeleteAll(ctx); err != nil {
+		return fmt.Errorf("deleting certificates: %w", err)
+	}
 	return maybeContinueDelete(ctx, cs.ds)
 }
 
 // Delete removes all asset belonging to an instance.
 func (cs *Store) Delete(ctx context.Context, instance uint64) error {
-	if err := cs.ds.Delete(ctx, cs.keyForCert(instance)); err != nil {
+	switch b, err := cs.backend.Get(ctx, instance); {
+	case errors.Is(err, ErrCertNotFound):
+	case err != nil:
+		return err
+	default:
+		var cert certs.FinalityCertificate
+		if err := cert.UnmarshalCBOR(bytes.NewReader(b)); err != nil {
+			return fmt.Errorf("unmarshalling cert at %d: %w", instance, err)
+		}
+		if err := cs.deleteIndex(ctx, &cert); err != nil {
+			return err
+		}
+	}
+	if err := cs.backend.Delete(ctx, instance); err != nil {
 		return err
 	}
 	return cs.ds.Delete(ctx, cs.keyForPowerTable(instance))
@@ -1,15 +1,21 @@
 package certstore
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"math"
+	"os"
+	"path/filepath"
 	"slices"
 	"testing"
 
 	"github.com/filecoin-project/go-f3/certs"
 	"github.com/filecoin-project/go-f3/gpbft"
+	"github.com/filecoin-project/go-f3/sim/signing"
 	"github.com/ipfs/go-cid"
 	datastore "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
 	"github.com/ipfs/go-datastore/query"
 	ds_sync "github.com/ipfs/go-datastore/sync"
 	"github.com/stretchr/testify/require"
@@ -185,6 +191,66 @@ func TestGetRange(t *testing.T) {
 	require.ErrorContains(t, err, "is too large")
 }
 
+func TestIterate(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ds := ds_sync.MutexWrap(datastore.NewMapDatastore())
+	pt, ptCid := testPowerTable(10)
+	supp := gpbft.SupplementalData{PowerTable: ptCid}
+
+	cs, err := CreateStore(ctx, ds, 1, pt)
+	require.NoError(t, err)
+	for i := uint64(1); i <= 5; i++ {
+		require.NoError(t, cs.Put(ctx, makeCert(i, supp)))
+	}
+
+	collect := func(start, end uint64) ([]uint64, error) {
+		var instances []uint64
+		for cert, err := range cs.Iterate(ctx, start, end) {
+			if err != nil {
+				return instances, err
+			}
+			instances = append(instances, cert.GPBFTInstance)
+		}
+		return instances, nil
+	}
+
+	instances, err := collect(1, 5)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{1, 2, 3, 4, 5}, instances)
+
+	instances, err = collect(5, 2)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{5, 4, 3, 2}, instances)
+
+	instances, err = collect(3, 3)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{3}, instances)
+
+	// Missing certs stop the iteration in either order.
+	instances, err = collect(4, 7)
+	require.ErrorIs(t, err, ErrCertNotFound)
+	require.Equal(t, []uint64{4, 5}, instances)
+	instances, err = collect(2, 0)
+	require.ErrorIs(t, err, ErrCertNotFound)
+	require.Equal(t, []uint64{2, 1}, instances)
+
+	// Breaking out of the iteration early is fine.
+	for cert, err := range cs.Iterate(ctx, 1, 5) {
+		require.NoError(t, err)
+		require.EqualValues(t, 1, cert.GPBFTInstance)
+		break
+	}
+
+	// Cancelled contexts stop the iteration.
+	cancelledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	for _, err := range cs.Iterate(cancelledCtx, 1, 5) {
+		require.ErrorIs(t, err, context.Canceled)
+	}
+}
+
 func TestDeleteAll(t *testing.T) {
 	t.Parallel()
 
@@ -560,3 +626,553 @@ func testPowerInner(t *testing.T, firstInstance uint64) {
 		require.ErrorContains(t, err, "cannot return a power table before the first instance")
 	}
 }
+
+func TestGetPowerTableByCID(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	ds := ds_sync.MutexWrap(datastore.NewMapDatastore())
+
+	pt, ptCid := testPowerTable(20)
+	cs, err := CreateStore(ctx, ds, 0, pt)
+	require.NoError(t, err)
+	cs.powerTableFrequency = 5
+
+	// Only the initial power table is known before any certificates are stored.
+	instance, actualPt, err := cs.GetPowerTableByCID(ctx, ptCid)
+	require.NoError(t, err)
+	require.Zero(t, instance)
+	require.Equal(t, pt, actualPt)
+
+	// Change the power table at instances 9 and 14.
+	var (
+		powerTables    = []gpbft.PowerEntries{pt}
+		powerTableCids = []cid.Cid{ptCid}
+	)
+	for i := uint64(0); i < 14; i++ {
+		cert := makeCert(i, gpbft.SupplementalData{PowerTable: powerTableCids[len(powerTableCids)-1]})
+		if i == 8 || i == 13 {
+			newPt := slices.Clone(powerTables[len(powerTables)-1])
+			newPt[0].PubKey = []byte(fmt.Sprintf("key at %d", i+1))
+			newPtCid, err := certs.MakePowerTableCID(newPt)
+			require.NoError(t, err)
+			cert.SupplementalData.PowerTable = newPtCid
+			cert.PowerTableDelta = certs.MakePowerTableDiff(powerTables[len(powerTables)-1], newPt)
+			powerTables = append(powerTables, newPt)
+			powerTableCids = append(powerTableCids, newPtCid)
+		}
+		require.NoError(t, cs.Put(ctx, cert))
+	}
+
+	// Found at the first instance, at the first stored power table after the change, and at the
+	// next instance, respectively.
+	for i, expectedInstance := range []uint64{0, 10, 14} {
+		instance, actualPt, err := cs.GetPowerTableByCID(ctx, powerTableCids[i])
+		require.NoError(t, err)
+		require.Equal(t, expectedInstance, instance)
+		require.Equal(t, powerTables[i], actualPt)
+	}
+
+	_, otherCid := testPowerTable(10)
+	_, _, err = cs.GetPowerTableByCID(ctx, otherCid)
+	require.ErrorIs(t, err, ErrPowerTableNotFound)
+	_, err = cs.GetPowerTable(ctx, 15)
+	require.ErrorIs(t, err, ErrPowerTableNotFound)
+}
+
+func TestRetention(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ds := ds_sync.MutexWrap(datastore.NewMapDatastore())
+	pt, ptCid := testPowerTable(10)
+	supp := gpbft.SupplementalData{PowerTable: ptCid}
+
+	cs, err := CreateStore(ctx, ds, 0, pt, WithRetention(7))
+	require.NoError(t, err)
+	cs.powerTableFrequency = 5
+
+	for i := range uint64(23) {
+		require.NoError(t, cs.Put(ctx, makeCert(i, supp)))
+	}
+	require.NoError(t, cs.Prune(ctx))
+
+	// The last 7 certificates are 16 to 22, and the nearest power table at or before 16 is
+	// stored at 15.
+	const prunedBefore = 15
+	for i := range uint64(prunedBefore) {
+		_, err := cs.Get(ctx, i)
+		require.ErrorIs(t, err, ErrCertPruned)
+		_, err = cs.GetPowerTable(ctx, i)
+		require.ErrorIs(t, err, ErrCertPruned)
+
+		_, err = cs.backend.Get(ctx, i)
+		require.ErrorIs(t, err, ErrCertNotFound)
+		has, err := cs.ds.Has(ctx, cs.keyForPowerTable(i))
+		require.NoError(t, err)
+		require.False(t, has)
+	}
+	for i := uint64(prunedBefore); i < 23; i++ {
+		cert, err := cs.Get(ctx, i)
+		require.NoError(t, err)
+		require.Equal(t, i, cert.GPBFTInstance)
+		actualPt, err := cs.GetPowerTable(ctx, i)
+		require.NoError(t, err)
+		require.Equal(t, pt, actualPt)
+	}
+	_, err = cs.GetRange(ctx, 10, 20)
+	require.ErrorIs(t, err, ErrCertPruned)
+	certs, err := cs.GetRange(ctx, prunedBefore, 20)
+	require.NoError(t, err)
+	require.Len(t, certs, 20-prunedBefore+1)
+
+	// Pruning survives reopening the store.
+	reopened, err := OpenStore(ctx, ds)
+	require.NoError(t, err)
+	reopened.powerTableFrequency = 5
+	_, err = reopened.Get(ctx, 3)
+	require.ErrorIs(t, err, ErrCertPruned)
+	_, err = reopened.Get(ctx, prunedBefore)
+	require.NoError(t, err)
+
+	// Pruned stores still open with their initial power table, which is no longer checked.
+	reopened, err = OpenOrCreateStore(ctx, ds, 0, pt)
+	require.NoError(t, err)
+	reopened.powerTableFrequency = 5
+	require.EqualValues(t, prunedBefore, reopened.FirstInstance())
+	latestPt, err := reopened.GetPowerTable(ctx, 23)
+	require.NoError(t, err)
+	require.Equal(t, pt, latestPt)
+
+	// Snapshots start at the initial instance, so pruned stores cannot export them.
+	_, _, err = reopened.ExportLatestSnapshot(ctx, &bytes.Buffer{})
+	require.ErrorIs(t, err, ErrCertPruned)
+	_, err = reopened.WriteSnapshotFrom(ctx, 22, 0, &bytes.Buffer{})
+	require.ErrorIs(t, err, ErrCertPruned)
+
+	// Without retention, nothing is pruned.
+	archive, err := CreateStore(ctx, ds_sync.MutexWrap(datastore.NewMapDatastore()), 0, pt)
+	require.NoError(t, err)
+	archive.powerTableFrequency = 5
+	for i := range uint64(23) {
+		require.NoError(t, archive.Put(ctx, makeCert(i, supp)))
+	}
+	require.NoError(t, archive.Prune(ctx))
+	_, err = archive.Get(ctx, 0)
+	require.NoError(t, err)
+}
+
+// failingBackend fails to delete the certificate at the given instance while failDelete is set.
+type failingBackend struct {
+	Backend
+	failDelete   bool
+	failDeleteAt uint64
+}
+
+func (b *failingBackend) Delete(ctx context.Context, instance uint64) error {
+	if b.failDelete && instance == b.failDeleteAt {
+		return fmt.Errorf("failed to delete cert at %d", instance)
+	}
+	return b.Backend.Delete(ctx, instance)
+}
+
+func TestPruneResumesAfterFailure(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ds := ds_sync.MutexWrap(datastore.NewMapDatastore())
+	pt, ptCid := testPowerTable(10)
+	supp := gpbft.SupplementalData{PowerTable: ptCid}
+
+	backend := &failingBackend{
+		Backend:      &datastoreBackend{ds: namespace.Wrap(ds, datastore.NewKey("/certstore"))},
+		failDelete:   true,
+		failDeleteAt: 7,
+	}
+	cs, err := CreateStore(ctx, ds, 0, pt, WithRetention(7), WithBackend(backend))
+	require.NoError(t, err)
+	cs.powerTableFrequency = 5
+	for i := range uint64(23) {
+		require.NoError(t, cs.Put(ctx, makeCert(i, supp)))
+	}
+	// Wait for pruning in the background to finish.
+	require.NoError(t, cs.Close())
+
+	// Pruning fails part-way, after the readers observe the new horizon.
+	const prunedBefore = 15
+	require.Error(t, cs.Prune(ctx))
+	_, err = cs.Get(ctx, 10)
+	require.ErrorIs(t, err, ErrCertPruned)
+	_, err = backend.Get(ctx, 10)
+	require.NoError(t, err)
+
+	// The next prune, whether or not the store was reopened in between, deletes the leftovers.
+	backend.failDelete = false
+	reopened, err := OpenStore(ctx, ds, WithRetention(7), WithBackend(backend))
+	require.NoError(t, err)
+	reopened.powerTableFrequency = 5
+	require.NoError(t, reopened.Prune(ctx))
+	for i := range uint64(prunedBefore) {
+		_, err = backend.Get(ctx, i)
+		require.ErrorIs(t, err, ErrCertNotFound)
+		has, err := reopened.ds.Has(ctx, reopened.keyForPowerTable(i))
+		require.NoError(t, err)
+		require.False(t, has)
+	}
+	has, err := reopened.ds.Has(ctx, reopened.keyForPowerTable(prunedBefore))
+	require.NoError(t, err)
+	require.True(t, has)
+	has, err = reopened.ds.Has(ctx, certStorePruningKey)
+	require.NoError(t, err)
+	require.False(t, has)
+	require.EqualValues(t, prunedBefore, reopened.PrunedBefore())
+}
+
+func TestIndex(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ds := ds_sync.MutexWrap(datastore.NewMapDatastore())
+	pt, ptCid := testPowerTable(10)
+	supp := gpbft.SupplementalData{PowerTable: ptCid}
+
+	// Each instance finalizes the tipsets at epochs 3i+1 and 3i+3, with a null round in between.
+	makeChainCert := func(instance uint64) *certs.FinalityCertificate {
+		base := int64(instance * 3)
+		cert := makeCert(instance, supp)
+		cert.ECChain = &gpbft.ECChain{
+			TipSets: []*gpbft.TipSet{
+				{Epoch: base, Key: gpbft.TipSetKey(fmt.Sprintf("tsk%d", base)), PowerTable: ptCid},
+				{Epoch: base + 1, Key: gpbft.TipSetKey(fmt.Sprintf("tsk%d", base+1)), PowerTable: ptCid},
+				{Epoch: base + 3, Key: gpbft.TipSetKey(fmt.Sprintf("tsk%d", base+3)), PowerTable: ptCid},
+			},
+		}
+		return cert
+	}
+
+	cs, err := CreateStore(ctx, ds, 0, pt)
+	require.NoError(t, err)
+	cs.powerTableFrequency = 5
+	for i := range uint64(10) {
+		require.NoError(t, cs.Put(ctx, makeChainCert(i)))
+	}
+
+	requireIndexed := func(cs *Store, instance uint64) {
+		for epoch := int64(instance*3) + 1; epoch <= int64(instance*3)+3; epoch++ {
+			cert, err := cs.GetByEpoch(ctx, epoch)
+			require.NoError(t, err)
+			require.Equal(t, instance, cert.GPBFTInstance)
+		}
+		for _, ts := range makeChainCert(instance).ECChain.Suffix() {
+			cert, err := cs.GetByTipSet(ctx, ts.Key)
+			require.NoError(t, err)
+			require.Equal(t, instance, cert.GPBFTInstance)
+		}
+	}
+	for i := range uint64(10) {
+		requireIndexed(cs, i)
+	}
+
+	_, err = cs.GetByEpoch(ctx, 31)
+	require.ErrorIs(t, err, ErrCertNotFound)
+	_, err = cs.GetByTipSet(ctx, gpbft.TipSetKey("tsk2"))
+	require.ErrorIs(t, err, ErrCertNotFound)
+	// The base of the first instance is not finalized by any certificate in the store.
+	_, err = cs.GetByTipSet(ctx, gpbft.TipSetKey("tsk0"))
+	require.ErrorIs(t, err, ErrCertNotFound)
+
+	// Pruning removes the index entries of the pruned certificates.
+	cs, err = OpenStore(ctx, ds, WithRetention(5))
+	require.NoError(t, err)
+	cs.powerTableFrequency = 5
+	require.NoError(t, cs.Prune(ctx))
+	for _, epoch := range []int64{1, 15} {
+		_, err = cs.GetByEpoch(ctx, epoch)
+		require.ErrorIs(t, err, ErrCertPruned)
+	}
+	_, err = cs.GetByTipSet(ctx, gpbft.TipSetKey("tsk1"))
+	require.ErrorIs(t, err, ErrCertNotFound)
+	for i := uint64(5); i < 10; i++ {
+		requireIndexed(cs, i)
+	}
+
+	// Stores without the index are indexed on open.
+	for i := uint64(5); i < 10; i++ {
+		cert, err := cs.Get(ctx, i)
+		require.NoError(t, err)
+		require.NoError(t, cs.deleteIndex(ctx, cert))
+	}
+	require.NoError(t, cs.ds.Delete(ctx, certStoreIndexedKey))
+	reopened, err := OpenStore(ctx, ds)
+	require.NoError(t, err)
+	reopened.powerTableFrequency = 5
+	<-reopened.indexed
+	for i := uint64(5); i < 10; i++ {
+		requireIndexed(reopened, i)
+	}
+	has, err := reopened.ds.Has(ctx, certStoreIndexedKey)
+	require.NoError(t, err)
+	require.True(t, has)
+	require.NoError(t, reopened.Close())
+}
+
+func TestVerifyAndRepair(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ds := ds_sync.MutexWrap(datastore.NewMapDatastore())
+	m, initialPowerTable, chain := generateCertChain(t, 1531, 50)
+	verifier := signing.NewFakeBackend()
+	powerTableAt := func(instance uint64) gpbft.PowerEntries {
+		pt := initialPowerTable
+		for _, cert := range chain[:instance-m.InitialInstance] {
+			var err error
+			pt, err = certs.ApplyPowerTableDiffs(pt, cert.PowerTableDelta)
+			require.NoError(t, err)
+		}
+		return pt
+	}
+
+	cs, err := CreateStore(ctx, ds, m.InitialInstance, initialPowerTable)
+	require.NoError(t, err)
+	cs.powerTableFrequency = 10
+	for _, cert := range chain {
+		require.NoError(t, cs.Put(ctx, cert))
+	}
+
+	report, err := cs.Verify(ctx, verifier, m.NetworkName)
+	require.NoError(t, err)
+	require.True(t, report.OK())
+	require.EqualValues(t, 50, report.Verified)
+	require.Equal(t, m.InitialInstance, report.FirstInstance)
+	require.Equal(t, m.InitialInstance+49, report.LatestInstance)
+
+	// Break the store: tamper with a signature, leave a gap and corrupt a power table.
+	tampered := *chain[15]
+	tampered.Signature = slices.Clone(tampered.Signature)
+	tampered.Signature[0] ^= 0xff
+	var buf bytes.Buffer
+	require.NoError(t, tampered.MarshalCBOR(&buf))
+	require.NoError(t, cs.backend.Put(ctx, 115, buf.Bytes()))
+	require.NoError(t, cs.backend.Delete(ctx, 123))
+	require.NoError(t, cs.putPowerTable(ctx, 140, initialPowerTable[1:]))
+
+	report, err = cs.Verify(ctx, verifier, m.NetworkName)
+	require.NoError(t, err)
+	require.False(t, report.OK())
+	require.EqualValues(t, 15+3+20, report.Verified)
+	require.Len(t, report.Problems, 3)
+	require.Equal(t, uint64(115), report.Problems[0].From)
+	require.Equal(t, uint64(119), report.Problems[0].To)
+	require.ErrorIs(t, report.Problems[0].Err, ErrInvalidCertificate)
+	require.Equal(t, uint64(123), report.Problems[1].From)
+	require.Equal(t, uint64(129), report.Problems[1].To)
+	require.ErrorIs(t, report.Problems[1].Err, ErrCertNotFound)
+	require.Equal(t, uint64(140), report.Problems[2].From)
+	require.Equal(t, uint64(140), report.Problems[2].To)
+	require.ErrorIs(t, report.Problems[2].Err, ErrPowerTableMismatch)
+
+	// Repairs are only accepted with the right power table and valid certificates.
+	require.Error(t, cs.Repair(ctx, verifier, m.NetworkName, initialPowerTable[1:], chain[15:20]...))
+	require.ErrorIs(t, cs.Repair(ctx, verifier, m.NetworkName, powerTableAt(115), &tampered), ErrInvalidCertificate)
+
+	require.NoError(t, cs.Repair(ctx, verifier, m.NetworkName, powerTableAt(115), chain[15:20]...))
+	require.NoError(t, cs.Repair(ctx, verifier, m.NetworkName, powerTableAt(123), chain[23:30]...))
+	// Repairing the certificate before a corrupt power table rewrites it.
+	require.NoError(t, cs.Repair(ctx, verifier, m.NetworkName, powerTableAt(139), chain[39]))
+
+	report, err = cs.Verify(ctx, verifier, m.NetworkName)
+	require.NoError(t, err)
+	require.True(t, report.OK(), "%+v", report.Problems)
+	require.EqualValues(t, 50, report.Verified)
+}
+
+func TestBackfill(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ds := ds_sync.MutexWrap(datastore.NewMapDatastore())
+	m, initialPowerTable, chain := generateCertChain(t, 1532, 50)
+	verifier := signing.NewFakeBackend()
+	powerTableAt := func(instance uint64) gpbft.PowerEntries {
+		pt := initialPowerTable
+		for _, cert := range chain[:instance-m.InitialInstance] {
+			var err error
+			pt, err = certs.ApplyPowerTableDiffs(pt, cert.PowerTableDelta)
+			require.NoError(t, err)
+		}
+		return pt
+	}
+
+	// Start from a later instance, as if bootstrapped from a snapshot.
+	first := m.InitialInstance + 30
+	cs, err := CreateStore(ctx, ds, first, powerTableAt(first))
+	require.NoError(t, err)
+	cs.powerTableFrequency = 10
+	for _, cert := range chain[30:] {
+		require.NoError(t, cs.Put(ctx, cert))
+	}
+
+	// Backfilling starts from a trusted anchor before the first instance, which every
+	// certificate is validated against.
+	_, err = cs.StartBackfill(verifier, m.NetworkName, first, powerTableAt(first))
+	require.Error(t, err)
+	forged, err := cs.StartBackfill(verifier, m.NetworkName, m.InitialInstance, initialPowerTable[1:])
+	require.NoError(t, err)
+	require.ErrorIs(t, forged.Add(ctx, chain[:10]...), ErrInvalidCertificate)
+
+	backfill, err := cs.StartBackfill(verifier, m.NetworkName, m.InitialInstance, initialPowerTable)
+	require.NoError(t, err)
+	// Certificates must continue from the next instance, validate, and end before the first
+	// instance.
+	require.ErrorIs(t, backfill.Add(ctx, chain[10:20]...), ErrInvalidCertificate)
+	tampered := *chain[9]
+	tampered.Signature = slices.Clone(tampered.Signature)
+	tampered.Signature[0] ^= 0xff
+	require.ErrorIs(t, backfill.Add(ctx, append(slices.Clone(chain[:9]), &tampered)...), ErrInvalidCertificate)
+	require.Error(t, backfill.Add(ctx, chain[:31]...))
+	require.Equal(t, m.InitialInstance, backfill.Next())
+
+	// Certificates only become part of the store once they reach the first instance.
+	require.NoError(t, backfill.Add(ctx, chain[:20]...))
+	require.Equal(t, m.InitialInstance+20, backfill.Next())
+	require.Equal(t, first, cs.FirstInstance())
+	require.NoError(t, backfill.Add(ctx, chain[20:30]...))
+	require.Equal(t, m.InitialInstance, cs.FirstInstance())
+
+	// The backfilled store is the same as one that had all the certificates from the start, and
+	// remains so once reopened.
+	cs, err = OpenStore(ctx, ds)
+	require.NoError(t, err)
+	cs.powerTableFrequency = 10
+	require.Equal(t, m.InitialInstance, cs.FirstInstance())
+	report, err := cs.Verify(ctx, verifier, m.NetworkName)
+	require.NoError(t, err)
+	require.True(t, report.OK(), "%+v", report.Problems)
+	require.EqualValues(t, 50, report.Verified)
+	pt, err := cs.GetPowerTable(ctx, m.InitialInstance+15)
+	require.NoError(t, err)
+	require.Equal(t, powerTableAt(m.InitialInstance+15), pt)
+	cert, err := cs.GetByEpoch(ctx, chain[5].ECChain.Head().Epoch)
+	require.NoError(t, err)
+	require.Equal(t, chain[5].GPBFTInstance, cert.GPBFTInstance)
+}
+
+func TestSegmentedBackend(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	countSegments := func() int {
+		matches, err := filepath.Glob(filepath.Join(dir, "*"+segmentExtension))
+		require.NoError(t, err)
+		return len(matches)
+	}
+	certAt := func(instance uint64, version byte) []byte {
+		return []byte{byte(instance), version, 0xf3}
+	}
+
+	subject, err := OpenSegments(dir)
+	require.NoError(t, err)
+	subject.rotateAt = 20
+
+	for i := range uint64(20) {
+		require.NoError(t, subject.Put(ctx, i, certAt(i, 0)))
+	}
+	// Each record takes 5 bytes, so every segment holds 4 of them.
+	require.Equal(t, 5, countSegments())
+	for i := range uint64(20) {
+		got, err := subject.Get(ctx, i)
+		require.NoError(t, err)
+		require.Equal(t, certAt(i, 0), got)
+	}
+	_, err = subject.Get(ctx, 20)
+	require.ErrorIs(t, err, ErrCertNotFound)
+
+	// Replacements take precedence, and deletions are remembered across reopening.
+	require.NoError(t, subject.Put(ctx, 15, certAt(15, 1)))
+	for i := range uint64(5) {
+		require.NoError(t, subject.Delete(ctx, i))
+	}
+	require.NoError(t, subject.Delete(ctx, 17))
+	// Deleting all the certificates in the first segment removes it, while the replacement and
+	// the deletions are appended to a new one.
+	require.Equal(t, 5, countSegments())
+	require.NoError(t, subject.Close())
+
+	// A partially written record is ignored.
+	last, err := os.OpenFile(subject.segmentPath(subject.nextSeq-1), os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = last.Write([]byte{21, 10, 0xf3})
+	require.NoError(t, err)
+	require.NoError(t, last.Close())
+
+	subject, err = OpenSegments(dir)
+	require.NoError(t, err)
+	for i := range uint64(22) {
+		got, err := subject.Get(ctx, i)
+		switch {
+		case i < 5, i == 17, i > 19:
+			require.ErrorIs(t, err, ErrCertNotFound, "instance %d", i)
+		case i == 15:
+			require.NoError(t, err)
+			require.Equal(t, certAt(i, 1), got)
+		default:
+			require.NoError(t, err)
+			require.Equal(t, certAt(i, 0), got)
+		}
+	}
+	// Writes after reopening go to a new segment.
+	require.NoError(t, subject.Put(ctx, 20, certAt(20, 0)))
+	require.Equal(t, 6, countSegments())
+
+	require.NoError(t, subject.DeleteAll(ctx))
+	require.Zero(t, countSegments())
+	_, err = subject.Get(ctx, 10)
+	require.ErrorIs(t, err, ErrCertNotFound)
+	require.NoError(t, subject.Close())
+}
+
+func TestStoreWithSegmentedBackend(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ds := ds_sync.MutexWrap(datastore.NewMapDatastore())
+	pt, ptCid := testPowerTable(10)
+	supp := gpbft.SupplementalData{PowerTable: ptCid}
+
+	backend, err := OpenSegments(t.TempDir())
+	require.NoError(t, err)
+	defer func() { require.NoError(t, backend.Close()) }()
+
+	cs, err := CreateStore(ctx, ds, 1, pt, WithBackend(backend))
+	require.NoError(t, err)
+	for i := uint64(1); i <= 10; i++ {
+		require.NoError(t, cs.Put(ctx, makeCert(i, supp)))
+	}
+
+	// Nothing but metadata ends up in the datastore.
+	results, err := cs.ds.Query(ctx, query.Query{Prefix: "/certs"})
+	require.NoError(t, err)
+	entries, err := results.Rest()
+	require.NoError(t, err)
+	require.Empty(t, entries)
+
+	reopened, err := OpenStore(ctx, ds, WithBackend(backend))
+	require.NoError(t, err)
+	require.EqualValues(t, 10, reopened.Latest().GPBFTInstance)
+	certs, err := reopened.GetRange(ctx, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, certs, 10)
+
+	require.NoError(t, reopened.DeleteAll(ctx))
+	_, err = backend.Get(ctx, 10)
+	require.ErrorIs(t, err, ErrCertNotFound)
+
+	// Certificates are not migrated to segments from the datastore of existing stores.
+	legacyDs := ds_sync.MutexWrap(datastore.NewMapDatastore())
+	legacy, err := CreateStore(ctx, legacyDs, 1, pt)
+	require.NoError(t, err)
+	require.NoError(t, legacy.Put(ctx, makeCert(1, supp)))
+	_, err = OpenStore(ctx, legacyDs, WithBackend(backend))
+	require.ErrorIs(t, err, ErrBackendMismatch)
+}
@@ -0,0 +1,239 @@
+package certstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/filecoin-project/go-f3/certs"
+	"github.com/filecoin-project/go-f3/gpbft"
+	"github.com/ipfs/go-datastore"
+	"golang.org/x/crypto/blake2b"
+)
+
+// The presence of this key indicates that every certificate in the store is indexed by epoch and
+// tipset key. Stores created before the index existed are indexed when opened.
+var certStoreIndexedKey = datastore.NewKey("/indexed")
+
+// The number of certificates indexed per batch when indexing the certificates already stored.
+const indexBatchSize = 1000
+
+// GetByEpoch returns the FinalityCertificate that finalized the given epoch, or an error derived
+// from ErrCertNotFound, or from ErrCertPruned if the certificate has been pruned. If the epoch is a
+// null round, the certificate that finalized it is the one that finalized the first tipset after it.
+func (cs *Store) GetByEpoch(ctx context.Context, epoch int64) (*certs.FinalityCertificate, error) {
+	instance, err := cs.readInstanceNumber(ctx, cs.keyForEpoch(epoch))
+	if errors.Is(err, datastore.ErrNotFound) {
+		if pruned, err := cs.epochPruned(ctx, epoch); err != nil {
+			return nil, err
+		} else if pruned {
+			return nil, fmt.Errorf("cert finalizing epoch %d: %w", epoch, ErrCertPruned)
+		}
+		return nil, cs.notIndexed(fmt.Sprintf("cert finalizing epoch %d", epoch))
+	} else if err != nil {
+		return nil, fmt.Errorf("looking up epoch %d: %w", epoch, err)
+	}
+	return cs.Get(ctx, instance)
+}
+
+// GetByTipSet returns the FinalityCertificate that finalized the tipset with the given key, or an
+// error derived from ErrCertNotFound.
+func (cs *Store) GetByTipSet(ctx context.Context, key gpbft.TipSetKey) (*certs.FinalityCertificate, error) {
+	instance, err := cs.readInstanceNumber(ctx, cs.keyForTipSet(key))
+	if errors.Is(err, datastore.ErrNotFound) {
+		return nil, cs.notIndexed(fmt.Sprintf("cert finalizing tipset %X", key))
+	} else if err != nil {
+		return nil, fmt.Errorf("looking up tipset %X: %w", key, err)
+	}
+	cert, err := cs.Get(ctx, instance)
+	if err != nil {
+		return nil, err
+	}
+	// The index is keyed by the hash of the tipset key, so double check the certificate.
+	for _, ts := range cert.ECChain.Suffix() {
+		if bytes.Equal(ts.Key, key) {
+			return cert, nil
+		}
+	}
+	return nil, fmt.Errorf("cert finalizing tipset %X: %w", key, ErrCertNotFound)
+}
+
+// epochPruned checks whether the given epoch was finalized by a pruned certificate, i.e. whether it
+// is no later than the base of the first certificate kept.
+func (cs *Store) epochPruned(ctx context.Context, epoch int64) (bool, error) {
+	prunedBefore := cs.prunedBefore.Load()
+	if prunedBefore <= cs.firstInstance.Load() {
+		return false, nil
+	}
+	first, err := cs.Get(ctx, prunedBefore)
+	if errors.Is(err, ErrCertNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("loading first cert kept: %w", err)
+	}
+	return epoch <= first.ECChain.Base().Epoch, nil
+}
+
+// notIndexed returns the error wrapping ErrCertNotFound of a lookup that missed the index, which
+// notes whether the certificates already stored are still being indexed.
+func (cs *Store) notIndexed(lookup string) error {
+	select {
+	case <-cs.indexed:
+		return fmt.Errorf("%s: %w", lookup, ErrCertNotFound)
+	default:
+		return fmt.Errorf("%s while still indexing certificates: %w", lookup, ErrCertNotFound)
+	}
+}
+
+// putIndex indexes the given certificate by the epochs it finalized, including null rounds, and
+// by the keys of the tipsets it finalized.
+func (cs *Store) putIndex(ctx context.Context, cert *certs.FinalityCertificate) error {
+	batch, err := cs.newBatch(ctx)
+	if err != nil {
+		return err
+	}
+	if err := cs.putIndexTo(ctx, batch, cert); err != nil {
+		return err
+	}
+	if err := batch.Commit(ctx); err != nil {
+		return fmt.Errorf("indexing cert at %d: %w", cert.GPBFTInstance, err)
+	}
+	return nil
+}
+
+// putIndexTo writes the index entries of the given certificate to the given batch.
+func (cs *Store) putIndexTo(ctx context.Context, batch datastore.Write, cert *certs.FinalityCertificate) error {
+	suffix := cert.ECChain.Suffix()
+	if len(suffix) == 0 {
+		return nil
+	}
+	instance := binary.BigEndian.AppendUint64(nil, cert.GPBFTInstance)
+	for epoch := cert.ECChain.Base().Epoch + 1; epoch <= cert.ECChain.Head().Epoch; epoch++ {
+		if err := batch.Put(ctx, cs.keyForEpoch(epoch), instance); err != nil {
+			return fmt.Errorf("indexing epoch %d: %w", epoch, err)
+		}
+	}
+	for _, ts := range suffix {
+		if err := batch.Put(ctx, cs.keyForTipSet(ts.Key), instance); err != nil {
+			return fmt.Errorf("indexing tipset at epoch %d: %w", ts.Epoch, err)
+		}
+	}
+	return nil
+}
+
+// deleteIndex removes the index entries of the given certificate.
+func (cs *Store) deleteIndex(ctx context.Context, cert *certs.FinalityCertificate) error {
+	suffix := cert.ECChain.Suffix()
+	if len(suffix) == 0 {
+		return nil
+	}
+	for epoch := cert.ECChain.Base().Epoch + 1; epoch <= cert.ECChain.Head().Epoch; epoch++ {
+		if err := cs.ds.Delete(ctx, cs.keyForEpoch(epoch)); err != nil {
+			return fmt.Errorf("deleting index of epoch %d: %w", epoch, err)
+		}
+	}
+	for _, ts := range suffix {
+		if err := cs.ds.Delete(ctx, cs.keyForTipSet(ts.Key)); err != nil {
+			return fmt.Errorf("deleting index of tipset at epoch %d: %w", ts.Epoch, err)
+		}
+	}
+	return nil
+}
+
+// maybeBuildIndex starts indexing all the certificates in the store in the background, unless
+// they already are. Lookups that miss the index note that indexing is still in progress until it
+// is done. Must be called once the first instance of the store is known.
+func (cs *Store) maybeBuildIndex(ctx context.Context) error {
+	if ok, err := cs.ds.Has(ctx, certStoreIndexedKey); err != nil {
+		return fmt.Errorf("checking index: %w", err)
+	} else if ok {
+		close(cs.indexed)
+		return nil
+	}
+	// Hold off pruning while indexing, so that pruned certificates are never indexed.
+	cs.pruneMu.Lock()
+	cs.wg.Add(1)
+	go func() {
+		defer cs.wg.Done()
+		defer cs.pruneMu.Unlock()
+		if err := cs.buildIndex(cs.ctx); err != nil {
+			if cs.ctx.Err() == nil {
+				log.Errorw("failed to index finality certificates", "error", err)
+			}
+			return
+		}
+		close(cs.indexed)
+	}()
+	return nil
+}
+
+func (cs *Store) buildIndex(ctx context.Context) error {
+	if latest := cs.Latest(); latest != nil {
+		from := cs.FirstInstance()
+		log.Infow("indexing finality certificates", "from", from, "to", latest.GPBFTInstance)
+		batch, err := cs.newBatch(ctx)
+		if err != nil {
+			return err
+		}
+		for cert, err := range cs.Iterate(ctx, from, latest.GPBFTInstance) {
+			if err != nil {
+				return fmt.Errorf("loading cert to index: %w", err)
+			}
+			if err := cs.putIndexTo(ctx, batch, cert); err != nil {
+				return err
+			}
+			if (cert.GPBFTInstance-from+1)%indexBatchSize == 0 {
+				if err := batch.Commit(ctx); err != nil {
+					return fmt.Errorf("indexing certs up to %d: %w", cert.GPBFTInstance, err)
+				}
+				if batch, err = cs.newBatch(ctx); err != nil {
+					return err
+				}
+			}
+		}
+		if err := batch.Commit(ctx); err != nil {
+			return fmt.Errorf("indexing certs: %w", err)
+		}
+		log.Infow("indexed finality certificates", "from", from, "to", latest.GPBFTInstance)
+	}
+	return cs.markIndexed(ctx)
+}
+
+func (cs *Store) markIndexed(ctx context.Context) error {
+	if err := cs.ds.Put(ctx, certStoreIndexedKey, []byte{1}); err != nil {
+		return fmt.Errorf("marking certificates as indexed: %w", err)
+	}
+	return nil
+}
+
+// newBatch returns a batch of writes to the datastore of the store. Should the datastore not
+// support batching, the writes are applied as they are made instead.
+func (cs *Store) newBatch(ctx context.Context) (datastore.Batch, error) {
+	if bds, ok := cs.ds.(datastore.Batching); ok {
+		batch, err := bds.Batch(ctx)
+		if err == nil {
+			return batch, nil
+		} else if !errors.Is(err, datastore.ErrBatchUnsupported) {
+			return nil, fmt.Errorf("starting a batch: %w", err)
+		}
+	}
+	return unbatched{cs.ds}, nil
+}
+
+// unbatched is a batch that applies writes as they are made.
+type unbatched struct {
+	datastore.Write
+}
+
+func (unbatched) Commit(context.Context) error { return nil }
+
+func (*Store) keyForEpoch(epoch int64) datastore.Key {
+	return datastore.NewKey(fmt.Sprintf("/epochs/%016X", uint64(epoch)))
+}
+
+func (*Store) keyForTipSet(key gpbft.TipSetKey) datastore.Key {
+	// Tipset keys are too long to use as is, so use their hash instead.
+	return datastore.NewKey(fmt.Sprintf("/tipsets/%X", blake2b.Sum256(key)))
+}
@@ -11,6 +11,7 @@ var metrics = struct {
 	latestInstance       metric.Int64Gauge
 	latestFinalizedEpoch metric.Int64Gauge
 	tipsetsPerInstance   metric.Int64Gauge
+	prunedBefore         metric.Int64Gauge
 }{
 	latestInstance: measurements.Must(meter.Int64Gauge("f3_certstore_latest_instance",
 		metric.WithDescription("The latest instance available in certstore."),
@@ -24,4 +25,8 @@ var metrics = struct {
 		metric.WithDescription("The number of new tipsets finalized per instance."),
 		metric.WithUnit("{tipset}"),
 	)),
+	prunedBefore: measurements.Must(meter.Int64Gauge("f3_certstore_pruned_before_instance",
+		metric.WithDescription("The first instance retained after pruning."),
+		metric.WithUnit("{instance}"),
+	)),
 }
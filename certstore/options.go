@@ -0,0 +1,43 @@
+package certstore
+
+// Option represents a configurable parameter of the certificate store.
+type Option func(*options) error
+
+type options struct {
+	retention uint64
+	backend   Backend
+}
+
+func newOptions(o ...Option) (*options, error) {
+	var opts options
+	for _, apply := range o {
+		if err := apply(&opts); err != nil {
+			return nil, err
+		}
+	}
+	return &opts, nil
+}
+
+// WithRetention limits the store to the last given number of finality certificates. Older
+// certificates are pruned in the background, along with the power tables that are no longer
+// needed to serve the retained certificates. Certificates are pruned at the granularity of the
+// stored power tables, so somewhat more than the given number of certificates may be kept.
+//
+// Defaults to 0, which retains all certificates.
+func WithRetention(certificates uint64) Option {
+	return func(o *options) error {
+		o.retention = certificates
+		return nil
+	}
+}
+
+// WithBackend sets the backend in which the certificates are persisted. Switching the backend
+// of an existing store is not supported; the certificates already stored are not migrated.
+//
+// Defaults to storing each certificate under its own key in the datastore of the store.
+func WithBackend(backend Backend) Option {
+	return func(o *options) error {
+		o.backend = backend
+		return nil
+	}
+}
@@ -0,0 +1,366 @@
+package certstore
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	segmentRotateAt  = 16 << 20 // 16MiB
+	segmentExtension = ".certs"
+)
+
+var _ Backend = (*SegmentedBackend)(nil)
+
+// SegmentedBackend is a Backend that appends certificates to a sequence of segment files in a
+// directory, in the style of a write-ahead log. Certificates are read with a single positioned
+// read each, which makes scanning large ranges considerably faster than on datastores that keep
+// one key per certificate.
+//
+// Each segment is a sequence of records made of the varint-encoded instance, the varint-encoded
+// length of the certificate and the certificate itself. A record of zero length marks the
+// deletion of the certificate at its instance. Replacing a certificate appends a new record, and
+// later records take precedence over earlier ones. Segments are removed once all the
+// certificates in them, and in all segments before them, have been deleted or replaced.
+//
+// Records are not synced to disk on every write, but only when a segment is completed, on Sync
+// and on Close. The store syncs the backend before recording the instances of the stored
+// certificates, so that it never refers to certificates lost in a crash.
+type SegmentedBackend struct {
+	mu sync.RWMutex
+
+	path     string
+	rotateAt int64
+	// The segments in the order they were written, where the last one is active. Only the active
+	// segment is written to.
+	segments []*segment
+	index    map[uint64]location
+	nextSeq  uint64
+}
+
+type segment struct {
+	seq  uint64
+	file *os.File
+	size int64
+	// The number of certificates in the segment that are neither deleted nor replaced.
+	live int
+	// Whether the segment can be appended to. Only true for segments created since opening.
+	writable bool
+}
+
+type location struct {
+	segment *segment
+	offset  int64
+	length  int
+}
+
+// OpenSegments opens the segmented backend in the given directory, creating it if it does not
+// exist. The caller must call Close once the backend is no longer in use.
+func OpenSegments(directory string) (*SegmentedBackend, error) {
+	b := &SegmentedBackend{
+		path:     directory,
+		rotateAt: segmentRotateAt,
+		index:    make(map[uint64]location),
+		nextSeq:  1,
+	}
+	if err := b.hydrate(); err != nil {
+		_ = b.Close()
+		return nil, fmt.Errorf("reading certificate segments: %w", err)
+	}
+	return b, nil
+}
+
+func (b *SegmentedBackend) Get(_ context.Context, instance uint64) ([]byte, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	loc, ok := b.index[instance]
+	if !ok {
+		return nil, fmt.Errorf("cert at %d: %w", instance, ErrCertNotFound)
+	}
+	cert := make([]byte, loc.length)
+	if _, err := loc.segment.file.ReadAt(cert, loc.offset); err != nil {
+		return nil, fmt.Errorf("reading cert at %d from segment %d: %w", instance, loc.segment.seq, err)
+	}
+	return cert, nil
+}
+
+func (b *SegmentedBackend) Size(_ context.Context, instance uint64) (int, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	loc, ok := b.index[instance]
+	if !ok {
+		return 0, fmt.Errorf("cert at %d: %w", instance, ErrCertNotFound)
+	}
+	return loc.length, nil
+}
+
+func (b *SegmentedBackend) Put(_ context.Context, instance uint64, cert []byte) error {
+	if len(cert) == 0 {
+		return errors.New("cannot store an empty certificate")
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	offset, seg, err := b.append(instance, cert)
+	if err != nil {
+		return err
+	}
+	b.release(instance)
+	b.index[instance] = location{segment: seg, offset: offset, length: len(cert)}
+	seg.live++
+	return b.removeDeadSegments()
+}
+
+func (b *SegmentedBackend) Delete(_ context.Context, instance uint64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.index[instance]; !ok {
+		return nil
+	}
+	if _, _, err := b.append(instance, nil); err != nil {
+		return err
+	}
+	b.release(instance)
+	return b.removeDeadSegments()
+}
+
+func (b *SegmentedBackend) DeleteAll(context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for len(b.segments) > 0 {
+		if err := b.removeSegment(); err != nil {
+			return err
+		}
+	}
+	clear(b.index)
+	return nil
+}
+
+// Sync syncs the active segment, the only one that may hold records that are not synced yet.
+func (b *SegmentedBackend) Sync(context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.segments) == 0 {
+		return nil
+	}
+	if active := b.segments[len(b.segments)-1]; active.writable {
+		if err := active.file.Sync(); err != nil {
+			return fmt.Errorf("syncing segment %d: %w", active.seq, err)
+		}
+	}
+	return nil
+}
+
+// Close syncs and closes all segment files.
+func (b *SegmentedBackend) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var err error
+	for _, seg := range b.segments {
+		if seg.writable {
+			err = errors.Join(err, seg.file.Sync())
+		}
+		err = errors.Join(err, seg.file.Close())
+	}
+	b.segments = nil
+	clear(b.index)
+	return err
+}
+
+// append writes a record to the active segment and returns the offset of the certificate in it.
+func (b *SegmentedBackend) append(instance uint64, cert []byte) (int64, *segment, error) {
+	if err := b.maybeRotate(); err != nil {
+		return 0, nil, fmt.Errorf("rotating certificate segment: %w", err)
+	}
+	active := b.segments[len(b.segments)-1]
+
+	record := binary.AppendUvarint(nil, instance)
+	record = binary.AppendUvarint(record, uint64(len(cert)))
+	offset := active.size + int64(len(record))
+	record = append(record, cert...)
+	if _, err := active.file.WriteAt(record, active.size); err != nil {
+		return 0, nil, fmt.Errorf("writing to segment %d: %w", active.seq, err)
+	}
+	active.size += int64(len(record))
+	return offset, active, nil
+}
+
+// release forgets the current location of the certificate at the given instance, if any.
+func (b *SegmentedBackend) release(instance uint64) {
+	if loc, ok := b.index[instance]; ok {
+		loc.segment.live--
+		delete(b.index, instance)
+	}
+}
+
+func (b *SegmentedBackend) maybeRotate() error {
+	if len(b.segments) > 0 {
+		if active := b.segments[len(b.segments)-1]; active.writable && active.size < b.rotateAt {
+			return nil
+		} else if active.writable {
+			if err := active.file.Sync(); err != nil {
+				return fmt.Errorf("syncing segment %d: %w", active.seq, err)
+			}
+		}
+	}
+	seq := b.nextSeq
+	file, err := os.OpenFile(b.segmentPath(seq), os.O_CREATE|os.O_RDWR|os.O_EXCL, 0666)
+	if err != nil {
+		return fmt.Errorf("creating segment %d: %w", seq, err)
+	}
+	b.segments = append(b.segments, &segment{seq: seq, file: file, writable: true})
+	b.nextSeq++
+	return nil
+}
+
+// removeDeadSegments removes the oldest segments for as long as they hold no certificates. Only
+// the oldest segments are removed so that the deletion records in later segments are retained
+// for as long as the certificates they delete may be in earlier ones.
+func (b *SegmentedBackend) removeDeadSegments() error {
+	for len(b.segments) > 1 && b.segments[0].live == 0 {
+		if err := b.removeSegment(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeSegment removes the oldest segment.
+func (b *SegmentedBackend) removeSegment() error {
+	seg := b.segments[0]
+	if err := seg.file.Close(); err != nil {
+		return fmt.Errorf("closing segment %d: %w", seg.seq, err)
+	}
+	if err := os.Remove(b.segmentPath(seg.seq)); err != nil {
+		return fmt.Errorf("removing segment %d: %w", seg.seq, err)
+	}
+	b.segments = b.segments[1:]
+	return nil
+}
+
+func (b *SegmentedBackend) segmentPath(seq uint64) string {
+	return filepath.Join(b.path, fmt.Sprintf("%016X%s", seq, segmentExtension))
+}
+
+func (b *SegmentedBackend) hydrate() error {
+	if err := os.MkdirAll(b.path, 0777); err != nil {
+		return fmt.Errorf("making segment directory at %q: %w", b.path, err)
+	}
+	dirEntries, err := os.ReadDir(b.path)
+	if err != nil {
+		return fmt.Errorf("reading dir entry at %q: %w", b.path, err)
+	}
+
+	var seqs []uint64
+	for _, entry := range dirEntries {
+		name, ok := strings.CutSuffix(entry.Name(), segmentExtension)
+		if !ok {
+			continue
+		}
+		seq, err := strconv.ParseUint(name, 16, 64)
+		if err != nil {
+			log.Warnw("ignoring unexpected file in certificate segment directory", "name", entry.Name())
+			continue
+		}
+		seqs = append(seqs, seq)
+	}
+	slices.Sort(seqs)
+
+	for _, seq := range seqs {
+		if err := b.readSegment(seq); err != nil {
+			return err
+		}
+		b.nextSeq = seq + 1
+	}
+	return b.removeDeadSegments()
+}
+
+// readSegment reads in the records of the segment with the given sequence number. Like the
+// write-ahead log, it tolerates a truncated last record, e.g. after a crash mid-write.
+func (b *SegmentedBackend) readSegment(seq uint64) error {
+	file, err := os.Open(b.segmentPath(seq))
+	if err != nil {
+		return fmt.Errorf("opening segment %d: %w", seq, err)
+	}
+	seg := &segment{seq: seq, file: file}
+	b.segments = append(b.segments, seg)
+
+	reader := &countingReader{r: bufio.NewReader(file)}
+	for {
+		instance, length, err := readSegmentRecordHeader(reader)
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			log.Errorw("got error while reading certificate segment", "segment", seq, "error", err)
+			break
+		}
+		offset := reader.n
+		if _, err := reader.Discard(int(length)); err != nil {
+			log.Errorw("got error while reading certificate segment", "segment", seq, "error", err)
+			break
+		}
+		b.release(instance)
+		if length > 0 {
+			b.index[instance] = location{segment: seg, offset: offset, length: int(length)}
+			seg.live++
+		}
+		seg.size = reader.n
+	}
+	return nil
+}
+
+func readSegmentRecordHeader(reader *countingReader) (uint64, uint64, error) {
+	instance, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return 0, 0, err
+	}
+	length, err := binary.ReadUvarint(reader)
+	if errors.Is(err, io.EOF) {
+		return 0, 0, io.ErrUnexpectedEOF
+	} else if err != nil {
+		return 0, 0, err
+	}
+	if length > maxCertificateSize {
+		return 0, 0, fmt.Errorf("certificate at instance %d is too large: %d", instance, length)
+	}
+	return instance, length, nil
+}
+
+// The maximum size of a certificate record, as a sanity check when reading segments.
+const maxCertificateSize = 16 << 20
+
+// countingReader counts the bytes read from the underlying reader.
+type countingReader struct {
+	r *bufio.Reader
+	n int64
+}
+
+func (c *countingReader) ReadByte() (byte, error) {
+	v, err := c.r.ReadByte()
+	if err == nil {
+		c.n++
+	}
+	return v, err
+}
+
+func (c *countingReader) Discard(n int) (int, error) {
+	discarded, err := c.r.Discard(n)
+	c.n += int64(discarded)
+	return discarded, err
+}
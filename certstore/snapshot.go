@@ -1,6 +1,7 @@
 package certstore
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/binary"
@@ -35,40 +36,162 @@ func (cs *Store) ExportLatestSnapshot(ctx context.Context, writer io.Writer) (ci
 	return cs.ExportSnapshot(ctx, cs.latestCertificate.GPBFTInstance, writer)
 }
 
-// ExportSnapshot exports an F3 snapshot that includes the finality certificate chain from the `Store.firstInstance` to the specified `lastInstance`.
+// ExportSnapshot exports an F3 snapshot that includes the finality certificate chain from the initial instance of the store to the
+// specified `lastInstance`. Snapshots start at the initial instance so that they are validated against the manifest on import, which
+// pruned stores no longer hold; exporting from them fails with an error wrapping ErrCertPruned.
 //
 // Checkout the snapshot format specification at <https://github.com/filecoin-project/FIPs/blob/master/FRCs/frc-0108.md>
 func (cs *Store) ExportSnapshot(ctx context.Context, latestInstance uint64, writer io.Writer) (cid.Cid, *SnapshotHeader, error) {
+	firstInstance, err := cs.snapshotFirstInstance()
+	if err != nil {
+		return cid.Undef, nil, err
+	}
+	return cs.exportSnapshot(ctx, firstInstance, latestInstance, writer)
+}
+
+// snapshotFirstInstance returns the first instance of the snapshots exported by the store, or an
+// error wrapping ErrCertPruned if it has been pruned.
+func (cs *Store) snapshotFirstInstance() (uint64, error) {
+	firstInstance := cs.firstInstance.Load()
+	if prunedBefore := cs.prunedBefore.Load(); prunedBefore > firstInstance {
+		return 0, fmt.Errorf("cannot export a snapshot from the initial instance %d once pruned before %d: %w", firstInstance, prunedBefore, ErrCertPruned)
+	}
+	return firstInstance, nil
+}
+
+// ExportDeltaSnapshot exports an F3 delta snapshot that includes the finality certificate chain from the specified `firstInstance` to
+// the specified `latestInstance`. The delta extends a store whose latest certificate is at `firstInstance - 1`, and can be applied on
+// top of it via ImportSnapshotToDatastore.
+//
+// The header of a delta snapshot carries the power table used to validate the certificate at `firstInstance`, which must match the
+// given `basePowerTable` CID. That is, the power table that the store the delta is destined for expects next.
+func (cs *Store) ExportDeltaSnapshot(ctx context.Context, firstInstance uint64, basePowerTable cid.Cid, latestInstance uint64, writer io.Writer) (cid.Cid, *SnapshotHeader, error) {
+	if firstInstance < cs.firstInstance.Load() {
+		return cid.Undef, nil, fmt.Errorf("cannot export a delta snapshot from instance %d before the first instance %d", firstInstance, cs.firstInstance.Load())
+	}
+	if latestInstance < firstInstance {
+		return cid.Undef, nil, fmt.Errorf("cannot export a delta snapshot with latest instance %d before its first instance %d", latestInstance, firstInstance)
+	}
+	powerTable, err := cs.GetPowerTable(ctx, firstInstance)
+	if err != nil {
+		return cid.Undef, nil, fmt.Errorf("failed to get base power table at instance %d: %w", firstInstance, err)
+	}
+	if err := checkPowerTable(powerTable, basePowerTable); err != nil {
+		return cid.Undef, nil, fmt.Errorf("base power table at instance %d: %w", firstInstance, err)
+	}
+	return cs.exportSnapshot(ctx, firstInstance, latestInstance, writer)
+}
+
+func (cs *Store) exportSnapshot(ctx context.Context, firstInstance, latestInstance uint64, writer io.Writer) (cid.Cid, *SnapshotHeader, error) {
 	hasher, err := blake2b.New256(nil)
 	if err != nil {
 		return cid.Undef, nil, err
 	}
 	hashWriter := hashWriter{hasher, writer}
-	initialPowerTable, err := cs.GetPowerTable(ctx, cs.firstInstance)
+	initialPowerTable, err := cs.GetPowerTable(ctx, firstInstance)
 	if err != nil {
-		return cid.Undef, nil, fmt.Errorf("failed to get initial power table at instance %d: %w", cs.firstInstance, err)
+		return cid.Undef, nil, fmt.Errorf("failed to get initial power table at instance %d: %w", firstInstance, err)
 	}
-	header := SnapshotHeader{1, cs.firstInstance, latestInstance, initialPowerTable}
+	header := SnapshotHeader{1, firstInstance, latestInstance, initialPowerTable}
 	if _, err := header.WriteTo(hashWriter); err != nil {
 		return cid.Undef, nil, fmt.Errorf("failed to write snapshot header: %w", err)
 	}
-	for i := cs.firstInstance; i <= latestInstance; i++ {
-		cert, err := cs.ds.Get(ctx, cs.keyForCert(i))
+	var writeErr error
+	if err := cs.iterateRaw(ctx, firstInstance, latestInstance, func(_ uint64, cert []byte) bool {
+		_, writeErr = writeSnapshotBlockBytes(hashWriter, bytes.NewBuffer(cert))
+		return writeErr == nil
+	}); err != nil {
+		return cid.Undef, nil, fmt.Errorf("failed to get certificate: %w", err)
+	}
+	if writeErr != nil {
+		return cid.Undef, nil, writeErr
+	}
+	snapshotCid, err := makeSnapshotCid(hashWriter.hasher)
+	if err != nil {
+		return cid.Undef, nil, err
+	}
+	return snapshotCid, &header, nil
+}
+
+// WriteSnapshotFrom writes the snapshot that ExportSnapshot exports up to the given latest
+// instance, starting at the given byte offset into it, and returns the number of bytes written.
+// The certificates before the offset are skipped by their size without being read, and nothing
+// is hashed, so that resuming the transfer of a large snapshot costs no more than the remaining
+// bytes.
+func (cs *Store) WriteSnapshotFrom(ctx context.Context, latestInstance, offset uint64, writer io.Writer) (int64, error) {
+	firstInstance, err := cs.snapshotFirstInstance()
+	if err != nil {
+		return 0, err
+	}
+	initialPowerTable, err := cs.GetPowerTable(ctx, firstInstance)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get initial power table at instance %d: %w", firstInstance, err)
+	}
+	var header bytes.Buffer
+	if _, err := writeSnapshotCborEncodedBlock(&header, &SnapshotHeader{1, firstInstance, latestInstance, initialPowerTable}); err != nil {
+		return 0, fmt.Errorf("failed to write snapshot header: %w", err)
+	}
+
+	var (
+		written  int64
+		position uint64
+	)
+	write := func(block []byte) error {
+		end := position + uint64(len(block))
+		if end > offset {
+			n, err := writer.Write(block[max(offset, position)-position:])
+			written += int64(n)
+			if err != nil {
+				return err
+			}
+		}
+		position = end
+		return nil
+	}
+	if err := write(header.Bytes()); err != nil {
+		return written, err
+	}
+	for i := firstInstance; i <= latestInstance; i++ {
+		if err := ctx.Err(); err != nil {
+			return written, err
+		}
+		if i < cs.prunedBefore.Load() {
+			return written, fmt.Errorf("cert at %d: %w", i, ErrCertPruned)
+		}
+		if position < offset {
+			size, err := cs.backend.Size(ctx, i)
+			if err != nil {
+				return written, fmt.Errorf("failed to get size of certificate at %d: %w", i, err)
+			}
+			if length := uint64(uvarintLen(uint64(size)) + size); position+length <= offset {
+				position += length
+				continue
+			}
+		}
+		cert, err := cs.backend.Get(ctx, i)
 		if err != nil {
-			return cid.Undef, nil, fmt.Errorf("failed to get certificate at instance %d:: %w", i, err)
+			return written, fmt.Errorf("failed to get certificate: %w", err)
 		}
-		buffer := bytes.NewBuffer(cert)
-		if _, err := writeSnapshotBlockBytes(hashWriter, buffer); err != nil {
-			return cid.Undef, nil, err
+		block := binary.AppendUvarint(make([]byte, 0, binary.MaxVarintLen64+len(cert)), uint64(len(cert)))
+		if err := write(append(block, cert...)); err != nil {
+			return written, err
 		}
 	}
-	hash := hashWriter.hasher.Sum(nil)
-	mh, err := multihash.Encode(hash, multihash.BLAKE2B_MIN+31)
+	return written, nil
+}
+
+func uvarintLen(v uint64) int {
+	var buf [binary.MaxVarintLen64]byte
+	return binary.PutUvarint(buf[:], v)
+}
+
+// makeSnapshotCid returns the CID of a snapshot given the hasher that has consumed its content.
+func makeSnapshotCid(hasher hash.Hash) (cid.Cid, error) {
+	mh, err := multihash.Encode(hasher.Sum(nil), multihash.BLAKE2B_MIN+31)
 	if err != nil {
-		return cid.Undef, nil, err
+		return cid.Undef, err
 	}
-
-	return cid.NewCidV1(cid.Raw, mh), &header, nil
+	return cid.NewCidV1(cid.Raw, mh), nil
 }
 
 type hashWriter struct {
@@ -90,12 +213,34 @@ type SnapshotReader interface {
 
 // ImportSnapshotToDatastore imports an F3 snapshot into the specified Datastore.
 // This function optionally validates the F3 snapshot against the manifest if provided.
+//
+// If the Datastore already holds a certificate store and the snapshot starts after its first instance, the snapshot is applied as a
+// delta on top of it. In that case, the snapshot must start right after the latest certificate in the store, with the power table
+// the store expects next.
+// The given options must match those the store is later opened with, e.g. WithBackend.
 // Checkout the snapshot format specification at <https://github.com/filecoin-project/FIPs/blob/master/FRCs/frc-0108.md>
-func ImportSnapshotToDatastore(ctx context.Context, snapshot SnapshotReader, ds datastore.Batching, m *manifest.Manifest) error {
-	return importSnapshotToDatastoreWithTestingPowerTableFrequency(ctx, snapshot, ds, m, 0)
+func ImportSnapshotToDatastore(ctx context.Context, snapshot SnapshotReader, ds datastore.Batching, m *manifest.Manifest, o ...Option) error {
+	return importSnapshotToDatastoreWithTestingPowerTableFrequency(ctx, snapshot, ds, m, 0, o...)
 }
 
-func importSnapshotToDatastoreWithTestingPowerTableFrequency(ctx context.Context, snapshot SnapshotReader, ds datastore.Batching, m *manifest.Manifest, testingPowerTableFrequency uint64) error {
+// ImportSnapshotToDatastoreWithCID imports an F3 snapshot into the specified Datastore, just like
+// ImportSnapshotToDatastore, and returns the CID of the imported snapshot as computed by
+// ExportSnapshot. Callers that trust a specific snapshot CID must compare it against the returned
+// one, and discard the imported data on mismatch.
+func ImportSnapshotToDatastoreWithCID(ctx context.Context, snapshot io.Reader, ds datastore.Batching, m *manifest.Manifest, o ...Option) (cid.Cid, error) {
+	hasher, err := blake2b.New256(nil)
+	if err != nil {
+		return cid.Undef, err
+	}
+	reader := bufio.NewReader(io.TeeReader(snapshot, hasher))
+	if err := importSnapshotToDatastoreWithTestingPowerTableFrequency(ctx, reader, ds, m, 0, o...); err != nil {
+		return cid.Undef, err
+	}
+	// The import reads the snapshot until EOF, so the hasher has consumed all of it.
+	return makeSnapshotCid(hasher)
+}
+
+func importSnapshotToDatastoreWithTestingPowerTableFrequency(ctx context.Context, snapshot SnapshotReader, ds datastore.Batching, m *manifest.Manifest, testingPowerTableFrequency uint64, o ...Option) error {
 	headerBytes, err := readSnapshotBlockBytes(snapshot)
 	if err != nil {
 		return err
@@ -105,24 +250,51 @@ func importSnapshotToDatastoreWithTestingPowerTableFrequency(ctx context.Context
 	if err != nil {
 		return fmt.Errorf("failed to decode snapshot header: %w", err)
 	}
-	// validate the header against the manifest if provided
-	if m != nil {
-		if m.InitialInstance != header.FirstInstance {
-			return fmt.Errorf("F3 initial instance in the snapshot(%d) does not match that in the manifest(%d)", header.FirstInstance, m.InitialInstance)
-		}
-		if m.InitialPowerTable.Defined() {
-			ptCid, err := certs.MakePowerTableCID(header.InitialPowerTable)
-			if err != nil {
-				return fmt.Errorf("failed to make initial power table CID: %w", err)
-			} else if m.InitialPowerTable != ptCid {
-				return fmt.Errorf("F3 initial power table CID in the snapshot(%s) does not match that in the manifest(%s)", ptCid, m.InitialPowerTable)
-			}
-		}
+	// Read the first certificate ahead, so that a delta is checked against the store before any of
+	// it is imported.
+	firstCertBytes, err := readSnapshotBlockBytes(snapshot)
+	if err == io.EOF {
+		return ErrNoCertificateExtracted
+	} else if err != nil {
+		return fmt.Errorf("failed to decode finality certificate: %w", err)
+	}
+	var firstCert certs.FinalityCertificate
+	if err := firstCert.UnmarshalCBOR(bytes.NewReader(firstCertBytes)); err != nil {
+		return err
 	}
 	dsb := autobatch.NewAutoBatching(ds, 1000)
 	defer dsb.Flush(ctx)
-	cs, err := OpenOrCreateStore(ctx, dsb, header.FirstInstance, header.InitialPowerTable)
-	if err != nil {
+	// The store is only written to, and through a datastore that is not safe for concurrent use,
+	// so never index it in the background.
+	cs, err := openStore(ctx, dsb, o...)
+	switch {
+	case err == nil && header.FirstInstance > cs.firstInstance.Load():
+		if err := checkDeltaContinuity(cs, &header, &firstCert); err != nil {
+			return err
+		}
+		if m != nil && m.InitialInstance != cs.firstInstance.Load() {
+			return fmt.Errorf("F3 initial instance in the store(%d) does not match that in the manifest(%d)", cs.firstInstance.Load(), m.InitialInstance)
+		}
+	case err == nil || errors.Is(err, ErrNotInitialized):
+		// validate the header against the manifest if provided
+		if m != nil {
+			if m.InitialInstance != header.FirstInstance {
+				return fmt.Errorf("F3 initial instance in the snapshot(%d) does not match that in the manifest(%d)", header.FirstInstance, m.InitialInstance)
+			}
+			if m.InitialPowerTable.Defined() {
+				ptCid, err := certs.MakePowerTableCID(header.InitialPowerTable)
+				if err != nil {
+					return fmt.Errorf("failed to make initial power table CID: %w", err)
+				} else if m.InitialPowerTable != ptCid {
+					return fmt.Errorf("F3 initial power table CID in the snapshot(%s) does not match that in the manifest(%s)", ptCid, m.InitialPowerTable)
+				}
+			}
+		}
+		cs, err = openOrCreateStore(ctx, dsb, header.FirstInstance, header.InitialPowerTable, o...)
+		if err != nil {
+			return err
+		}
+	default:
 		return err
 	}
 	if testingPowerTableFrequency > 0 {
@@ -130,12 +302,15 @@ func importSnapshotToDatastoreWithTestingPowerTableFrequency(ctx context.Context
 	}
 	var latestCert *certs.FinalityCertificate
 	ptm := certs.PowerTableArrayToMap(header.InitialPowerTable)
+	certBytes := firstCertBytes
 	for i := header.FirstInstance; ; i += 1 {
-		certBytes, err := readSnapshotBlockBytes(snapshot)
-		if err == io.EOF {
-			break
-		} else if err != nil {
-			return fmt.Errorf("failed to decode finality certificate: %w", err)
+		if i > header.FirstInstance {
+			certBytes, err = readSnapshotBlockBytes(snapshot)
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return fmt.Errorf("failed to decode finality certificate: %w", err)
+			}
 		}
 
 		var cert certs.FinalityCertificate
@@ -152,7 +327,10 @@ func importSnapshotToDatastoreWithTestingPowerTableFrequency(ctx context.Context
 			return fmt.Errorf("certificate of instance %d is found, expected latest instance %d", i, header.LatestInstance)
 		}
 
-		if err := cs.ds.Put(ctx, cs.keyForCert(cert.GPBFTInstance), certBytes); err != nil {
+		if err := cs.backend.Put(ctx, cert.GPBFTInstance, certBytes); err != nil {
+			return err
+		}
+		if err := cs.putIndex(ctx, &cert); err != nil {
 			return err
 		}
 
@@ -184,9 +362,34 @@ func importSnapshotToDatastoreWithTestingPowerTableFrequency(ctx context.Context
 		return err
 	}
 
+	if err := cs.backend.Sync(ctx); err != nil {
+		return fmt.Errorf("syncing certificates: %w", err)
+	}
 	return cs.writeInstanceNumber(ctx, certStoreLatestKey, header.LatestInstance)
 }
 
+// checkDeltaContinuity checks that the snapshot with the given header and first certificate
+// extends the given store.
+func checkDeltaContinuity(cs *Store, header *SnapshotHeader, first *certs.FinalityCertificate) error {
+	latest := cs.Latest()
+	if latest == nil {
+		return fmt.Errorf("cannot apply a delta snapshot from instance %d to a store without certificates", header.FirstInstance)
+	}
+	if latest.GPBFTInstance+1 != header.FirstInstance {
+		return fmt.Errorf("delta snapshot from instance %d does not continue from the latest instance %d in the store", header.FirstInstance, latest.GPBFTInstance)
+	}
+	if err := checkPowerTable(header.InitialPowerTable, latest.SupplementalData.PowerTable); err != nil {
+		return fmt.Errorf("delta snapshot base power table does not match the store: %w", err)
+	}
+	if first.GPBFTInstance != header.FirstInstance {
+		return fmt.Errorf("the certificate of instance %d is missing", header.FirstInstance)
+	}
+	if base, head := first.ECChain.Base(), latest.ECChain.Head(); !base.Equal(head) {
+		return fmt.Errorf("delta snapshot chain base %s does not continue from the head %s of the latest certificate in the store", base, head)
+	}
+	return nil
+}
+
 func checkPowerTable(pt gpbft.PowerEntries, expectedCid cid.Cid) error {
 	ptCid, err := certs.MakePowerTableCID(pt)
 	if err != nil {
@@ -1,6 +1,7 @@
 package certstore
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"math/rand"
@@ -118,6 +119,12 @@ func Test_SnapshotExportImportRoundTrip(t *testing.T) {
 
 	require.NotEqual(t, ds1, ds3)
 
+	ds5 := datastore.NewMapDatastore()
+	importedCid, err := ImportSnapshotToDatastoreWithCID(ctx, bytes.NewReader(snapshot.Bytes()), ds5, &m)
+	require.NoError(t, err)
+	require.Equal(t, c, importedCid)
+	require.Equal(t, ds3, ds5)
+
 	// Test manifest validation logic
 	ds4 := datastore.NewMapDatastore()
 	m2 := manifest.LocalDevnetManifest()
@@ -134,6 +141,191 @@ func Test_SnapshotExportImportRoundTrip(t *testing.T) {
 	require.ErrorContains(t, err, "initial power table CID")
 }
 
+// generateCertChain generates a valid chain of finality certificates starting at instance 100.
+func generateCertChain(t *testing.T, seed int64, certChainLength uint64) (manifest.Manifest, gpbft.PowerEntries, []*certs.FinalityCertificate) {
+	ctx, clk := clock.WithMockClock(context.Background())
+	m := manifest.LocalDevnetManifest()
+	m.InitialInstance = 100
+	signVerifier := signing.NewFakeBackend()
+	rng := rand.New(rand.NewSource(seed * 23))
+	generatePublicKey := func(id gpbft.ActorID) gpbft.PubKey {
+		//TODO: add the ability to evolve public key across instances. Fake signing
+		//      backed does not support this.
+
+		// Use allow instead of GenerateKey for a reproducible key generation.
+		return signVerifier.Allow(int(id))
+	}
+	initialPowerTable := generatePowerTable(t, rng, generatePublicKey, nil)
+	ptCid, err := certs.MakePowerTableCID(initialPowerTable)
+	require.NoError(t, err)
+	m.InitialPowerTable = ptCid
+
+	ec := consensus.NewFakeEC(
+		consensus.WithClock(clk),
+		consensus.WithSeed(seed*13),
+		consensus.WithBootstrapEpoch(m.BootstrapEpoch),
+		consensus.WithECPeriod(m.EC.Period),
+		consensus.WithInitialPowerTable(initialPowerTable),
+		consensus.WithEvolvingPowerTable(
+			func(epoch int64, entries gpbft.PowerEntries) gpbft.PowerEntries {
+				if epoch == m.BootstrapEpoch-m.EC.Finality {
+					return initialPowerTable
+				}
+				rng := rand.New(rand.NewSource(epoch * seed))
+				next := generatePowerTable(t, rng, generatePublicKey, entries)
+				return next
+			},
+		),
+	)
+
+	subject, err := certchain.New(
+		certchain.WithSeed(seed),
+		certchain.WithSignVerifier(signVerifier),
+		certchain.WithManifest(m),
+		certchain.WithEC(ec),
+	)
+	require.NoError(t, err)
+
+	// The mock clock is buried into context passed to fake EC. The face EC will
+	// refuse to generate a chain if the clock is not advanced. Advance it
+	// sufficiently to never be bothered by it again.
+	//
+	// The fake EC and its relationship with clock needs to be reworked: Clock should
+	// ideally be passed as an option, and its absence should mean "advance the clock
+	// as needed". Because, we do not always care about controlling the progress of
+	// chain generated by fake EC.
+	clk.Add(200 * time.Hour)
+
+	generatedChain, err := subject.Generate(ctx, certChainLength)
+	require.NoError(t, err)
+	return m, initialPowerTable, generatedChain
+}
+
+func Test_DeltaSnapshotExportImport(t *testing.T) {
+	const (
+		seed                      = 1451
+		certChainLength           = 100
+		deltaStart                = 60
+		testingPowerTableFreqency = uint64(23)
+	)
+
+	ctx := context.Background()
+	m, initialPowerTable, generatedChain := generateCertChain(t, seed, certChainLength)
+
+	source, err := OpenOrCreateStore(ctx, datastore.NewMapDatastore(), m.InitialInstance, initialPowerTable)
+	require.NoError(t, err)
+	source.powerTableFrequency = testingPowerTableFreqency
+	for _, cert := range generatedChain {
+		require.NoError(t, source.Put(ctx, cert))
+	}
+
+	// Export the base and the delta.
+	var base, delta bytes.Buffer
+	_, _, err = source.ExportSnapshot(ctx, generatedChain[deltaStart-1].GPBFTInstance, &base)
+	require.NoError(t, err)
+	basePowerTable := generatedChain[deltaStart-1].SupplementalData.PowerTable
+	deltaFirst := generatedChain[deltaStart].GPBFTInstance
+	latest := generatedChain[certChainLength-1].GPBFTInstance
+	_, header, err := source.ExportDeltaSnapshot(ctx, deltaFirst, basePowerTable, latest, &delta)
+	require.NoError(t, err)
+	require.Equal(t, deltaFirst, header.FirstInstance)
+	require.Equal(t, latest, header.LatestInstance)
+
+	// The base power table must match.
+	_, _, err = source.ExportDeltaSnapshot(ctx, deltaFirst, m.InitialPowerTable, latest, &bytes.Buffer{})
+	require.ErrorContains(t, err, "base power table")
+
+	// A delta cannot be applied to an empty store, nor to one that does not end right before it.
+	err = importSnapshotToDatastoreWithTestingPowerTableFrequency(ctx, bytes.NewReader(delta.Bytes()), datastore.NewMapDatastore(), &m, testingPowerTableFreqency)
+	require.Error(t, err)
+
+	var shortBase bytes.Buffer
+	_, _, err = source.ExportSnapshot(ctx, generatedChain[deltaStart-2].GPBFTInstance, &shortBase)
+	require.NoError(t, err)
+	gapped := datastore.NewMapDatastore()
+	require.NoError(t, importSnapshotToDatastoreWithTestingPowerTableFrequency(ctx, bytes.NewReader(shortBase.Bytes()), gapped, &m, testingPowerTableFreqency))
+	err = importSnapshotToDatastoreWithTestingPowerTableFrequency(ctx, bytes.NewReader(delta.Bytes()), gapped, &m, testingPowerTableFreqency)
+	require.ErrorContains(t, err, "does not continue")
+
+	// The first certificate of a delta must extend the head of the latest certificate in the store.
+	{
+		reader := bufio.NewReader(bytes.NewReader(delta.Bytes()))
+		headerBytes, err := readSnapshotBlockBytes(reader)
+		require.NoError(t, err)
+		firstBytes, err := readSnapshotBlockBytes(reader)
+		require.NoError(t, err)
+		var first certs.FinalityCertificate
+		require.NoError(t, first.UnmarshalCBOR(bytes.NewReader(firstBytes)))
+		forkedBase := *first.ECChain.Base()
+		forkedBase.Key = []byte("fork")
+		first.ECChain = &gpbft.ECChain{TipSets: append([]*gpbft.TipSet{&forkedBase}, first.ECChain.Suffix()...)}
+
+		var forked bytes.Buffer
+		_, err = writeSnapshotBlockBytes(&forked, bytes.NewBuffer(headerBytes))
+		require.NoError(t, err)
+		_, err = writeSnapshotCborEncodedBlock(&forked, &first)
+		require.NoError(t, err)
+		_, err = reader.WriteTo(&forked)
+		require.NoError(t, err)
+
+		ds := datastore.NewMapDatastore()
+		require.NoError(t, importSnapshotToDatastoreWithTestingPowerTableFrequency(ctx, bytes.NewReader(base.Bytes()), ds, &m, testingPowerTableFreqency))
+		err = importSnapshotToDatastoreWithTestingPowerTableFrequency(ctx, bytes.NewReader(forked.Bytes()), ds, &m, testingPowerTableFreqency)
+		require.ErrorContains(t, err, "chain base")
+	}
+
+	// Base followed by delta yields the same store as the full snapshot.
+	ds := datastore.NewMapDatastore()
+	require.NoError(t, importSnapshotToDatastoreWithTestingPowerTableFrequency(ctx, bytes.NewReader(base.Bytes()), ds, &m, testingPowerTableFreqency))
+	require.NoError(t, importSnapshotToDatastoreWithTestingPowerTableFrequency(ctx, bytes.NewReader(delta.Bytes()), ds, &m, testingPowerTableFreqency))
+
+	var full bytes.Buffer
+	_, _, err = source.ExportLatestSnapshot(ctx, &full)
+	require.NoError(t, err)
+	expected := datastore.NewMapDatastore()
+	require.NoError(t, importSnapshotToDatastoreWithTestingPowerTableFrequency(ctx, bytes.NewReader(full.Bytes()), expected, &m, testingPowerTableFreqency))
+	require.Equal(t, expected, ds)
+
+	imported, err := OpenStore(ctx, ds)
+	require.NoError(t, err)
+	require.Equal(t, latest, imported.Latest().GPBFTInstance)
+}
+
+func Test_WriteSnapshotFrom(t *testing.T) {
+	const (
+		seed            = 1481
+		certChainLength = 30
+	)
+
+	ctx := context.Background()
+	m, initialPowerTable, generatedChain := generateCertChain(t, seed, certChainLength)
+	cs, err := OpenOrCreateStore(ctx, datastore.NewMapDatastore(), m.InitialInstance, initialPowerTable)
+	require.NoError(t, err)
+	for _, cert := range generatedChain {
+		require.NoError(t, cs.Put(ctx, cert))
+	}
+	latest := generatedChain[certChainLength-1].GPBFTInstance
+
+	var expected bytes.Buffer
+	_, _, err = cs.ExportSnapshot(ctx, latest, &expected)
+	require.NoError(t, err)
+
+	size := uint64(expected.Len())
+	offsets := []uint64{0, 1, size - 1, size, size + 1}
+	for offset := uint64(0); offset < size; offset += size/17 + 1 {
+		offsets = append(offsets, offset)
+	}
+	for _, offset := range offsets {
+		var got bytes.Buffer
+		written, err := cs.WriteSnapshotFrom(ctx, latest, offset, &got)
+		require.NoError(t, err)
+		// Compare the contents only, as nothing is written from the end of the snapshot on.
+		want := expected.Bytes()[min(offset, size):]
+		require.True(t, bytes.Equal(want, got.Bytes()), "offset %d", offset)
+		require.EqualValues(t, len(want), written, "offset %d", offset)
+	}
+}
+
 func generatePowerTable(t *testing.T, rng *rand.Rand, generatePublicKey func(id gpbft.ActorID) gpbft.PubKey, previousEntries gpbft.PowerEntries) gpbft.PowerEntries {
 	const (
 		maxEntries             = 100
@@ -143,7 +335,8 @@ func generatePowerTable(t *testing.T, rng *rand.Rand, generatePublicKey func(id
 		powerChangeProbability = 0.2
 	)
 
-	size := rng.Intn(maxEntries)
+	// Never generate an empty power table, which no certificate may lead to.
+	size := 1 + rng.Intn(maxEntries)
 	entries := make(gpbft.PowerEntries, 0, size)
 	for i := range size {
 		var entry gpbft.PowerEntry
@@ -0,0 +1,236 @@
+package certstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/filecoin-project/go-f3/certs"
+	"github.com/filecoin-project/go-f3/gpbft"
+)
+
+// The maximum number of certificates to validate at once during verification.
+const verifyBatchSize = 256
+
+var (
+	// ErrInvalidCertificate is reported when a stored certificate fails validation.
+	ErrInvalidCertificate = errors.New("invalid finality certificate")
+	// ErrPowerTableMismatch is reported when a stored power table does not match the one
+	// computed from the certificates before it.
+	ErrPowerTableMismatch = errors.New("stored power table does not match certificates")
+)
+
+// VerifyReport describes the outcome of verifying a certificate store.
+type VerifyReport struct {
+	// The first and latest instances that were verified, inclusive.
+	FirstInstance, LatestInstance uint64
+	// The number of certificates found valid.
+	Verified uint64
+	// The problems found, in the order of instance.
+	Problems []VerifyProblem
+}
+
+// VerifyProblem describes a range of instances that failed verification.
+type VerifyProblem struct {
+	// The first and last affected instances, inclusive.
+	From, To uint64
+	// The reason for the failure, which wraps either ErrCertNotFound for gaps,
+	// ErrInvalidCertificate for certificates that do not validate, or ErrPowerTableMismatch for
+	// corrupt stored power tables.
+	Err error
+}
+
+// OK checks whether verification found no problems.
+func (r *VerifyReport) OK() bool {
+	return len(r.Problems) == 0
+}
+
+// Verify replays every stored certificate through certs.ValidateFinalityCertificates, starting
+// from the stored power tables, and reports any gaps, invalid certificates or corrupt power
+// tables.
+//
+// Certificates cannot be validated past a problem, so verification resumes at the next stored
+// power table. The problem then covers all the instances up to it. An error is only returned if
+// verification could not complete, e.g. because the context was cancelled.
+func (cs *Store) Verify(ctx context.Context, verifier gpbft.Verifier, nn gpbft.NetworkName) (*VerifyReport, error) {
+	report := &VerifyReport{FirstInstance: max(cs.firstInstance.Load(), cs.prunedBefore.Load())}
+	latest := cs.Latest()
+	if latest == nil {
+		return report, nil
+	}
+	report.LatestInstance = latest.GPBFTInstance
+
+	// The instance of the next stored power table after the given instance, beyond which
+	// verification can always resume.
+	nextCheckpoint := func(instance uint64) uint64 {
+		return min(instance-instance%cs.powerTableFrequency+cs.powerTableFrequency, report.LatestInstance+1)
+	}
+
+	var (
+		powerTable gpbft.PowerEntries
+		base       *gpbft.TipSet
+	)
+	for next := report.FirstInstance; next <= report.LatestInstance; {
+		if powerTable == nil {
+			var err error
+			if powerTable, err = cs.readPowerTable(ctx, next); err != nil {
+				resume := nextCheckpoint(next)
+				err = fmt.Errorf("%w: %w", ErrPowerTableMismatch, err)
+				report.Problems = append(report.Problems, VerifyProblem{From: next, To: resume - 1, Err: err})
+				next = resume
+				continue
+			}
+		}
+
+		end := min(next+verifyBatchSize, nextCheckpoint(next)) - 1
+		var (
+			batch   []*certs.FinalityCertificate
+			loadErr error
+		)
+		for cert, err := range cs.Iterate(ctx, next, end) {
+			if err != nil {
+				loadErr = err
+				break
+			}
+			batch = append(batch, cert)
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		validNext, _, nextPowerTable, err := certs.ValidateFinalityCertificates(verifier, nn, powerTable, next, base, batch...)
+		if err != nil {
+			err = fmt.Errorf("%w: %w", ErrInvalidCertificate, err)
+		} else if errors.Is(loadErr, ErrCertNotFound) {
+			err = loadErr
+		} else if loadErr != nil {
+			err = fmt.Errorf("%w: %w", ErrInvalidCertificate, loadErr)
+		}
+		report.Verified += validNext - next
+		if validNext > next {
+			powerTable = nextPowerTable
+			base = batch[validNext-next-1].ECChain.Head()
+		}
+		if err != nil {
+			resume := nextCheckpoint(validNext)
+			report.Problems = append(report.Problems, VerifyProblem{From: validNext, To: resume - 1, Err: err})
+			next, powerTable, base = resume, nil, nil
+			continue
+		}
+		next = validNext
+
+		// Check the stored power table, if any, against the one computed from the certificates.
+		if next%cs.powerTableFrequency == 0 {
+			if err := cs.verifyPowerTable(ctx, next, powerTable); err != nil {
+				report.Problems = append(report.Problems, VerifyProblem{From: next, To: next, Err: err})
+			}
+		}
+	}
+	return report, nil
+}
+
+func (cs *Store) verifyPowerTable(ctx context.Context, instance uint64, expected gpbft.PowerEntries) error {
+	stored, err := cs.readPowerTable(ctx, instance)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrPowerTableMismatch, err)
+	}
+	storedCid, err := certs.MakePowerTableCID(stored)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrPowerTableMismatch, err)
+	}
+	if err := checkPowerTable(expected, storedCid); err != nil {
+		return fmt.Errorf("%w at instance %d: %w", ErrPowerTableMismatch, instance, err)
+	}
+	return nil
+}
+
+// Repair overwrites the stored certificates starting at the instance of the first given
+// certificate with the given ones, along with the power tables stored at the instances they
+// cover. It is meant to fix the problems reported by Verify using certificates fetched from
+// elsewhere, and cannot extend the store beyond its latest certificate.
+//
+// The given power table must be the one to validate the first certificate with. It is trusted
+// only if it matches the power table committed to by the stored certificate before it, or the
+// stored power table if the first certificate is the first one in the store. The certificates
+// are then validated in full before anything is written.
+func (cs *Store) Repair(ctx context.Context, verifier gpbft.Verifier, nn gpbft.NetworkName, powerTable gpbft.PowerEntries, certificates ...*certs.FinalityCertificate) error {
+	if len(certificates) == 0 {
+		return nil
+	}
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	from := certificates[0].GPBFTInstance
+	if latest := cs.latestCertificate; latest == nil || latest.GPBFTInstance < certificates[len(certificates)-1].GPBFTInstance {
+		return errors.New("cannot repair certificates beyond the latest certificate")
+	}
+	var base *gpbft.TipSet
+	switch first := max(cs.firstInstance.Load(), cs.prunedBefore.Load()); {
+	case from < first:
+		return fmt.Errorf("cannot repair certificates before the first instance %d", first)
+	case from == first:
+		stored, err := cs.readPowerTable(ctx, from)
+		if err != nil {
+			return fmt.Errorf("loading trusted power table: %w", err)
+		}
+		storedCid, err := certs.MakePowerTableCID(stored)
+		if err != nil {
+			return err
+		}
+		if err := checkPowerTable(powerTable, storedCid); err != nil {
+			return fmt.Errorf("power table at instance %d: %w", from, err)
+		}
+	default:
+		prev, err := cs.Get(ctx, from-1)
+		if err != nil {
+			return fmt.Errorf("loading the certificate before the repaired range: %w", err)
+		}
+		if err := checkPowerTable(powerTable, prev.SupplementalData.PowerTable); err != nil {
+			return fmt.Errorf("power table at instance %d: %w", from, err)
+		}
+		base = prev.ECChain.Head()
+	}
+
+	powerTables := make([]gpbft.PowerEntries, len(certificates))
+	for i, cert := range certificates {
+		var err error
+		_, _, powerTables[i], err = certs.ValidateFinalityCertificates(verifier, nn, powerTable, from+uint64(i), base, cert)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidCertificate, err)
+		}
+		powerTable, base = powerTables[i], cert.ECChain.Head()
+	}
+
+	for i, cert := range certificates {
+		if b, err := cs.backend.Get(ctx, cert.GPBFTInstance); err == nil {
+			var old certs.FinalityCertificate
+			if err := old.UnmarshalCBOR(bytes.NewReader(b)); err == nil {
+				if err := cs.deleteIndex(ctx, &old); err != nil {
+					return err
+				}
+			}
+		}
+		var buf bytes.Buffer
+		if err := cert.MarshalCBOR(&buf); err != nil {
+			return fmt.Errorf("marshalling cert instance %d: %w", cert.GPBFTInstance, err)
+		}
+		if err := cs.backend.Put(ctx, cert.GPBFTInstance, buf.Bytes()); err != nil {
+			return fmt.Errorf("putting the cert: %w", err)
+		}
+		if err := cs.putIndex(ctx, cert); err != nil {
+			return err
+		}
+		if (cert.GPBFTInstance+1)%cs.powerTableFrequency == 0 {
+			if err := cs.putPowerTable(ctx, cert.GPBFTInstance+1, powerTables[i]); err != nil {
+				return err
+			}
+		}
+		if cert.GPBFTInstance == cs.latestCertificate.GPBFTInstance {
+			cs.latestCertificate = cert
+			cs.latestPowerTable = powerTables[i]
+		}
+	}
+	log.Infow("repaired finality certificates", "from", from, "to", from+uint64(len(certificates))-1)
+	return nil
+}
@@ -1,16 +1,21 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"strconv"
 	"time"
 
+	"github.com/filecoin-project/go-f3/blssig"
 	"github.com/filecoin-project/go-f3/certexchange"
 	"github.com/filecoin-project/go-f3/certs"
+	"github.com/filecoin-project/go-f3/certstore"
 	"github.com/filecoin-project/go-f3/gpbft"
 	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore/namespace"
+	leveldb "github.com/ipfs/go-ds-leveldb"
 	"github.com/libp2p/go-libp2p"
 	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/urfave/cli/v2"
@@ -91,9 +96,160 @@ var (
 					return nil
 				},
 			},
+			{
+				Name:      "list",
+				Usage:     "Lists the certificates from the given instance to the given instance inclusive in the local certificate store, in decreasing order if the former is larger",
+				ArgsUsage: "<from> <to>",
+				Flags: []cli.Flag{
+					datastoreFlag,
+					segmentsFlag,
+				},
+				Action: func(cctx *cli.Context) error {
+					if cctx.Args().Len() != 2 {
+						return errors.New("expected from and to instances as arguments")
+					}
+					from, err := strconv.ParseUint(cctx.Args().Get(0), 10, 64)
+					if err != nil {
+						return err
+					}
+					to, err := strconv.ParseUint(cctx.Args().Get(1), 10, 64)
+					if err != nil {
+						return err
+					}
+					cs, closer, err := openLocalCertstore(cctx)
+					if err != nil {
+						return err
+					}
+					defer closer()
+
+					// Print one certificate per line as they are read, so that arbitrarily
+					// large ranges can be listed.
+					encoder := json.NewEncoder(cctx.App.Writer)
+					for cert, err := range cs.Iterate(cctx.Context, from, to) {
+						if err != nil {
+							return err
+						}
+						if err := encoder.Encode(cert); err != nil {
+							return err
+						}
+					}
+					return nil
+				},
+			},
+			{
+				Name:  "verify",
+				Usage: "Verifies the integrity of every certificate in the local certificate store",
+				Flags: []cli.Flag{
+					datastoreFlag,
+					segmentsFlag,
+				},
+				Action: func(cctx *cli.Context) error {
+					m, err := getManifest(cctx)
+					if err != nil {
+						return err
+					}
+					cs, closer, err := openLocalCertstore(cctx)
+					if err != nil {
+						return err
+					}
+					defer closer()
+
+					report, err := cs.Verify(cctx.Context, blssig.VerifierWithKeyOnG1(), m.NetworkName)
+					if err != nil {
+						return err
+					}
+					printVerifyReport(cctx, report)
+					if !report.OK() {
+						return errors.New("certificate store is inconsistent")
+					}
+					return nil
+				},
+			},
+			{
+				Name:  "repair",
+				Usage: "Verifies the local certificate store and re-fetches the broken certificates from a peer",
+				Flags: []cli.Flag{
+					datastoreFlag,
+					segmentsFlag,
+					fromAddrFlag,
+					timeoutFlag,
+				},
+				Action: func(cctx *cli.Context) error {
+					m, err := getManifest(cctx)
+					if err != nil {
+						return err
+					}
+					cs, closer, err := openLocalCertstore(cctx)
+					if err != nil {
+						return err
+					}
+					defer closer()
+
+					verifier := blssig.VerifierWithKeyOnG1()
+					report, err := cs.Verify(cctx.Context, verifier, m.NetworkName)
+					if err != nil {
+						return err
+					}
+					printVerifyReport(cctx, report)
+					if report.OK() {
+						return nil
+					}
+
+					host, err := libp2p.New()
+					if err != nil {
+						return err
+					}
+					defer func() { _ = host.Close() }()
+
+					if err := host.Connect(cctx.Context, *certFrom); err != nil {
+						return err
+					}
+					client := certexchange.Client{
+						Host:           host,
+						NetworkName:    m.NetworkName,
+						RequestTimeout: cctx.Duration(timeoutFlag.Name),
+					}
+
+					for _, problem := range report.Problems {
+						from, to := problem.From, problem.To
+						// Stored power tables are rewritten by repairing the certificate before them.
+						if errors.Is(problem.Err, certstore.ErrPowerTableMismatch) && from > report.FirstInstance {
+							from--
+						}
+						powerTable, certificates, err := fetchCertificates(cctx.Context, &client, certFrom.ID, from, to)
+						if err == nil {
+							err = cs.Repair(cctx.Context, verifier, m.NetworkName, powerTable, certificates...)
+						}
+						if err != nil {
+							_, _ = fmt.Fprintf(cctx.App.Writer, "failed to repair instances %d to %d: %v\n", from, to, err)
+							continue
+						}
+						_, _ = fmt.Fprintf(cctx.App.Writer, "repaired instances %d to %d\n", from, to)
+					}
+
+					if report, err = cs.Verify(cctx.Context, verifier, m.NetworkName); err != nil {
+						return err
+					}
+					printVerifyReport(cctx, report)
+					if !report.OK() {
+						return errors.New("certificate store is still inconsistent")
+					}
+					return nil
+				},
+			},
 		},
 	}
 
+	datastoreFlag = &cli.PathFlag{
+		Name:     "datastore",
+		Aliases:  []string{"ds"},
+		Usage:    "The path to the leveldb datastore of the F3 node",
+		Required: true,
+	}
+	segmentsFlag = &cli.PathFlag{
+		Name:  "segments",
+		Usage: "The path to the certificate segments of the network, for F3 nodes that store certificates in segment files",
+	}
 	limitFlag = &cli.Uint64Flag{
 		Name:  "limit",
 		Usage: "Maximum number of certificates to list from the peer",
@@ -127,3 +283,83 @@ var (
 		Value: 30 * time.Second,
 	}
 )
+
+// openLocalCertstore opens the certificate store of the network in the manifest from the leveldb
+// datastore at the path given by the datastore flag, with the certificates in the segments at the
+// path given by the segments flag, if any.
+func openLocalCertstore(cctx *cli.Context) (*certstore.Store, func(), error) {
+	m, err := getManifest(cctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("loading manifest: %w", err)
+	}
+	ds, err := leveldb.NewDatastore(cctx.Path(datastoreFlag.Name), nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("opening datastore: %w", err)
+	}
+	var (
+		opts     []certstore.Option
+		segments *certstore.SegmentedBackend
+	)
+	if path := cctx.Path(segmentsFlag.Name); path != "" {
+		if segments, err = certstore.OpenSegments(path); err != nil {
+			_ = ds.Close()
+			return nil, nil, fmt.Errorf("opening certificate segments: %w", err)
+		}
+		opts = append(opts, certstore.WithBackend(segments))
+	}
+	closer := func() {
+		if segments != nil {
+			_ = segments.Close()
+		}
+		_ = ds.Close()
+	}
+	cs, err := certstore.OpenStore(cctx.Context, namespace.Wrap(ds, m.DatastorePrefix()), opts...)
+	if err != nil {
+		closer()
+		return nil, nil, fmt.Errorf("opening certificate store: %w", err)
+	}
+	return cs, func() {
+		_ = cs.Close()
+		closer()
+	}, nil
+}
+
+func printVerifyReport(cctx *cli.Context, report *certstore.VerifyReport) {
+	_, _ = fmt.Fprintf(cctx.App.Writer, "verified %d certificates from instance %d to %d\n", report.Verified, report.FirstInstance, report.LatestInstance)
+	for _, problem := range report.Problems {
+		_, _ = fmt.Fprintf(cctx.App.Writer, "instances %d to %d: %v\n", problem.From, problem.To, problem.Err)
+	}
+}
+
+// fetchCertificates fetches the certificates from the given instance to the given instance
+// inclusive from the given peer, along with the power table to validate the first one with.
+func fetchCertificates(ctx context.Context, client *certexchange.Client, p peer.ID, from, to uint64) (gpbft.PowerEntries, []*certs.FinalityCertificate, error) {
+	var (
+		powerTable   gpbft.PowerEntries
+		certificates []*certs.FinalityCertificate
+	)
+	for next := from; next <= to; next = from + uint64(len(certificates)) {
+		rh, ch, err := client.Request(ctx, p, &certexchange.Request{
+			FirstInstance:     next,
+			Limit:             to - next + 1,
+			IncludePowerTable: powerTable == nil,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		if powerTable == nil {
+			if len(rh.PowerTable) == 0 {
+				return nil, nil, fmt.Errorf("peer %s has no power table for instance %d", p, next)
+			}
+			powerTable = rh.PowerTable
+		}
+		received := len(certificates)
+		for cert := range ch {
+			certificates = append(certificates, cert)
+		}
+		if len(certificates) == received {
+			return nil, nil, fmt.Errorf("peer %s has no certificates from instance %d", p, next)
+		}
+	}
+	return powerTable, certificates, nil
+}
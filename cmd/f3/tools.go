@@ -2,11 +2,14 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 
+	"github.com/filecoin-project/go-f3/blssig"
 	"github.com/filecoin-project/go-f3/certs"
 	"github.com/filecoin-project/go-f3/gpbft"
+	"github.com/filecoin-project/go-f3/recording"
 	"github.com/urfave/cli/v2"
 )
 
@@ -15,6 +18,7 @@ var toolsCmd = cli.Command{
 	Usage: "various tools for f3",
 	Subcommands: []*cli.Command{
 		&ptCidCmd,
+		&replayCmd,
 	},
 }
 
@@ -37,3 +41,50 @@ var ptCidCmd = cli.Command{
 		return nil
 	},
 }
+
+var replayCmd = cli.Command{
+	Name:  "replay",
+	Usage: "replay a recording of gpbft inputs and report where the decisions diverge from it",
+	Flags: []cli.Flag{
+		&cli.PathFlag{
+			Name:     "recording",
+			Usage:    "The path to the recording directory of the F3 node, i.e. <disk path>/recording/<network>",
+			Required: true,
+		},
+	},
+	Action: func(c *cli.Context) error {
+		m, err := getManifest(c)
+		if err != nil {
+			return err
+		}
+		entries, err := recording.Read(c.Path("recording"))
+		if err != nil {
+			return err
+		}
+		replayer := recording.Replayer{
+			NetworkName: m.NetworkName,
+			Verifier:    blssig.VerifierWithKeyOnG1(),
+			Options:     m.GpbftOptions(),
+		}
+		report, err := replayer.Replay(c.Context, entries)
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(c.App.Writer, "replayed %d entries, deciding %d instances\n", report.Replayed, len(report.Decided))
+		for _, d := range report.Divergences {
+			_, _ = fmt.Fprintf(c.App.Writer, "instance %d at %s diverged while replaying instance %d round %d phase %s: recorded %s, replayed %s\n",
+				d.Instance, d.At, d.Progress.ID, d.Progress.Round, d.Progress.Phase, decisionValue(d.Recorded), decisionValue(d.Replayed))
+		}
+		if !report.OK() {
+			return errors.New("replay diverged from the recording")
+		}
+		return nil
+	},
+}
+
+func decisionValue(decision *gpbft.Justification) string {
+	if decision == nil {
+		return "no decision"
+	}
+	return decision.Vote.Value.String()
+}
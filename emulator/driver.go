@@ -56,6 +56,22 @@ func (d *Driver) StartInstance(id uint64) error {
 	return nil
 }
 
+// ExportState exports the state of the instance in progress by the emulated honest
+// gpbft.Participant. See gpbft.Participant.ExportState.
+func (d *Driver) ExportState() ([]byte, error) {
+	return d.subject.ExportState()
+}
+
+// ResumeInstance signals the start of instance to the emulated honest gpbft.Participant, and
+// resumes it from the given state instead of beginning it afresh. See
+// gpbft.Participant.ImportState.
+func (d *Driver) ResumeInstance(id uint64, state []byte) error {
+	if err := d.subject.StartInstanceAt(id, d.host.Time()); err != nil {
+		return err
+	}
+	return d.subject.ImportState(context.Background(), state)
+}
+
 // AddInstance adds an instance to the list of instances known by the driver.
 func (d *Driver) AddInstance(instance *Instance) {
 	d.require.NoError(d.host.addInstance(instance))
@@ -25,6 +25,12 @@ func (d *Driver) RequireStartInstance(id uint64) {
 	d.require.NoError(d.StartInstance(id))
 }
 
+// RequireResumeInstance asserts that instance with the given ID is resumed from the given state.
+// See ResumeInstance.
+func (d *Driver) RequireResumeInstance(id uint64, state []byte) {
+	d.require.NoError(d.ResumeInstance(id, state))
+}
+
 func (d *Driver) RequireDeliverAlarm() {
 	delivered, err := d.DeliverAlarm()
 	d.require.NoError(err)
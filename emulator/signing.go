@@ -58,6 +58,18 @@ func (s adhocSigning) Verify(sender gpbft.PubKey, msg, got []byte) error {
 	}
 }
 
+func (s adhocSigning) VerifyBatch(senders []gpbft.PubKey, msgs [][]byte, sigs [][]byte) error {
+	if len(senders) != len(sigs) || len(msgs) != len(sigs) {
+		return errors.New("public keys, messages and signatures length mismatch")
+	}
+	for i := range sigs {
+		if err := s.Verify(senders[i], msgs[i], sigs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 type aggregate struct {
 	keys    []gpbft.PubKey
 	signing adhocSigning
@@ -120,6 +132,10 @@ func (p erroneousSigning) Verify(gpbft.PubKey, []byte, []byte) error {
 	return errors.New("err Verify")
 }
 
+func (p erroneousSigning) VerifyBatch([]gpbft.PubKey, [][]byte, [][]byte) error {
+	return errors.New("err VerifyBatch")
+}
+
 func (p erroneousAggregate) VerifyAggregate([]int, []byte, []byte) error {
 	return errors.New("err VerifyAggregate")
 }
@@ -143,6 +159,7 @@ type panicSigning struct{}
 type panicAggregate struct{}
 
 func (p panicSigning) Verify(gpbft.PubKey, []byte, []byte) error                         { panic("π") }
+func (p panicSigning) VerifyBatch([]gpbft.PubKey, [][]byte, [][]byte) error              { panic("π") }
 func (p panicSigning) VerifyAggregate([]byte, []byte, []gpbft.PubKey) error              { panic("π") }
 func (p panicSigning) Sign(context.Context, gpbft.PubKey, []byte) ([]byte, error)        { panic("π") }
 func (p panicSigning) MarshalPayloadForSigning(gpbft.NetworkName, *gpbft.Payload) []byte { panic("π") }
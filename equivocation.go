@@ -17,14 +17,22 @@ type equivocationFilter struct {
 	// seenMessages map unique message slot to its signature
 	seenMessages  map[equivocationKey]equivMessage
 	activeSenders map[gpbft.ActorID]equivSenders
+	// localActors are the actors hosted by the local node, whose messages are tracked as they
+	// are received even before the node broadcasts any on their behalf.
+	localActors map[gpbft.ActorID]struct{}
 }
 
-func newEquivocationFilter(localPID peer.ID) equivocationFilter {
-	return equivocationFilter{
+func newEquivocationFilter(localPID peer.ID, localActors ...gpbft.ActorID) equivocationFilter {
+	ef := equivocationFilter{
 		localPID:      localPID,
 		seenMessages:  make(map[equivocationKey]equivMessage),
 		activeSenders: make(map[gpbft.ActorID]equivSenders),
+		localActors:   make(map[gpbft.ActorID]struct{}, len(localActors)),
 	}
+	for _, id := range localActors {
+		ef.localActors[id] = struct{}{}
+	}
+	return ef
 }
 
 type equivocationKey struct {
@@ -74,9 +82,7 @@ func (ef *equivocationFilter) ProcessBroadcast(m *gpbft.GMessage) bool {
 	}
 	// moved onto new instance
 	if m.Vote.Instance > ef.currentInstance {
-		ef.currentInstance = m.Vote.Instance
-		ef.seenMessages = make(map[equivocationKey]equivMessage)
-		ef.activeSenders = make(map[gpbft.ActorID]equivSenders)
+		ef.moveToInstance(m.Vote.Instance)
 	}
 
 	key := ef.formKey(m)
@@ -98,6 +104,11 @@ func (ef *equivocationFilter) ProcessBroadcast(m *gpbft.GMessage) bool {
 	}
 	// save ourselves as one of the senders
 	senders := ef.activeSenders[m.Sender]
+	if equivocationDetected {
+		// The other message may have been received before we were tracking the sender, e.g.
+		// for a local actor.
+		senders.addSender(msgInfo.origin, true)
+	}
 	senders.addSender(ef.localPID, equivocationDetected)
 	ef.activeSenders[m.Sender] = senders
 
@@ -114,16 +125,27 @@ func (ef *equivocationFilter) ProcessBroadcast(m *gpbft.GMessage) bool {
 	return senders.origins[0] == ef.localPID
 }
 
+func (ef *equivocationFilter) moveToInstance(instance uint64) {
+	ef.currentInstance = instance
+	ef.seenMessages = make(map[equivocationKey]equivMessage)
+	ef.activeSenders = make(map[gpbft.ActorID]equivSenders)
+}
+
 func (ef *equivocationFilter) ProcessReceive(peerID peer.ID, m *gpbft.GMessage) {
 	ef.lk.Lock()
 	defer ef.lk.Unlock()
 
+	_, local := ef.localActors[m.Sender]
+	if local && m.Vote.Instance > ef.currentInstance {
+		// A local actor may be seen sending messages for a new instance before we do.
+		ef.moveToInstance(m.Vote.Instance)
+	}
 	if m.Vote.Instance != ef.currentInstance {
 		// the instance does not match
 		return
 	}
 	senders, ok := ef.activeSenders[m.Sender]
-	if !ok {
+	if !ok && !local {
 		// we do not track the sender because we didn't send any messages from that ID
 		// otherwise we would have to track all messages
 		return
@@ -1,6 +1,7 @@
 package f3
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/filecoin-project/go-f3/gpbft"
@@ -159,3 +160,43 @@ func TestEquivocationFilter_PeerIDBasedEquivocationHandling(t *testing.T) {
 	// Local broadcast after lower PeerID equivocation
 	require.False(t, ef.ProcessBroadcast(msg1), "Local message should not be processed after lower PeerID equivocation")
 }
+
+func TestEquivocationFilter_LocalActors(t *testing.T) {
+	ef := newEquivocationFilter(localGoodPID, 1, 2)
+
+	// Messages of distinct local actors at the same round and phase are not equivocations.
+	for _, sender := range []gpbft.ActorID{1, 2} {
+		msg := &gpbft.GMessage{
+			Sender:    sender,
+			Vote:      gpbft.Payload{Instance: 1, Round: 1, Phase: gpbft.Phase(1)},
+			Signature: []byte(fmt.Sprint("signature", sender)),
+		}
+		require.True(t, ef.ProcessBroadcast(msg), "message of local actor %d should be processed", sender)
+	}
+
+	// A remote node with a lower PeerID sends a message for a local actor at the next instance,
+	// before any is broadcast locally.
+	ef.ProcessReceive(remotePID0, &gpbft.GMessage{
+		Sender:    gpbft.ActorID(2),
+		Vote:      gpbft.Payload{Instance: 2, Round: 0, Phase: gpbft.Phase(1)},
+		Signature: []byte("remote"),
+	})
+	require.False(t, ef.ProcessBroadcast(&gpbft.GMessage{
+		Sender:    gpbft.ActorID(2),
+		Vote:      gpbft.Payload{Instance: 2, Round: 0, Phase: gpbft.Phase(1)},
+		Signature: []byte("local"),
+	}), "local node should back off for the equivocating actor")
+	require.True(t, ef.ProcessBroadcast(&gpbft.GMessage{
+		Sender:    gpbft.ActorID(1),
+		Vote:      gpbft.Payload{Instance: 2, Round: 0, Phase: gpbft.Phase(1)},
+		Signature: []byte("local"),
+	}), "other local actors should be unaffected")
+
+	// Messages of remote actors are not tracked.
+	ef.ProcessReceive(remotePID5, &gpbft.GMessage{
+		Sender:    gpbft.ActorID(3),
+		Vote:      gpbft.Payload{Instance: 2, Round: 0, Phase: gpbft.Phase(1)},
+		Signature: []byte("remote"),
+	})
+	require.NotContains(t, ef.activeSenders, gpbft.ActorID(3))
+}
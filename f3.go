@@ -21,10 +21,13 @@ import (
 	"github.com/filecoin-project/go-f3/internal/powerstore"
 	"github.com/filecoin-project/go-f3/internal/writeaheadlog"
 	"github.com/filecoin-project/go-f3/manifest"
+	"github.com/filecoin-project/go-f3/recording"
 
 	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
 	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
 
 	"go.uber.org/multierr"
 )
@@ -34,14 +37,17 @@ import (
 var ErrF3NotRunning = errors.New("f3 is not running")
 
 type f3State struct {
-	cs       *certstore.Store
-	runner   *gpbftRunner
-	ps       *powerstore.Store
-	certsub  *certexpoll.Subscriber
-	certserv *certexchange.Server
+	cs           *certstore.Store
+	certSegments *certstore.SegmentedBackend
+	runner       *gpbftRunner
+	ps           *powerstore.Store
+	certsub      *certexpoll.Subscriber
+	certserv     *certexchange.Server
 }
 
 type F3 struct {
+	*options
+
 	verifier gpbft.Verifier
 	mfst     manifest.Manifest
 	diskPath string
@@ -63,12 +69,17 @@ type F3 struct {
 // New creates and setups f3 with libp2p
 // The context is used for initialization not runtime.
 func New(_ctx context.Context, manifest manifest.Manifest, ds datastore.Datastore, h host.Host,
-	ps *pubsub.PubSub, verif gpbft.Verifier, ecBackend ec.Backend, diskPath string) (*F3, error) {
+	ps *pubsub.PubSub, verif gpbft.Verifier, ecBackend ec.Backend, diskPath string, o ...Option) (*F3, error) {
+	opts, err := newOptions(o...)
+	if err != nil {
+		return nil, err
+	}
 	runningCtx, cancel := context.WithCancel(context.WithoutCancel(_ctx))
 
 	// concurrency is limited to half of the number of CPUs, and cache size is set to 256 which is more than 2x max ECChain size
 	ecBackend = ec.NewPowerCachingECWrapper(ecBackend, max(runtime.NumCPU()/2, 8), 256)
 	return &F3{
+		options:          opts,
 		verifier:         verif,
 		mfst:             manifest,
 		diskPath:         diskPath,
@@ -86,6 +97,7 @@ func New(_ctx context.Context, manifest manifest.Manifest, ds datastore.Datastor
 // MessagesToSign returns a channel of outbound messages that need to be signed by the client(s).
 // - The same channel is shared between all callers and will never be closed.
 // - GPBFT will block if this channel is not read from.
+// - Nothing is sent to the channel if F3 signs messages itself, see WithLocalActors.
 func (m *F3) MessagesToSign() <-chan *gpbft.MessageBuilder {
 	return m.outboundMessages
 }
@@ -128,6 +140,24 @@ func (m *F3) GetCert(ctx context.Context, instance uint64) (*certs.FinalityCerti
 	return cs.Get(ctx, instance)
 }
 
+// GetCertByEpoch returns the finality certificate that finalized the given epoch.
+func (m *F3) GetCertByEpoch(ctx context.Context, epoch int64) (*certs.FinalityCertificate, error) {
+	cs, err := m.GetCertStore()
+	if err != nil {
+		return nil, err
+	}
+	return cs.GetByEpoch(ctx, epoch)
+}
+
+// GetCertByTipSet returns the finality certificate that finalized the tipset with the given key.
+func (m *F3) GetCertByTipSet(ctx context.Context, tsk gpbft.TipSetKey) (*certs.FinalityCertificate, error) {
+	cs, err := m.GetCertStore()
+	if err != nil {
+		return nil, err
+	}
+	return cs.GetByTipSet(ctx, tsk)
+}
+
 func (m *F3) GetCertStore() (*certstore.Store, error) {
 	if state := m.state.Load(); state != nil && state.cs != nil {
 		return state.cs, nil
@@ -135,6 +165,15 @@ func (m *F3) GetCertStore() (*certstore.Store, error) {
 	return nil, ErrF3NotRunning
 }
 
+// GetCertExchangePeers returns a snapshot of the certificate exchange peers F3 polls for
+// finality certificates, from best to worst, for debugging purposes.
+func (m *F3) GetCertExchangePeers() ([]certexpoll.PeerRecord, error) {
+	if state := m.state.Load(); state != nil && state.certsub != nil {
+		return state.certsub.PeerRecords(), nil
+	}
+	return nil, ErrF3NotRunning
+}
+
 // GetPowerTableByInstance returns the power table (committee) used to validate the specified instance.
 func (m *F3) GetPowerTableByInstance(ctx context.Context, instance uint64) (gpbft.PowerEntries, error) {
 	cs, err := m.GetCertStore()
@@ -237,6 +276,14 @@ func (s *f3State) stop(ctx context.Context) (err error) {
 	if serr := s.certserv.Stop(ctx); serr != nil {
 		err = multierr.Append(err, fmt.Errorf("failed to stop certificate exchange server: %w", serr))
 	}
+	if serr := s.cs.Close(); serr != nil {
+		err = multierr.Append(err, fmt.Errorf("failed to close certstore: %w", serr))
+	}
+	if s.certSegments != nil {
+		if serr := s.certSegments.Close(); serr != nil {
+			err = multierr.Append(err, fmt.Errorf("failed to close certificate segments: %w", serr))
+		}
+	}
 	return err
 }
 
@@ -283,9 +330,24 @@ func (m *F3) startInternal(ctx context.Context) error {
 		NetworkName:    m.mfst.NetworkName,
 		RequestTimeout: m.mfst.CertificateExchange.ClientRequestTimeout,
 	}
+	cleanName := strings.ReplaceAll(string(m.mfst.NetworkName), "/", "-")
+	cleanName = strings.ReplaceAll(cleanName, ".", "")
+	cleanName = strings.ReplaceAll(cleanName, "\u0000", "")
+
+	var certBackend certstore.Backend
+	if m.certificateSegments {
+		state.certSegments, err = certstore.OpenSegments(filepath.Join(m.diskPath, "certs", cleanName))
+		if err != nil {
+			return fmt.Errorf("failed to open certificate segments: %w", err)
+		}
+		certBackend = state.certSegments
+	}
 	cds := measurements.NewMeteredDatastore(meter, "f3_certstore_datastore_", m.ds)
-	state.cs, err = openCertstore(ctx, m.ec, cds, m.mfst, certClient)
+	state.cs, err = openCertstore(ctx, m.ec, cds, m.mfst, certClient, m.verifier, m.options, certBackend)
 	if err != nil {
+		if state.certSegments != nil {
+			_ = state.certSegments.Close()
+		}
 		return fmt.Errorf("failed to open certstore: %w", err)
 	}
 
@@ -300,6 +362,12 @@ func (m *F3) startInternal(ctx context.Context) error {
 		RequestTimeout: m.mfst.CertificateExchange.ServerRequestTimeout,
 		Host:           m.host,
 		Store:          state.cs,
+
+		MaxConcurrentRequests:    m.options.serverLimits.MaxConcurrentRequests,
+		PeerRequestRate:          m.options.serverLimits.PeerRequestRate,
+		PeerRequestBurst:         m.options.serverLimits.PeerRequestBurst,
+		MaxCertificatesPerMinute: m.options.serverLimits.MaxCertificatesPerMinute,
+		MaxConcurrentSnapshots:   m.options.serverLimits.MaxConcurrentSnapshots,
 	}
 
 	state.certsub = &certexpoll.Subscriber{
@@ -314,19 +382,44 @@ func (m *F3) startInternal(ctx context.Context) error {
 		MaximumPollInterval: m.mfst.CertificateExchange.MaximumPollInterval,
 		MinimumPollInterval: m.mfst.CertificateExchange.MinimumPollInterval,
 	}
-	cleanName := strings.ReplaceAll(string(m.mfst.NetworkName), "/", "-")
-	cleanName = strings.ReplaceAll(cleanName, ".", "")
-	cleanName = strings.ReplaceAll(cleanName, "\u0000", "")
-
+	if state.certsub.Discovery, err = m.peerDiscovery(); err != nil {
+		return err
+	}
+	if m.announcements {
+		state.certsub.PubSub = m.pubsub
+	}
+	if m.backfill {
+		if m.mfst.InitialPowerTable.Defined() {
+			state.certsub.Backfill = true
+			state.certsub.BackfillTo = m.mfst.InitialInstance
+			state.certsub.BackfillPowerTable = m.mfst.InitialPowerTable
+		} else {
+			log.Warn("not backfilling finality certificates, as the manifest does not specify the initial power table to trust")
+		}
+	}
+	state.certsub.ScheduleHint = m.certificateScheduleHint
+	if m.persistPeers {
+		state.certsub.Datastore = namespace.Wrap(m.ds, m.mfst.DatastorePrefix().ChildString("certexchange"))
+	}
 	walPath := filepath.Join(m.diskPath, "wal", cleanName)
 	wal, err := writeaheadlog.Open[walEntry](walPath)
 	if err != nil {
 		return fmt.Errorf("opening WAL: %w", err)
 	}
 
+	var recorder *recording.Recorder
+	if m.recording {
+		recorder, err = recording.Open(filepath.Join(m.diskPath, "recording", cleanName))
+		if err != nil {
+			return err
+		}
+	}
+
 	state.runner, err = newRunner(
 		ctx, state.cs, state.ps, m.pubsub, m.verifier,
-		m.outboundMessages, m.mfst, wal, m.host.ID(),
+		m.outboundMessages, m.localActors, m.mfst, wal, recorder,
+		namespace.Wrap(m.ds, m.mfst.DatastorePrefix().ChildString("gpbft")), m.host.ID(),
+		m.gpbftOptions()...,
 	)
 	if err != nil {
 		return err
@@ -363,3 +456,58 @@ func (m *F3) Progress() (instant gpbft.InstanceProgress) {
 	}
 	return
 }
+
+// certificateScheduleHint estimates when the finality certificate following the given one will be
+// available, so that the certificate exchange polls for it just after it is decided. The local
+// runner knows best when the next instance starts. Until it runs, the estimate is derived from the
+// timestamp of the tipset the given certificate finalized, ignoring any base decision backoff.
+func (m *F3) certificateScheduleHint(ctx context.Context, cert *certs.FinalityCertificate) (time.Time, bool) {
+	if st := m.state.Load(); st != nil && st.runner != nil {
+		return st.runner.computeNextInstanceStart(cert).Add(expectedDecisionDuration(m.mfst)), true
+	}
+	head, err := m.ec.GetHead(ctx)
+	if err != nil {
+		log.Debugw("failed to get EC head to estimate the next certificate time", "error", err)
+		return time.Time{}, false
+	}
+	ecDelay := time.Duration(m.mfst.EC.DelayMultiplier * float64(m.mfst.EC.Period))
+	lookbackDelay := m.mfst.EC.Period * time.Duration(m.mfst.EC.HeadLookback)
+	baseTimestamp := computeTipsetTimestampAtEpoch(head, cert.ECChain.Head().Epoch, m.mfst.EC.Period)
+	return baseTimestamp.Add(ecDelay + lookbackDelay + expectedDecisionDuration(m.mfst)), true
+}
+
+// expectedDecisionDuration estimates how long an instance takes to decide in its first round: the
+// QUALITY phase, which may run until its timeout, followed by PREPARE and COMMIT.
+func expectedDecisionDuration(m manifest.Manifest) time.Duration {
+	return time.Duration(m.Gpbft.QualityDeltaMultiplier*float64(m.Gpbft.Delta)) + m.Gpbft.Delta
+}
+
+// peerDiscovery returns the mechanisms selected by the manifest to find certificate exchange peers.
+func (m *F3) peerDiscovery() ([]certexpoll.Discovery, error) {
+	var discovery []certexpoll.Discovery
+	for _, d := range m.mfst.CertificateExchange.PeerDiscovery {
+		switch d {
+		case manifest.PeerDiscoveryConnected:
+			discovery = append(discovery, certexpoll.ConnectedPeers{})
+		case manifest.PeerDiscoveryStatic:
+			peers := make(certexpoll.StaticPeers, 0, len(m.mfst.CertificateExchange.StaticPeers))
+			for _, addr := range m.mfst.CertificateExchange.StaticPeers {
+				info, err := peer.AddrInfoFromString(addr)
+				if err != nil {
+					return nil, fmt.Errorf("parsing static certificate exchange peer %q: %w", addr, err)
+				}
+				peers = append(peers, *info)
+			}
+			discovery = append(discovery, peers)
+		case manifest.PeerDiscoveryDHT:
+			if m.contentRouting == nil {
+				log.Warn("skipping DHT discovery of certificate exchange peers without content routing")
+				continue
+			}
+			discovery = append(discovery, &certexpoll.ProviderDiscovery{Routing: m.contentRouting, Advertise: true})
+		default:
+			return nil, fmt.Errorf("unknown certificate exchange peer discovery %q", d)
+		}
+	}
+	return discovery, nil
+}
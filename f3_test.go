@@ -1,8 +1,10 @@
 package f3_test
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"path/filepath"
 	"strings"
 	"sync/atomic"
@@ -17,7 +19,9 @@ import (
 	"github.com/filecoin-project/go-f3/internal/consensus"
 	"github.com/filecoin-project/go-f3/internal/psutil"
 	"github.com/filecoin-project/go-f3/manifest"
+	"github.com/filecoin-project/go-f3/recording"
 	"github.com/filecoin-project/go-f3/sim/signing"
+	"github.com/ipfs/go-cid"
 	"github.com/ipfs/go-datastore"
 	"github.com/ipfs/go-datastore/failstore"
 	ds_sync "github.com/ipfs/go-datastore/sync"
@@ -213,6 +217,116 @@ func TestF3LateBootstrap(t *testing.T) {
 	})
 }
 
+func TestF3SnapshotBootstrap(t *testing.T) {
+	env := newTestEnvironment(t).withNodes(2).start()
+	env.requireInstanceEventually(5, eventualCheckTimeout, true)
+
+	cs, err := env.nodes[0].f3.GetCertStore()
+	require.NoError(t, err)
+	var snapshot bytes.Buffer
+	snapshotCid, header, err := cs.ExportLatestSnapshot(env.testCtx, &snapshot)
+	require.NoError(t, err)
+	source := func(context.Context) (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(snapshot.Bytes())), nil
+	}
+
+	// Untrusted snapshots are validated against the manifest initial power table.
+	cert0, err := env.nodes[0].f3.GetCert(env.testCtx, 0)
+	require.NoError(t, err)
+	env.manifest.InitialPowerTable = cert0.ECChain.Base().PowerTable
+
+	for _, test := range []struct {
+		name       string
+		trustedCid cid.Cid
+		wantImport bool
+	}{
+		{name: "trusted", trustedCid: snapshotCid, wantImport: true},
+		{name: "untrusted", trustedCid: cid.Undef, wantImport: true},
+		{name: "mismatching CID", trustedCid: cert0.SupplementalData.PowerTable, wantImport: false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			newNode := env.addNode()
+			newNode.opts = []f3.Option{f3.WithSnapshotBootstrap(source, test.trustedCid)}
+			module := newNode.init()
+			env.connectAll()
+			env.errgrp.Go(func() error {
+				return module.Start(env.testCtx)
+			})
+			env.whileAdvancingClock(func() {
+				require.Eventually(t, module.IsRunning, eventualCheckTimeout, eventualCheckInterval)
+			})
+
+			if test.wantImport {
+				// The snapshot is available as soon as the node is running.
+				_, err := module.GetCert(env.testCtx, header.LatestInstance)
+				require.NoError(t, err)
+			}
+			// Rejected snapshots are discarded, which TestImportSnapshot_DiscardsFailedImport
+			// checks, since the node starts catching up as soon as it is running.
+
+			// Either way, the node catches up with the rest of the network.
+			env.whileAdvancingClock(func() {
+				require.Eventually(t, func() bool {
+					cert, err := module.GetLatestCert(env.testCtx)
+					require.NoError(t, err)
+					return cert != nil && cert.GPBFTInstance > header.LatestInstance
+				}, eventualCheckTimeout, eventualCheckInterval)
+			})
+		})
+	}
+}
+
+func TestF3LocalActors(t *testing.T) {
+	// Node 0 hosts actors 0 and 2, and node 1 hosts actor 1, all with the same power. A strong
+	// quorum takes more than two thirds of it, so no instance is decided unless both of the votes
+	// of node 0 are broadcast and counted.
+	env := newTestEnvironment(t).withNodes(2).withActors(3)
+	env.nodes[0].opts = []f3.Option{f3.WithLocalActors(env.signingBackend, 0, 2)}
+	env.nodes[1].opts = []f3.Option{f3.WithRecording()}
+	env.start()
+	env.requireInstanceEventually(3, eventualCheckTimeout, true)
+
+	// Once restarted, node 0 resumes from its WAL rather than signing conflicting votes.
+	env.stopNode(0)
+	env.requireF3NotRunningEventually(eventualCheckTimeout, nodeMatchers.byID(0))
+	env.startNode(0)
+	env.requireF3RunningEventually(eventualCheckTimeout, nodeMatchers.byID(0))
+	env.requireInstanceEventually(env.nodes[1].currentGpbftInstance()+3, eventualCheckTimeout, true)
+
+	// Node 1 received the votes of both local actors of node 0, and never two different votes
+	// from either at the same instance, round and phase.
+	env.stopNode(1)
+	dirs, err := filepath.Glob(filepath.Join(env.tempDir, "participant-1", "recording", "*"))
+	require.NoError(t, err)
+	require.Len(t, dirs, 1)
+	entries, err := recording.Read(dirs[0])
+	require.NoError(t, err)
+
+	type voteKey struct {
+		sender          gpbft.ActorID
+		instance, round uint64
+		phase           gpbft.Phase
+	}
+	votes := make(map[voteKey][]byte)
+	for _, entry := range entries {
+		if entry.Kind != recording.KindMessage {
+			continue
+		}
+		msg := entry.Message
+		key := voteKey{sender: msg.Sender, instance: msg.Vote.Instance, round: msg.Vote.Round, phase: msg.Vote.Phase}
+		if signature, found := votes[key]; found {
+			require.Equal(t, signature, msg.Signature, "actor %d equivocated at %+v", msg.Sender, key)
+		}
+		votes[key] = msg.Signature
+	}
+	senders := make(map[gpbft.ActorID]struct{})
+	for key := range votes {
+		senders[key.sender] = struct{}{}
+	}
+	require.Contains(t, senders, gpbft.ActorID(0))
+	require.Contains(t, senders, gpbft.ActorID(2))
+}
+
 func TestF3EpochFinalizedWithChainExchange(t *testing.T) {
 	env := newTestEnvironment(t).withNodes(2)
 
@@ -299,6 +413,7 @@ type testNode struct {
 	f3        *f3.F3
 	dsErrFunc func(string) error
 	ec        ec.Backend
+	opts      []f3.Option
 }
 
 func (n *testNode) currentGpbftInstance() uint64 {
@@ -328,7 +443,7 @@ func (n *testNode) init() *f3.F3 {
 		n.ec = n.e.ec
 	}
 	n.f3, err = f3.New(n.e.testCtx, n.e.manifest, ds, n.h, ps, n.e.signingBackend, n.ec,
-		filepath.Join(n.e.tempDir, fmt.Sprintf("participant-%d", n.id)))
+		filepath.Join(n.e.tempDir, fmt.Sprintf("participant-%d", n.id)), n.opts...)
 	require.NoError(n.e.t, err)
 
 	n.e.errgrp.Go(func() error {
@@ -393,6 +508,8 @@ type testEnv struct {
 	net            mocknet.Mocknet
 	clock          *clock.Mock
 	tempDir        string // we need to ask for it before any of our cleanup hooks
+	// The number of actors in the initial power table, if not one per node.
+	actors int
 
 	manifest manifest.Manifest
 }
@@ -431,6 +548,13 @@ func (e *testEnv) withNodes(n int) *testEnv {
 	return e
 }
 
+// withActors sets the number of actors in the initial power table, for nodes that host several
+// actors or none.
+func (e *testEnv) withActors(n int) *testEnv {
+	e.actors = n
+	return e
+}
+
 func (e *testEnv) whileAdvancingClock(do func()) {
 	e.t.Helper()
 	ctx, cancel := context.WithCancel(e.testCtx)
@@ -582,11 +706,15 @@ func (e *testEnv) connectAll() *testEnv {
 func (e *testEnv) initialize() *testEnv {
 	// Construct the EC if necessary.
 	if e.ec == nil {
+		actors := e.actors
+		if actors == 0 {
+			actors = len(e.nodes)
+		}
 		initialPowerTable := gpbft.PowerEntries{}
-		for _, n := range e.nodes {
+		for id := range actors {
 			pubkey, _ := e.signingBackend.GenerateKey()
 			initialPowerTable = append(initialPowerTable, gpbft.PowerEntry{
-				ID:     gpbft.ActorID(n.id),
+				ID:     gpbft.ActorID(id),
 				PubKey: pubkey,
 				Power:  gpbft.NewStoragePower(1000),
 			})
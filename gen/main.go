@@ -9,6 +9,7 @@ import (
 	"github.com/filecoin-project/go-f3/certstore"
 	"github.com/filecoin-project/go-f3/chainexchange"
 	"github.com/filecoin-project/go-f3/gpbft"
+	"github.com/filecoin-project/go-f3/recording"
 	gen "github.com/whyrusleeping/cbor-gen"
 	"golang.org/x/sync/errgroup"
 )
@@ -28,6 +29,13 @@ func main() {
 			gpbft.Justification{},
 			gpbft.PowerEntry{},
 			gpbft.PowerEntries{},
+			gpbft.InstanceState{},
+			gpbft.RoundSnapshot{},
+			gpbft.QuorumSnapshot{},
+			gpbft.SenderVote{},
+			gpbft.ReceivedJustification{},
+			gpbft.ConvergeSnapshot{},
+			gpbft.ConvergeValueSnapshot{},
 		)
 	})
 	eg.Go(func() error {
@@ -35,12 +43,21 @@ func main() {
 			certs.PowerTableDelta{},
 			certs.PowerTableDiff{},
 			certs.FinalityCertificate{},
+			certs.TipSetInclusionProof{},
 		)
 	})
 	eg.Go(func() error {
 		return gen.WriteTupleEncodersToFile("../certexchange/cbor_gen.go", "certexchange",
 			certexchange.Request{},
 			certexchange.ResponseHeader{},
+			certexchange.RequestV2{},
+			certexchange.ResponseHeaderV2{},
+			certexchange.PowerTableCheckpoint{},
+			certexchange.SnapshotRequest{},
+			certexchange.SnapshotResponseHeader{},
+			certexchange.Announcement{},
+			certexchange.PowerTableRequest{},
+			certexchange.PowerTableResponse{},
 		)
 	})
 	eg.Go(func() error {
@@ -53,6 +70,11 @@ func main() {
 			certstore.SnapshotHeader{},
 		)
 	})
+	eg.Go(func() error {
+		return gen.WriteTupleEncodersToFile("../recording/cbor_gen.go", "recording",
+			recording.Entry{},
+		)
+	})
 	if err := eg.Wait(); err != nil {
 		fmt.Printf("Failed to complete cborg_gen: %v\n", err)
 		os.Exit(1)
@@ -154,6 +154,30 @@ type Verifier interface {
 	Aggregate(pubKeys []PubKey) (Aggregate, error)
 }
 
+// BatchVerifier may optionally be implemented by a Verifier that verifies many signatures at
+// once faster than one at a time, which is then used to verify the signatures of messages in
+// batches when batch validation is enabled.
+type BatchVerifier interface {
+	// Verifies a batch of signatures at once, where the signature at each index is by the
+	// public key at the same index over the message at the same index. Returns nil only if all
+	// signatures are valid. An error does not tell which signatures are invalid; callers should
+	// fall back on Verifier.Verify to find out.
+	//
+	// Implementations must be safe for concurrent use.
+	VerifyBatch(pubKeys []PubKey, msgs [][]byte, sigs [][]byte) error
+}
+
+// BatchAggregate may optionally be implemented by an Aggregate whose aggregate signatures are
+// signatures by a single aggregate public key, which is then used to verify the aggregate
+// signatures of justifications in batches along with other signatures.
+type BatchAggregate interface {
+	// AggregatePublicKey returns the public key that an aggregate signature by the given signers
+	// is verified against with Verifier.Verify or BatchVerifier.VerifyBatch.
+	//
+	// Implementations must be safe for concurrent use.
+	AggregatePublicKey(signerMask []int) (PubKey, error)
+}
+
 type DecisionReceiver interface {
 	// Receives a finality decision from the instance, with signatures from a strong quorum
 	// of participants justifying it.
@@ -170,6 +194,13 @@ type Tracer interface {
 	Log(format string, args ...any)
 }
 
+// EventSink receives the typed events of a Participant, as set by WithEventSink. Events are
+// delivered synchronously, from within the API methods of the Participant, so ReceiveEvent must
+// not block nor call back into the Participant.
+type EventSink interface {
+	ReceiveEvent(Event)
+}
+
 // Participant interface to the host system resources.
 type Host interface {
 	ProposalProvider
@@ -0,0 +1,153 @@
+package gpbft
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// maxVerifyBatchSize is the maximum number of signatures verified in a single batch.
+const maxVerifyBatchSize = 256
+
+var _ Verifier = (*batchVerifier)(nil)
+
+// batchVerifier verifies signatures submitted concurrently, such as the ones of the messages
+// validated by pubsub, in batches. The first signature submitted opens a batch, which collects
+// the signatures submitted after it until either the batch window elapses or the batch is full,
+// whichever comes first. The batch is then verified by the goroutine that opened it, while the
+// others wait for the result. At most the configured number of batches are verified
+// concurrently, making up the worker pool.
+//
+// Batches are verified at once if the wrapped Verifier implements BatchVerifier, and one
+// signature at a time otherwise. A batch that fails verification has its signatures verified one
+// at a time to tell which are invalid, so that a single invalid signature never causes a valid
+// one to be rejected.
+//
+// Aggregate signatures are verified in batches too when their Aggregate implements
+// BatchAggregate. Aggregation is otherwise left to the wrapped Verifier as is.
+type batchVerifier struct {
+	Verifier
+	batch   BatchVerifier
+	window  time.Duration
+	workers chan struct{}
+
+	mu      sync.Mutex
+	pending *verifyBatch
+}
+
+type verifyBatch struct {
+	pubKeys []PubKey
+	msgs    [][]byte
+	sigs    [][]byte
+	// full is closed once the batch stops accepting signatures before its window elapses.
+	full chan struct{}
+	// done is closed once the batch is verified and results are set.
+	done    chan struct{}
+	results []error
+}
+
+func newBatchVerifier(verifier Verifier, window time.Duration, workers int) *batchVerifier {
+	batch, _ := verifier.(BatchVerifier)
+	return &batchVerifier{
+		Verifier: verifier,
+		batch:    batch,
+		window:   window,
+		workers:  make(chan struct{}, workers),
+	}
+}
+
+// Verify adds the signature to the pending batch, and waits for the batch to be verified.
+func (b *batchVerifier) Verify(pubKey PubKey, msg, sig []byte) error {
+	b.mu.Lock()
+	batch := b.pending
+	opened := batch == nil
+	if opened {
+		batch = &verifyBatch{
+			full: make(chan struct{}),
+			done: make(chan struct{}),
+		}
+		b.pending = batch
+	}
+	index := len(batch.sigs)
+	batch.pubKeys = append(batch.pubKeys, pubKey)
+	batch.msgs = append(batch.msgs, msg)
+	batch.sigs = append(batch.sigs, sig)
+	if len(batch.sigs) >= maxVerifyBatchSize {
+		b.pending = nil
+		close(batch.full)
+	}
+	b.mu.Unlock()
+
+	if opened {
+		b.collectAndVerify(batch)
+	} else {
+		<-batch.done
+	}
+	return batch.results[index]
+}
+
+// VerifyAggregate adds the aggregate signature to the pending batch as a signature by the
+// aggregate public key of its signers, and waits for the batch to be verified. Aggregate
+// signatures of an Aggregate that does not implement BatchAggregate are verified right away.
+func (b *batchVerifier) VerifyAggregate(agg Aggregate, signerMask []int, payload, aggSig []byte) error {
+	batchAgg, ok := agg.(BatchAggregate)
+	if !ok {
+		return agg.VerifyAggregate(signerMask, payload, aggSig)
+	}
+	pubKey, err := batchAgg.AggregatePublicKey(signerMask)
+	if err != nil {
+		return fmt.Errorf("aggregating public keys: %w", err)
+	}
+	return b.Verify(pubKey, payload, aggSig)
+}
+
+func (b *batchVerifier) collectAndVerify(batch *verifyBatch) {
+	// Flush the batch as soon as it is full, without waiting for the window to elapse.
+	select {
+	case <-batch.full:
+	default:
+		timer := time.NewTimer(b.window)
+		select {
+		case <-timer.C:
+		case <-batch.full:
+		}
+		timer.Stop()
+	}
+
+	b.mu.Lock()
+	if b.pending == batch {
+		b.pending = nil
+	}
+	b.mu.Unlock()
+
+	b.workers <- struct{}{}
+	defer func() { <-b.workers }()
+	batch.verify(b.Verifier, b.batch)
+	close(batch.done)
+}
+
+func (vb *verifyBatch) verify(verifier Verifier, batchVerifier BatchVerifier) {
+	vb.results = make([]error, len(vb.sigs))
+	defer func() {
+		if r := recover(); r != nil {
+			err := newPanicError(r)
+			for i := range vb.results {
+				vb.results[i] = err
+			}
+		}
+	}()
+
+	metrics.verifyBatchSize.Record(context.TODO(), int64(len(vb.sigs)))
+	if len(vb.sigs) > 1 && batchVerifier != nil {
+		if err := batchVerifier.VerifyBatch(vb.pubKeys, vb.msgs, vb.sigs); err == nil {
+			return
+		}
+		// At least one signature is invalid, or the batch could not be verified as a whole.
+		// Either way, verify each signature on its own.
+		metrics.verifyBatchFallback.Add(context.TODO(), 1)
+	}
+	for i := range vb.sigs {
+		vb.results[i] = verifier.Verify(vb.pubKeys[i], vb.msgs[i], vb.sigs[i])
+	}
+}
@@ -0,0 +1,179 @@
+package gpbft
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var _ Verifier = (*countingVerifier)(nil)
+
+// countingVerifier accepts any signature other than "invalid", and counts the calls made to it.
+type countingVerifier struct {
+	Verifier
+	verified, batches atomic.Int64
+}
+
+func (v *countingVerifier) Verify(_ PubKey, _, sig []byte) error {
+	v.verified.Add(1)
+	if string(sig) == "invalid" {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+func (v *countingVerifier) VerifyBatch(_ []PubKey, _ [][]byte, sigs [][]byte) error {
+	v.batches.Add(1)
+	for _, sig := range sigs {
+		if string(sig) == "invalid" {
+			return errors.New("invalid batch")
+		}
+	}
+	return nil
+}
+
+var _ BatchAggregate = (*countingAggregate)(nil)
+
+// countingAggregate accepts any aggregate signature other than "invalid", and counts the ones
+// verified directly rather than in batches.
+type countingAggregate struct {
+	verified atomic.Int64
+}
+
+// unbatchedAggregate hides AggregatePublicKey of the Aggregate it embeds.
+type unbatchedAggregate struct {
+	aggregate
+}
+
+type aggregate = Aggregate
+
+func (a *countingAggregate) Aggregate([]int, [][]byte) ([]byte, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (a *countingAggregate) VerifyAggregate(_ []int, _, aggSig []byte) error {
+	a.verified.Add(1)
+	if string(aggSig) == "invalid" {
+		return errors.New("invalid aggregate signature")
+	}
+	return nil
+}
+
+func (a *countingAggregate) AggregatePublicKey(signerMask []int) (PubKey, error) {
+	return PubKey(fmt.Sprint(signerMask)), nil
+}
+
+func TestBatchVerifier(t *testing.T) {
+	verifyConcurrently := func(subject *batchVerifier, sigs [][]byte) []error {
+		results := make([]error, len(sigs))
+		var wg sync.WaitGroup
+		for i, sig := range sigs {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results[i] = subject.Verify(PubKey(fmt.Sprint(i)), []byte("msg"), sig)
+			}()
+		}
+		wg.Wait()
+		return results
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		verifier := &countingVerifier{}
+		subject := newBatchVerifier(verifier, 100*time.Millisecond, 2)
+		sigs := make([][]byte, 16)
+		for i := range sigs {
+			sigs[i] = []byte("valid")
+		}
+		for _, err := range verifyConcurrently(subject, sigs) {
+			require.NoError(t, err)
+		}
+		require.NotZero(t, verifier.batches.Load())
+		// Signatures are verified in batches, with only lone ones verified on their own.
+		require.Less(t, verifier.batches.Load()+verifier.verified.Load(), int64(len(sigs)))
+	})
+	t.Run("invalid", func(t *testing.T) {
+		verifier := &countingVerifier{}
+		subject := newBatchVerifier(verifier, 100*time.Millisecond, 2)
+		sigs := make([][]byte, 16)
+		for i := range sigs {
+			sigs[i] = []byte("valid")
+		}
+		sigs[5] = []byte("invalid")
+		for i, err := range verifyConcurrently(subject, sigs) {
+			if i == 5 {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err, "signature %d", i)
+			}
+		}
+	})
+	t.Run("without batch verification", func(t *testing.T) {
+		verifier := &countingVerifier{}
+		// Hide VerifyBatch, so that signatures are verified one at a time.
+		subject := newBatchVerifier(struct{ Verifier }{verifier}, 100*time.Millisecond, 2)
+		sigs := make([][]byte, 16)
+		for i := range sigs {
+			sigs[i] = []byte("valid")
+		}
+		sigs[5] = []byte("invalid")
+		for i, err := range verifyConcurrently(subject, sigs) {
+			if i == 5 {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err, "signature %d", i)
+			}
+		}
+		require.Zero(t, verifier.batches.Load())
+		require.Equal(t, int64(len(sigs)), verifier.verified.Load())
+	})
+	t.Run("aggregates", func(t *testing.T) {
+		verifier := &countingVerifier{}
+		aggregate := &countingAggregate{}
+		subject := newBatchVerifier(verifier, time.Hour, 1)
+		sigs := make([][]byte, maxVerifyBatchSize-1)
+		for i := range sigs {
+			sigs[i] = []byte("valid")
+		}
+		// The aggregate signature fills the batch along with the others.
+		var aggErr error
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			aggErr = subject.VerifyAggregate(aggregate, []int{0, 1}, []byte("msg"), []byte("valid"))
+		}()
+		for _, err := range verifyConcurrently(subject, sigs) {
+			require.NoError(t, err)
+		}
+		wg.Wait()
+		require.NoError(t, aggErr)
+		require.Equal(t, int64(1), verifier.batches.Load())
+		require.Zero(t, aggregate.verified.Load())
+
+		// Aggregates that do not support batching are verified on their own.
+		err := subject.VerifyAggregate(unbatchedAggregate{aggregate}, []int{0, 1}, []byte("msg"), []byte("invalid"))
+		require.Error(t, err)
+		require.Equal(t, int64(1), aggregate.verified.Load())
+	})
+	t.Run("full", func(t *testing.T) {
+		verifier := &countingVerifier{}
+		// A window long enough for the test to time out unless full batches are verified
+		// without waiting for it.
+		subject := newBatchVerifier(verifier, time.Hour, 1)
+		sigs := make([][]byte, maxVerifyBatchSize)
+		for i := range sigs {
+			sigs[i] = []byte("valid")
+		}
+		for _, err := range verifyConcurrently(subject, sigs) {
+			require.NoError(t, err)
+		}
+		require.Equal(t, int64(1), verifier.batches.Load())
+		require.Zero(t, verifier.verified.Load())
+	})
+}
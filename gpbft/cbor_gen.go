@@ -1041,3 +1041,1108 @@ func (t *PowerEntries) UnmarshalCBOR(r io.Reader) (err error) {
 	}
 	return nil
 }
+
+var lengthBufInstanceState = []byte{140}
+
+func (t *InstanceState) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
+		return err
+	}
+
+	cw := cbg.NewCborWriter(w)
+
+	if _, err := cw.Write(lengthBufInstanceState); err != nil {
+		return err
+	}
+
+	// t.Instance (uint64) (uint64)
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.Instance)); err != nil {
+		return err
+	}
+
+	// t.Round (uint64) (uint64)
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.Round)); err != nil {
+		return err
+	}
+
+	// t.Phase (gpbft.Phase) (uint8)
+	if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.Phase)); err != nil {
+		return err
+	}
+
+	// t.PhaseTimeout (int64) (int64)
+	if t.PhaseTimeout >= 0 {
+		if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.PhaseTimeout)); err != nil {
+			return err
+		}
+	} else {
+		if err := cw.WriteMajorTypeHeader(cbg.MajNegativeInt, uint64(-t.PhaseTimeout-1)); err != nil {
+			return err
+		}
+	}
+
+	// t.Input (gpbft.ECChain) (struct)
+	if err := t.Input.MarshalCBOR(cw); err != nil {
+		return err
+	}
+
+	// t.SupplementalData (gpbft.SupplementalData) (struct)
+	if err := t.SupplementalData.MarshalCBOR(cw); err != nil {
+		return err
+	}
+
+	// t.Proposal (gpbft.ECChain) (struct)
+	if err := t.Proposal.MarshalCBOR(cw); err != nil {
+		return err
+	}
+
+	// t.Value (gpbft.ECChain) (struct)
+	if err := t.Value.MarshalCBOR(cw); err != nil {
+		return err
+	}
+
+	// t.Candidates ([]*gpbft.ECChain) (slice)
+	if len(t.Candidates) > 8192 {
+		return xerrors.Errorf("Slice value in field t.Candidates was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajArray, uint64(len(t.Candidates))); err != nil {
+		return err
+	}
+	for _, v := range t.Candidates {
+		if err := v.MarshalCBOR(cw); err != nil {
+			return err
+		}
+
+	}
+
+	// t.Quality (gpbft.QuorumSnapshot) (struct)
+	if err := t.Quality.MarshalCBOR(cw); err != nil {
+		return err
+	}
+
+	// t.Rounds ([]gpbft.RoundSnapshot) (slice)
+	if len(t.Rounds) > 8192 {
+		return xerrors.Errorf("Slice value in field t.Rounds was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajArray, uint64(len(t.Rounds))); err != nil {
+		return err
+	}
+	for _, v := range t.Rounds {
+		if err := v.MarshalCBOR(cw); err != nil {
+			return err
+		}
+
+	}
+
+	// t.Decision (gpbft.QuorumSnapshot) (struct)
+	if err := t.Decision.MarshalCBOR(cw); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (t *InstanceState) UnmarshalCBOR(r io.Reader) (err error) {
+	*t = InstanceState{}
+
+	cr := cbg.NewCborReader(r)
+
+	maj, extra, err := cr.ReadHeader()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+	}()
+
+	if maj != cbg.MajArray {
+		return fmt.Errorf("cbor input should be of type array")
+	}
+
+	if extra != 12 {
+		return fmt.Errorf("cbor input had wrong number of fields")
+	}
+
+	// t.Instance (uint64) (uint64)
+
+	{
+
+		maj, extra, err = cr.ReadHeader()
+		if err != nil {
+			return err
+		}
+		if maj != cbg.MajUnsignedInt {
+			return fmt.Errorf("wrong type for uint64 field")
+		}
+		t.Instance = uint64(extra)
+
+	}
+	// t.Round (uint64) (uint64)
+
+	{
+
+		maj, extra, err = cr.ReadHeader()
+		if err != nil {
+			return err
+		}
+		if maj != cbg.MajUnsignedInt {
+			return fmt.Errorf("wrong type for uint64 field")
+		}
+		t.Round = uint64(extra)
+
+	}
+	// t.Phase (gpbft.Phase) (uint8)
+
+	maj, extra, err = cr.ReadHeader()
+	if err != nil {
+		return err
+	}
+	if maj != cbg.MajUnsignedInt {
+		return fmt.Errorf("wrong type for uint8 field")
+	}
+	if extra > math.MaxUint8 {
+		return fmt.Errorf("integer in input was too large for uint8 field")
+	}
+	t.Phase = Phase(extra)
+	// t.PhaseTimeout (int64) (int64)
+	{
+		maj, extra, err := cr.ReadHeader()
+		if err != nil {
+			return err
+		}
+		var extraI int64
+		switch maj {
+		case cbg.MajUnsignedInt:
+			extraI = int64(extra)
+			if extraI < 0 {
+				return fmt.Errorf("int64 positive overflow")
+			}
+		case cbg.MajNegativeInt:
+			extraI = int64(extra)
+			if extraI < 0 {
+				return fmt.Errorf("int64 negative overflow")
+			}
+			extraI = -1 - extraI
+		default:
+			return fmt.Errorf("wrong type for int64 field: %d", maj)
+		}
+
+		t.PhaseTimeout = int64(extraI)
+	}
+	// t.Input (gpbft.ECChain) (struct)
+
+	{
+
+		b, err := cr.ReadByte()
+		if err != nil {
+			return err
+		}
+		if b != cbg.CborNull[0] {
+			if err := cr.UnreadByte(); err != nil {
+				return err
+			}
+			t.Input = new(ECChain)
+			if err := t.Input.UnmarshalCBOR(cr); err != nil {
+				return xerrors.Errorf("unmarshaling t.Input pointer: %w", err)
+			}
+		}
+
+	}
+	// t.SupplementalData (gpbft.SupplementalData) (struct)
+
+	{
+
+		b, err := cr.ReadByte()
+		if err != nil {
+			return err
+		}
+		if b != cbg.CborNull[0] {
+			if err := cr.UnreadByte(); err != nil {
+				return err
+			}
+			t.SupplementalData = new(SupplementalData)
+			if err := t.SupplementalData.UnmarshalCBOR(cr); err != nil {
+				return xerrors.Errorf("unmarshaling t.SupplementalData pointer: %w", err)
+			}
+		}
+
+	}
+	// t.Proposal (gpbft.ECChain) (struct)
+
+	{
+
+		b, err := cr.ReadByte()
+		if err != nil {
+			return err
+		}
+		if b != cbg.CborNull[0] {
+			if err := cr.UnreadByte(); err != nil {
+				return err
+			}
+			t.Proposal = new(ECChain)
+			if err := t.Proposal.UnmarshalCBOR(cr); err != nil {
+				return xerrors.Errorf("unmarshaling t.Proposal pointer: %w", err)
+			}
+		}
+
+	}
+	// t.Value (gpbft.ECChain) (struct)
+
+	{
+
+		b, err := cr.ReadByte()
+		if err != nil {
+			return err
+		}
+		if b != cbg.CborNull[0] {
+			if err := cr.UnreadByte(); err != nil {
+				return err
+			}
+			t.Value = new(ECChain)
+			if err := t.Value.UnmarshalCBOR(cr); err != nil {
+				return xerrors.Errorf("unmarshaling t.Value pointer: %w", err)
+			}
+		}
+
+	}
+	// t.Candidates ([]*gpbft.ECChain) (slice)
+
+	maj, extra, err = cr.ReadHeader()
+	if err != nil {
+		return err
+	}
+
+	if extra > 8192 {
+		return fmt.Errorf("t.Candidates: array too large (%d)", extra)
+	}
+
+	if maj != cbg.MajArray {
+		return fmt.Errorf("expected cbor array")
+	}
+
+	if extra > 0 {
+		t.Candidates = make([]*ECChain, extra)
+	}
+
+	for i := 0; i < int(extra); i++ {
+		{
+			var maj byte
+			var extra uint64
+			var err error
+			_ = maj
+			_ = extra
+			_ = err
+
+			{
+
+				b, err := cr.ReadByte()
+				if err != nil {
+					return err
+				}
+				if b != cbg.CborNull[0] {
+					if err := cr.UnreadByte(); err != nil {
+						return err
+					}
+					t.Candidates[i] = new(ECChain)
+					if err := t.Candidates[i].UnmarshalCBOR(cr); err != nil {
+						return xerrors.Errorf("unmarshaling t.Candidates[i] pointer: %w", err)
+					}
+				}
+
+			}
+
+		}
+	}
+	// t.Quality (gpbft.QuorumSnapshot) (struct)
+
+	{
+
+		if err := t.Quality.UnmarshalCBOR(cr); err != nil {
+			return xerrors.Errorf("unmarshaling t.Quality: %w", err)
+		}
+
+	}
+	// t.Rounds ([]gpbft.RoundSnapshot) (slice)
+
+	maj, extra, err = cr.ReadHeader()
+	if err != nil {
+		return err
+	}
+
+	if extra > 8192 {
+		return fmt.Errorf("t.Rounds: array too large (%d)", extra)
+	}
+
+	if maj != cbg.MajArray {
+		return fmt.Errorf("expected cbor array")
+	}
+
+	if extra > 0 {
+		t.Rounds = make([]RoundSnapshot, extra)
+	}
+
+	for i := 0; i < int(extra); i++ {
+		{
+			var maj byte
+			var extra uint64
+			var err error
+			_ = maj
+			_ = extra
+			_ = err
+
+			{
+
+				if err := t.Rounds[i].UnmarshalCBOR(cr); err != nil {
+					return xerrors.Errorf("unmarshaling t.Rounds[i]: %w", err)
+				}
+
+			}
+
+		}
+	}
+	// t.Decision (gpbft.QuorumSnapshot) (struct)
+
+	{
+
+		if err := t.Decision.UnmarshalCBOR(cr); err != nil {
+			return xerrors.Errorf("unmarshaling t.Decision: %w", err)
+		}
+
+	}
+	return nil
+}
+
+var lengthBufRoundSnapshot = []byte{132}
+
+func (t *RoundSnapshot) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
+		return err
+	}
+
+	cw := cbg.NewCborWriter(w)
+
+	if _, err := cw.Write(lengthBufRoundSnapshot); err != nil {
+		return err
+	}
+
+	// t.Round (uint64) (uint64)
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.Round)); err != nil {
+		return err
+	}
+
+	// t.Converged (gpbft.ConvergeSnapshot) (struct)
+	if err := t.Converged.MarshalCBOR(cw); err != nil {
+		return err
+	}
+
+	// t.Prepared (gpbft.QuorumSnapshot) (struct)
+	if err := t.Prepared.MarshalCBOR(cw); err != nil {
+		return err
+	}
+
+	// t.Committed (gpbft.QuorumSnapshot) (struct)
+	if err := t.Committed.MarshalCBOR(cw); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (t *RoundSnapshot) UnmarshalCBOR(r io.Reader) (err error) {
+	*t = RoundSnapshot{}
+
+	cr := cbg.NewCborReader(r)
+
+	maj, extra, err := cr.ReadHeader()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+	}()
+
+	if maj != cbg.MajArray {
+		return fmt.Errorf("cbor input should be of type array")
+	}
+
+	if extra != 4 {
+		return fmt.Errorf("cbor input had wrong number of fields")
+	}
+
+	// t.Round (uint64) (uint64)
+
+	{
+
+		maj, extra, err = cr.ReadHeader()
+		if err != nil {
+			return err
+		}
+		if maj != cbg.MajUnsignedInt {
+			return fmt.Errorf("wrong type for uint64 field")
+		}
+		t.Round = uint64(extra)
+
+	}
+	// t.Converged (gpbft.ConvergeSnapshot) (struct)
+
+	{
+
+		if err := t.Converged.UnmarshalCBOR(cr); err != nil {
+			return xerrors.Errorf("unmarshaling t.Converged: %w", err)
+		}
+
+	}
+	// t.Prepared (gpbft.QuorumSnapshot) (struct)
+
+	{
+
+		if err := t.Prepared.UnmarshalCBOR(cr); err != nil {
+			return xerrors.Errorf("unmarshaling t.Prepared: %w", err)
+		}
+
+	}
+	// t.Committed (gpbft.QuorumSnapshot) (struct)
+
+	{
+
+		if err := t.Committed.UnmarshalCBOR(cr); err != nil {
+			return xerrors.Errorf("unmarshaling t.Committed: %w", err)
+		}
+
+	}
+	return nil
+}
+
+var lengthBufQuorumSnapshot = []byte{130}
+
+func (t *QuorumSnapshot) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
+		return err
+	}
+
+	cw := cbg.NewCborWriter(w)
+
+	if _, err := cw.Write(lengthBufQuorumSnapshot); err != nil {
+		return err
+	}
+
+	// t.Votes ([]gpbft.SenderVote) (slice)
+	if len(t.Votes) > 8192 {
+		return xerrors.Errorf("Slice value in field t.Votes was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajArray, uint64(len(t.Votes))); err != nil {
+		return err
+	}
+	for _, v := range t.Votes {
+		if err := v.MarshalCBOR(cw); err != nil {
+			return err
+		}
+
+	}
+
+	// t.Justifications ([]gpbft.ReceivedJustification) (slice)
+	if len(t.Justifications) > 8192 {
+		return xerrors.Errorf("Slice value in field t.Justifications was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajArray, uint64(len(t.Justifications))); err != nil {
+		return err
+	}
+	for _, v := range t.Justifications {
+		if err := v.MarshalCBOR(cw); err != nil {
+			return err
+		}
+
+	}
+	return nil
+}
+
+func (t *QuorumSnapshot) UnmarshalCBOR(r io.Reader) (err error) {
+	*t = QuorumSnapshot{}
+
+	cr := cbg.NewCborReader(r)
+
+	maj, extra, err := cr.ReadHeader()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+	}()
+
+	if maj != cbg.MajArray {
+		return fmt.Errorf("cbor input should be of type array")
+	}
+
+	if extra != 2 {
+		return fmt.Errorf("cbor input had wrong number of fields")
+	}
+
+	// t.Votes ([]gpbft.SenderVote) (slice)
+
+	maj, extra, err = cr.ReadHeader()
+	if err != nil {
+		return err
+	}
+
+	if extra > 8192 {
+		return fmt.Errorf("t.Votes: array too large (%d)", extra)
+	}
+
+	if maj != cbg.MajArray {
+		return fmt.Errorf("expected cbor array")
+	}
+
+	if extra > 0 {
+		t.Votes = make([]SenderVote, extra)
+	}
+
+	for i := 0; i < int(extra); i++ {
+		{
+			var maj byte
+			var extra uint64
+			var err error
+			_ = maj
+			_ = extra
+			_ = err
+
+			{
+
+				if err := t.Votes[i].UnmarshalCBOR(cr); err != nil {
+					return xerrors.Errorf("unmarshaling t.Votes[i]: %w", err)
+				}
+
+			}
+
+		}
+	}
+	// t.Justifications ([]gpbft.ReceivedJustification) (slice)
+
+	maj, extra, err = cr.ReadHeader()
+	if err != nil {
+		return err
+	}
+
+	if extra > 8192 {
+		return fmt.Errorf("t.Justifications: array too large (%d)", extra)
+	}
+
+	if maj != cbg.MajArray {
+		return fmt.Errorf("expected cbor array")
+	}
+
+	if extra > 0 {
+		t.Justifications = make([]ReceivedJustification, extra)
+	}
+
+	for i := 0; i < int(extra); i++ {
+		{
+			var maj byte
+			var extra uint64
+			var err error
+			_ = maj
+			_ = extra
+			_ = err
+
+			{
+
+				if err := t.Justifications[i].UnmarshalCBOR(cr); err != nil {
+					return xerrors.Errorf("unmarshaling t.Justifications[i]: %w", err)
+				}
+
+			}
+
+		}
+	}
+	return nil
+}
+
+var lengthBufSenderVote = []byte{131}
+
+func (t *SenderVote) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
+		return err
+	}
+
+	cw := cbg.NewCborWriter(w)
+
+	if _, err := cw.Write(lengthBufSenderVote); err != nil {
+		return err
+	}
+
+	// t.Sender (gpbft.ActorID) (uint64)
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.Sender)); err != nil {
+		return err
+	}
+
+	// t.Value (gpbft.ECChain) (struct)
+	if err := t.Value.MarshalCBOR(cw); err != nil {
+		return err
+	}
+
+	// t.Signature ([]uint8) (slice)
+	if len(t.Signature) > 2097152 {
+		return xerrors.Errorf("Byte array in field t.Signature was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajByteString, uint64(len(t.Signature))); err != nil {
+		return err
+	}
+
+	if _, err := cw.Write(t.Signature); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (t *SenderVote) UnmarshalCBOR(r io.Reader) (err error) {
+	*t = SenderVote{}
+
+	cr := cbg.NewCborReader(r)
+
+	maj, extra, err := cr.ReadHeader()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+	}()
+
+	if maj != cbg.MajArray {
+		return fmt.Errorf("cbor input should be of type array")
+	}
+
+	if extra != 3 {
+		return fmt.Errorf("cbor input had wrong number of fields")
+	}
+
+	// t.Sender (gpbft.ActorID) (uint64)
+
+	{
+
+		maj, extra, err = cr.ReadHeader()
+		if err != nil {
+			return err
+		}
+		if maj != cbg.MajUnsignedInt {
+			return fmt.Errorf("wrong type for uint64 field")
+		}
+		t.Sender = ActorID(extra)
+
+	}
+	// t.Value (gpbft.ECChain) (struct)
+
+	{
+
+		b, err := cr.ReadByte()
+		if err != nil {
+			return err
+		}
+		if b != cbg.CborNull[0] {
+			if err := cr.UnreadByte(); err != nil {
+				return err
+			}
+			t.Value = new(ECChain)
+			if err := t.Value.UnmarshalCBOR(cr); err != nil {
+				return xerrors.Errorf("unmarshaling t.Value pointer: %w", err)
+			}
+		}
+
+	}
+	// t.Signature ([]uint8) (slice)
+
+	maj, extra, err = cr.ReadHeader()
+	if err != nil {
+		return err
+	}
+
+	if extra > 2097152 {
+		return fmt.Errorf("t.Signature: byte array too large (%d)", extra)
+	}
+	if maj != cbg.MajByteString {
+		return fmt.Errorf("expected byte array")
+	}
+
+	if extra > 0 {
+		t.Signature = make([]uint8, extra)
+	}
+
+	if _, err := io.ReadFull(cr, t.Signature); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+var lengthBufReceivedJustification = []byte{130}
+
+func (t *ReceivedJustification) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
+		return err
+	}
+
+	cw := cbg.NewCborWriter(w)
+
+	if _, err := cw.Write(lengthBufReceivedJustification); err != nil {
+		return err
+	}
+
+	// t.Value (gpbft.ECChain) (struct)
+	if err := t.Value.MarshalCBOR(cw); err != nil {
+		return err
+	}
+
+	// t.Justification (gpbft.Justification) (struct)
+	if err := t.Justification.MarshalCBOR(cw); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (t *ReceivedJustification) UnmarshalCBOR(r io.Reader) (err error) {
+	*t = ReceivedJustification{}
+
+	cr := cbg.NewCborReader(r)
+
+	maj, extra, err := cr.ReadHeader()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+	}()
+
+	if maj != cbg.MajArray {
+		return fmt.Errorf("cbor input should be of type array")
+	}
+
+	if extra != 2 {
+		return fmt.Errorf("cbor input had wrong number of fields")
+	}
+
+	// t.Value (gpbft.ECChain) (struct)
+
+	{
+
+		b, err := cr.ReadByte()
+		if err != nil {
+			return err
+		}
+		if b != cbg.CborNull[0] {
+			if err := cr.UnreadByte(); err != nil {
+				return err
+			}
+			t.Value = new(ECChain)
+			if err := t.Value.UnmarshalCBOR(cr); err != nil {
+				return xerrors.Errorf("unmarshaling t.Value pointer: %w", err)
+			}
+		}
+
+	}
+	// t.Justification (gpbft.Justification) (struct)
+
+	{
+
+		b, err := cr.ReadByte()
+		if err != nil {
+			return err
+		}
+		if b != cbg.CborNull[0] {
+			if err := cr.UnreadByte(); err != nil {
+				return err
+			}
+			t.Justification = new(Justification)
+			if err := t.Justification.UnmarshalCBOR(cr); err != nil {
+				return xerrors.Errorf("unmarshaling t.Justification pointer: %w", err)
+			}
+		}
+
+	}
+	return nil
+}
+
+var lengthBufConvergeSnapshot = []byte{130}
+
+func (t *ConvergeSnapshot) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
+		return err
+	}
+
+	cw := cbg.NewCborWriter(w)
+
+	if _, err := cw.Write(lengthBufConvergeSnapshot); err != nil {
+		return err
+	}
+
+	// t.Senders ([]gpbft.ActorID) (slice)
+	if len(t.Senders) > 8192 {
+		return xerrors.Errorf("Slice value in field t.Senders was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajArray, uint64(len(t.Senders))); err != nil {
+		return err
+	}
+	for _, v := range t.Senders {
+
+		if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(v)); err != nil {
+			return err
+		}
+
+	}
+
+	// t.Values ([]gpbft.ConvergeValueSnapshot) (slice)
+	if len(t.Values) > 8192 {
+		return xerrors.Errorf("Slice value in field t.Values was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajArray, uint64(len(t.Values))); err != nil {
+		return err
+	}
+	for _, v := range t.Values {
+		if err := v.MarshalCBOR(cw); err != nil {
+			return err
+		}
+
+	}
+	return nil
+}
+
+func (t *ConvergeSnapshot) UnmarshalCBOR(r io.Reader) (err error) {
+	*t = ConvergeSnapshot{}
+
+	cr := cbg.NewCborReader(r)
+
+	maj, extra, err := cr.ReadHeader()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+	}()
+
+	if maj != cbg.MajArray {
+		return fmt.Errorf("cbor input should be of type array")
+	}
+
+	if extra != 2 {
+		return fmt.Errorf("cbor input had wrong number of fields")
+	}
+
+	// t.Senders ([]gpbft.ActorID) (slice)
+
+	maj, extra, err = cr.ReadHeader()
+	if err != nil {
+		return err
+	}
+
+	if extra > 8192 {
+		return fmt.Errorf("t.Senders: array too large (%d)", extra)
+	}
+
+	if maj != cbg.MajArray {
+		return fmt.Errorf("expected cbor array")
+	}
+
+	if extra > 0 {
+		t.Senders = make([]ActorID, extra)
+	}
+
+	for i := 0; i < int(extra); i++ {
+		{
+			var maj byte
+			var extra uint64
+			var err error
+			_ = maj
+			_ = extra
+			_ = err
+
+			{
+
+				maj, extra, err = cr.ReadHeader()
+				if err != nil {
+					return err
+				}
+				if maj != cbg.MajUnsignedInt {
+					return fmt.Errorf("wrong type for uint64 field")
+				}
+				t.Senders[i] = ActorID(extra)
+
+			}
+
+		}
+	}
+	// t.Values ([]gpbft.ConvergeValueSnapshot) (slice)
+
+	maj, extra, err = cr.ReadHeader()
+	if err != nil {
+		return err
+	}
+
+	if extra > 8192 {
+		return fmt.Errorf("t.Values: array too large (%d)", extra)
+	}
+
+	if maj != cbg.MajArray {
+		return fmt.Errorf("expected cbor array")
+	}
+
+	if extra > 0 {
+		t.Values = make([]ConvergeValueSnapshot, extra)
+	}
+
+	for i := 0; i < int(extra); i++ {
+		{
+			var maj byte
+			var extra uint64
+			var err error
+			_ = maj
+			_ = extra
+			_ = err
+
+			{
+
+				if err := t.Values[i].UnmarshalCBOR(cr); err != nil {
+					return xerrors.Errorf("unmarshaling t.Values[i]: %w", err)
+				}
+
+			}
+
+		}
+	}
+	return nil
+}
+
+var lengthBufConvergeValueSnapshot = []byte{131}
+
+func (t *ConvergeValueSnapshot) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
+		return err
+	}
+
+	cw := cbg.NewCborWriter(w)
+
+	if _, err := cw.Write(lengthBufConvergeValueSnapshot); err != nil {
+		return err
+	}
+
+	// t.Chain (gpbft.ECChain) (struct)
+	if err := t.Chain.MarshalCBOR(cw); err != nil {
+		return err
+	}
+
+	// t.Justification (gpbft.Justification) (struct)
+	if err := t.Justification.MarshalCBOR(cw); err != nil {
+		return err
+	}
+
+	// t.Rank (uint64) (uint64)
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.Rank)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (t *ConvergeValueSnapshot) UnmarshalCBOR(r io.Reader) (err error) {
+	*t = ConvergeValueSnapshot{}
+
+	cr := cbg.NewCborReader(r)
+
+	maj, extra, err := cr.ReadHeader()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+	}()
+
+	if maj != cbg.MajArray {
+		return fmt.Errorf("cbor input should be of type array")
+	}
+
+	if extra != 3 {
+		return fmt.Errorf("cbor input had wrong number of fields")
+	}
+
+	// t.Chain (gpbft.ECChain) (struct)
+
+	{
+
+		b, err := cr.ReadByte()
+		if err != nil {
+			return err
+		}
+		if b != cbg.CborNull[0] {
+			if err := cr.UnreadByte(); err != nil {
+				return err
+			}
+			t.Chain = new(ECChain)
+			if err := t.Chain.UnmarshalCBOR(cr); err != nil {
+				return xerrors.Errorf("unmarshaling t.Chain pointer: %w", err)
+			}
+		}
+
+	}
+	// t.Justification (gpbft.Justification) (struct)
+
+	{
+
+		b, err := cr.ReadByte()
+		if err != nil {
+			return err
+		}
+		if b != cbg.CborNull[0] {
+			if err := cr.UnreadByte(); err != nil {
+				return err
+			}
+			t.Justification = new(Justification)
+			if err := t.Justification.UnmarshalCBOR(cr); err != nil {
+				return xerrors.Errorf("unmarshaling t.Justification pointer: %w", err)
+			}
+		}
+
+	}
+	// t.Rank (uint64) (uint64)
+
+	{
+
+		maj, extra, err = cr.ReadHeader()
+		if err != nil {
+			return err
+		}
+		if maj != cbg.MajUnsignedInt {
+			return fmt.Errorf("wrong type for uint64 field")
+		}
+		t.Rank = uint64(extra)
+
+	}
+	return nil
+}
@@ -39,6 +39,13 @@ var (
 	ErrReceivedAfterTermination = errors.New("received message after terminating")
 	// ErrReceivedInternalError signals that an error has occurred during message processing.
 	ErrReceivedInternalError = errors.New("error processing message")
+
+	// ErrNoInstanceInProgress signals that there is no instance in progress whose state can be
+	// exported.
+	ErrNoInstanceInProgress = errors.New("no instance in progress")
+	// ErrStateNotImportable signals that an InstanceState cannot be imported because it is not of
+	// the instance the participant is about to begin.
+	ErrStateNotImportable = errors.New("instance state not importable")
 )
 
 // ValidationError signals that an error has occurred while validating a GMessage.
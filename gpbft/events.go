@@ -0,0 +1,101 @@
+package gpbft
+
+import "time"
+
+var (
+	_ Event = PhaseStarted{}
+	_ Event = QuorumReached{}
+	_ Event = RoundSkipped{}
+	_ Event = CandidateAdded{}
+	_ Event = Decided{}
+	_ Event = RebroadcastTriggered{}
+	_ Event = MessageDropped{}
+)
+
+// Event is a typed record of a logical state change of a Participant, delivered to the EventSink
+// set by WithEventSink. Events are one of the types declared in this file.
+type Event interface {
+	// EventInstant returns the instance, round and phase at which the event occurred.
+	EventInstant() Instant
+}
+
+// PhaseStarted is emitted when the participant begins a phase, after broadcasting its message for
+// the phase.
+type PhaseStarted struct {
+	Instant
+	Proposal *ECChain
+	Value    *ECChain
+	// The time at which the phase times out, or zero if it has no timeout.
+	Timeout time.Time
+}
+
+// QuorumReached is emitted when the participant observes a strong quorum of messages for a value,
+// which may be bottom, that lets it complete the phase of the instant.
+type QuorumReached struct {
+	Instant
+	Value *ECChain
+}
+
+// RoundSkipped is emitted when the participant skips ahead from the round of the instant to a
+// future round, having seen a weak quorum of PREPARE messages for it.
+type RoundSkipped struct {
+	Instant
+	ToRound uint64
+	// The proposal carried into the future round.
+	Proposal *ECChain
+}
+
+// CandidateAdded is emitted when a chain becomes acceptable for the participant to vote for.
+type CandidateAdded struct {
+	Instant
+	Candidate *ECChain
+}
+
+// Decided is emitted when the instance terminates with a decision justified by a strong quorum
+// of DECIDE messages.
+type Decided struct {
+	Instant
+	Decision *Justification
+}
+
+// RebroadcastTriggered is emitted when the participant rebroadcasts its messages because the
+// current phase has not completed in time.
+type RebroadcastTriggered struct {
+	Instant
+	// The number of rebroadcasts triggered before this one in the current phase.
+	Attempt int
+}
+
+// DropReason is the reason a message was dropped without affecting the participant.
+type DropReason string
+
+const (
+	// DropOldInstance indicates that the message is for an instance before the current one.
+	DropOldInstance DropReason = "old-instance"
+	// DropPriorRound indicates that the message is a CONVERGE or PREPARE for a round before
+	// the current one.
+	DropPriorRound DropReason = "prior-round"
+	// DropBeyondLookahead indicates that the message is unjustified and for a round beyond
+	// the maximum lookahead rounds.
+	DropBeyondLookahead DropReason = "beyond-lookahead"
+	// DropTerminated indicates that the instance has already terminated.
+	DropTerminated DropReason = "terminated"
+	// DropInvalid indicates that the message failed the validation that could only be done
+	// once its instance started.
+	DropInvalid DropReason = "invalid"
+)
+
+// MessageDropped is emitted when a message received by the participant is dropped.
+type MessageDropped struct {
+	Instant
+	Message *GMessage
+	Reason  DropReason
+}
+
+func (e PhaseStarted) EventInstant() Instant         { return e.Instant }
+func (e QuorumReached) EventInstant() Instant        { return e.Instant }
+func (e RoundSkipped) EventInstant() Instant         { return e.Instant }
+func (e CandidateAdded) EventInstant() Instant       { return e.Instant }
+func (e Decided) EventInstant() Instant              { return e.Instant }
+func (e RebroadcastTriggered) EventInstant() Instant { return e.Instant }
+func (e MessageDropped) EventInstant() Instant       { return e.Instant }
@@ -58,8 +58,8 @@ type instance struct {
 	// instance. This includes the base chain, all prefixes of proposal that found a
 	// strong quorum of support in the QUALITY phase or late arriving quality
 	// messages, including any chains that could possibly have been decided by
-	// another participant.
-	candidates map[ECChainKey]struct{}
+	// another participant. Each candidate is kept along with its key.
+	candidates map[ECChainKey]*ECChain
 	// The final termination value of the instance, for communication to the participant.
 	// This field is an alternative to plumbing an optional decision value out through
 	// all the method calls, or holding a callback handle to receive it here.
@@ -114,8 +114,8 @@ func newInstance(
 		supplementalData: data,
 		proposal:         input,
 		value:            &ECChain{},
-		candidates: map[ECChainKey]struct{}{
-			input.BaseChain().Key(): {},
+		candidates: map[ECChainKey]*ECChain{
+			input.BaseChain().Key(): input.BaseChain(),
 		},
 		quality: newQuorumState(powerTable, attrQualityPhase, attrKeyRound.Int(0)),
 		rounds: map[uint64]*roundState{
@@ -178,6 +178,7 @@ func (i *instance) ReceiveMany(msgs []*GMessage) error {
 			if errors.As(err, &ValidationError{}) {
 				// Drop late-binding validation errors.
 				i.log("dropping invalid message: %s", err)
+				i.emitDropped(msg, DropInvalid)
 			} else {
 				return err
 			}
@@ -228,12 +229,14 @@ func (i *instance) receiveOne(msg *GMessage) (bool, error) {
 	}
 
 	if i.current.Phase == TERMINATED_PHASE {
+		i.emitDropped(msg, DropTerminated)
 		return false, nil // No-op
 	}
 	// Ignore CONVERGE and PREPARE messages for prior rounds.
 	forPriorRound := msg.Vote.Round < i.current.Round
 	if (forPriorRound && msg.Vote.Phase == CONVERGE_PHASE) ||
 		(forPriorRound && msg.Vote.Phase == PREPARE_PHASE) {
+		i.emitDropped(msg, DropPriorRound)
 		return false, nil
 	}
 
@@ -242,6 +245,7 @@ func (i *instance) receiveOne(msg *GMessage) (bool, error) {
 	//  * carry no justification, i.e. are spammable.
 	beyondMaxLookaheadRounds := msg.Vote.Round > i.current.Round+i.participant.maxLookaheadRounds
 	if beyondMaxLookaheadRounds && isSpammable(msg) {
+		i.emitDropped(msg, DropBeyondLookahead)
 		return false, nil
 	}
 
@@ -390,6 +394,7 @@ func (i *instance) beginQuality() error {
 	i.resetRebroadcastParams()
 	i.broadcast(i.current.Round, QUALITY_PHASE, i.proposal, false, nil)
 	i.reportPhaseMetrics()
+	i.emitPhaseStarted(i.phaseTimeout)
 	return nil
 }
 
@@ -404,6 +409,9 @@ func (i *instance) tryQuality() error {
 	foundQuorum := i.quality.HasStrongQuorumFor(i.proposal.Key())
 	timeoutExpired := i.phaseTimeoutElapsed()
 
+	if foundQuorum {
+		i.emit(QuorumReached{Instant: i.current.Instant, Value: i.proposal})
+	}
 	if foundQuorum || timeoutExpired {
 		// If strong quorum of input is found the proposal will remain unchanged.
 		// Otherwise, change the proposal to the longest prefix of input with strong
@@ -450,6 +458,7 @@ func (i *instance) beginConverge(justification *Justification) {
 
 	i.broadcast(i.current.Round, CONVERGE_PHASE, i.proposal, true, justification)
 	i.reportPhaseMetrics()
+	i.emitPhaseStarted(i.phaseTimeout)
 }
 
 // Attempts to end the CONVERGE phase and begin PREPARE based on current state.
@@ -510,6 +519,7 @@ func (i *instance) beginPrepare(justification *Justification) {
 
 	i.broadcast(i.current.Round, PREPARE_PHASE, i.value, false, justification)
 	i.reportPhaseMetrics()
+	i.emitPhaseStarted(i.phaseTimeout)
 }
 
 // Attempts to end the PREPARE phase and begin COMMIT based on current state.
@@ -535,6 +545,10 @@ func (i *instance) tryPrepare() error {
 		nextRound.converged.HasJustificationOf(PREPARE_PHASE, proposalKey)
 
 	if foundQuorum || foundJustification {
+		// A justification only tells us others observed the quorum.
+		if foundQuorum {
+			i.emit(QuorumReached{Instant: i.current.Instant, Value: i.proposal})
+		}
 		i.value = i.proposal
 	} else if quorumNotPossible || phaseComplete {
 		i.value = &ECChain{}
@@ -578,6 +592,7 @@ func (i *instance) beginCommit() {
 
 	i.broadcast(i.current.Round, COMMIT_PHASE, i.value, false, justification)
 	i.reportPhaseMetrics()
+	i.emitPhaseStarted(i.phaseTimeout)
 }
 
 func (i *instance) tryCommit(round uint64) error {
@@ -603,6 +618,7 @@ func (i *instance) tryCommit(round uint64) error {
 		// There is a strong quorum for a non-zero value; accept it. A participant may be
 		// forced to decide a value that's not its preferred chain. The participant isn't
 		// influencing that decision against their interest, just accepting it.
+		i.emit(QuorumReached{Instant: Instant{i.current.ID, round, COMMIT_PHASE}, Value: quorumValue})
 		i.value = quorumValue
 		i.beginDecide(round)
 	case i.current.Round != round, i.current.Phase != COMMIT_PHASE:
@@ -610,6 +626,9 @@ func (i *instance) tryCommit(round uint64) error {
 		// nothing else to do.
 	case foundStrongQuorum, foundJustificationForBottom:
 		// There is a strong quorum for bottom, carry forward the existing proposal.
+		if foundStrongQuorum {
+			i.emit(QuorumReached{Instant: i.current.Instant, Value: bottomECChain})
+		}
 		i.beginNextRound()
 	case phaseComplete:
 		// There is no strong quorum for bottom, which implies there must be a COMMIT for
@@ -659,6 +678,8 @@ func (i *instance) beginDecide(round uint64) {
 	// in order to be aggregated.
 	i.broadcast(0, DECIDE_PHASE, i.value, false, justification)
 	i.reportPhaseMetrics()
+	// The DECIDE phase has no timeout.
+	i.emitPhaseStarted(time.Time{})
 }
 
 // Skips immediately to the DECIDE phase and sends a DECIDE message
@@ -674,12 +695,14 @@ func (i *instance) skipToDecide(value *ECChain, justification *Justification) {
 
 	metrics.skipCounter.Add(context.TODO(), 1, metric.WithAttributes(attrSkipToDecide))
 	i.reportPhaseMetrics()
+	i.emitPhaseStarted(time.Time{})
 }
 
 func (i *instance) tryDecide() error {
 	quorumValue, ok := i.decision.FindStrongQuorumValue()
 	if ok {
 		if quorum, ok := i.decision.FindStrongQuorumFor(quorumValue.Key()); ok {
+			i.emit(QuorumReached{Instant: i.current.Instant, Value: quorumValue})
 			decision := i.buildJustification(quorum, 0, DECIDE_PHASE, quorumValue)
 			i.terminate(decision)
 		} else {
@@ -737,6 +760,7 @@ func (i *instance) beginNextRound() {
 // See shouldSkipToRound.
 func (i *instance) skipToRound(round uint64, chain *ECChain, justification *Justification) {
 	i.log("skipping from round %d to round %d with %s", i.current.Round, round, i.proposal.String())
+	skipped := i.current.Instant
 	i.current.Round = round
 	metrics.currentRound.Record(context.TODO(), int64(i.current.Round))
 	metrics.skipCounter.Add(context.TODO(), 1, metric.WithAttributes(attrSkipToRound))
@@ -746,6 +770,7 @@ func (i *instance) skipToRound(round uint64, chain *ECChain, justification *Just
 		i.addCandidate(chain)
 		i.proposal = chain
 	}
+	i.emit(RoundSkipped{Instant: skipped, ToRound: round, Proposal: i.proposal})
 	i.beginConverge(justification)
 }
 
@@ -767,7 +792,8 @@ func (i *instance) addCandidatePrefixes(c *ECChain) bool {
 func (i *instance) addCandidate(c *ECChain) bool {
 	key := c.Key()
 	if _, exists := i.candidates[key]; !exists {
-		i.candidates[key] = struct{}{}
+		i.candidates[key] = c
+		i.emit(CandidateAdded{Instant: i.current.Instant, Candidate: c})
 		return true
 	}
 	return false
@@ -783,6 +809,7 @@ func (i *instance) terminate(decision *Justification) {
 
 	metrics.roundHistogram.Record(context.TODO(), int64(i.current.Round))
 	i.reportPhaseMetrics()
+	i.emit(Decided{Instant: i.current.Instant, Decision: decision})
 }
 
 func (i *instance) terminated() bool {
@@ -863,6 +890,7 @@ func (i *instance) tryRebroadcast() {
 	case i.rebroadcastTimeoutElapsed():
 		// Rebroadcast now that the corresponding timeout has elapsed, and schedule the
 		// successive rebroadcast.
+		i.emit(RebroadcastTriggered{Instant: i.current.Instant, Attempt: i.rebroadcastAttempts})
 		i.rebroadcast()
 		i.rebroadcastAttempts++
 
@@ -990,6 +1018,18 @@ func (i *instance) buildJustification(quorum QuorumResult, round uint64, phase P
 	}
 }
 
+func (i *instance) emit(e Event) {
+	i.participant.emit(e)
+}
+
+func (i *instance) emitPhaseStarted(timeout time.Time) {
+	i.emit(PhaseStarted{Instant: i.current.Instant, Proposal: i.proposal, Value: i.value, Timeout: timeout})
+}
+
+func (i *instance) emitDropped(msg *GMessage, reason DropReason) {
+	i.emit(MessageDropped{Instant: i.current.Instant, Message: msg, Reason: reason})
+}
+
 func (i *instance) log(format string, args ...any) {
 	if i.tracer != nil {
 		msg := fmt.Sprintf(format, args...)
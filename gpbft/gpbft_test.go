@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"crypto/rand"
 	"encoding/json"
+	"fmt"
 	"io"
 	"testing"
 	"time"
@@ -924,6 +925,133 @@ func TestGPBFT_SoloParticipant(t *testing.T) {
 	})
 }
 
+type eventRecorder []gpbft.Event
+
+func (r *eventRecorder) ReceiveEvent(e gpbft.Event) { *r = append(*r, e) }
+
+func TestGPBFT_EventSink(t *testing.T) {
+	var events eventRecorder
+	driver := emulator.NewDriver(t, gpbft.WithEventSink(&events))
+	instance := emulator.NewInstance(t,
+		0,
+		gpbft.PowerEntries{
+			gpbft.PowerEntry{
+				ID:    0,
+				Power: gpbft.NewStoragePower(1),
+			},
+		},
+		tipset0, tipSet1, tipSet2,
+	)
+	driver.AddInstance(instance)
+	driver.RequireNoBroadcast()
+	driver.RequireStartInstance(instance.ID())
+	driver.RequireQuality()
+	driver.RequirePrepare(instance.Proposal())
+	driver.RequireCommit(
+		0,
+		instance.Proposal(),
+		instance.NewJustification(0, gpbft.PREPARE_PHASE, instance.Proposal(), 0),
+	)
+	driver.RequireDecide(
+		instance.Proposal(),
+		instance.NewJustification(0, gpbft.COMMIT_PHASE, instance.Proposal(), 0),
+	)
+	driver.RequireDecision(instance.ID(), instance.Proposal())
+
+	type summary struct {
+		event string
+		phase gpbft.Phase
+	}
+	var summaries []summary
+	for _, e := range events {
+		summaries = append(summaries, summary{fmt.Sprintf("%T", e), e.EventInstant().Phase})
+	}
+	require.Equal(t, []summary{
+		{"gpbft.PhaseStarted", gpbft.QUALITY_PHASE},
+		{"gpbft.QuorumReached", gpbft.QUALITY_PHASE},
+		{"gpbft.CandidateAdded", gpbft.QUALITY_PHASE},
+		{"gpbft.PhaseStarted", gpbft.PREPARE_PHASE},
+		{"gpbft.QuorumReached", gpbft.PREPARE_PHASE},
+		{"gpbft.PhaseStarted", gpbft.COMMIT_PHASE},
+		{"gpbft.QuorumReached", gpbft.COMMIT_PHASE},
+		{"gpbft.PhaseStarted", gpbft.DECIDE_PHASE},
+		{"gpbft.QuorumReached", gpbft.DECIDE_PHASE},
+		{"gpbft.Decided", gpbft.TERMINATED_PHASE},
+	}, summaries)
+
+	for _, e := range events {
+		switch e := e.(type) {
+		case gpbft.PhaseStarted:
+			require.True(t, instance.Proposal().Eq(e.Proposal))
+			// Only the DECIDE phase has no timeout.
+			require.Equal(t, e.Phase == gpbft.DECIDE_PHASE, e.Timeout.IsZero())
+		case gpbft.QuorumReached:
+			require.True(t, instance.Proposal().Eq(e.Value))
+		case gpbft.CandidateAdded:
+			require.True(t, instance.Proposal().Eq(e.Candidate))
+		case gpbft.Decided:
+			require.True(t, instance.Proposal().Eq(e.Decision.Vote.Value))
+		}
+	}
+}
+
+func TestGPBFT_ExportImportState(t *testing.T) {
+	newInstanceAndDriver := func(t *testing.T) (*emulator.Instance, *emulator.Driver) {
+		driver := emulator.NewDriver(t)
+		instance := emulator.NewInstance(t,
+			0,
+			gpbft.PowerEntries{
+				gpbft.PowerEntry{ID: 0, Power: gpbft.NewStoragePower(1)},
+				gpbft.PowerEntry{ID: 1, Power: gpbft.NewStoragePower(1)},
+				gpbft.PowerEntry{ID: 2, Power: gpbft.NewStoragePower(1)},
+				gpbft.PowerEntry{ID: 3, Power: gpbft.NewStoragePower(1)},
+			},
+			tipset0, tipSet1, tipSet2,
+		)
+		driver.AddInstance(instance)
+		driver.RequireNoBroadcast()
+		return instance, driver
+	}
+
+	instance, driver := newInstanceAndDriver(t)
+	_, err := driver.ExportState()
+	require.ErrorIs(t, err, gpbft.ErrNoInstanceInProgress)
+
+	driver.RequireStartInstance(instance.ID())
+	driver.RequireQuality()
+	for _, sender := range []gpbft.ActorID{0, 1, 2} {
+		driver.RequireDeliverMessage(&gpbft.GMessage{Sender: sender, Vote: instance.NewQuality(instance.Proposal())})
+	}
+	driver.RequirePrepare(instance.Proposal())
+	// Receive a single PREPARE, which along with that of self is one short of a strong quorum.
+	driver.RequireDeliverMessage(&gpbft.GMessage{Sender: 1, Vote: instance.NewPrepare(0, instance.Proposal())})
+	driver.RequireNoBroadcast()
+	state, err := driver.ExportState()
+	require.NoError(t, err)
+
+	t.Run("Resumes from state", func(t *testing.T) {
+		instance, driver := newInstanceAndDriver(t)
+		driver.RequireResumeInstance(instance.ID(), state)
+		driver.RequireNoBroadcast()
+		// The PREPARE of self, rebroadcast back to it, is not counted twice.
+		driver.RequireDeliverMessage(&gpbft.GMessage{Sender: 0, Vote: instance.NewPrepare(0, instance.Proposal())})
+		driver.RequireNoBroadcast()
+		// The PREPARE messages received before restart, including that of self, complete the
+		// quorum along with one more.
+		driver.RequireDeliverMessage(&gpbft.GMessage{Sender: 2, Vote: instance.NewPrepare(0, instance.Proposal())})
+		driver.RequireCommit(
+			0,
+			instance.Proposal(),
+			instance.NewJustification(0, gpbft.PREPARE_PHASE, instance.Proposal(), 0, 1, 2),
+		)
+	})
+	t.Run("Rejects state of other instance", func(t *testing.T) {
+		_, driver := newInstanceAndDriver(t)
+		err := driver.ResumeInstance(1, state)
+		require.ErrorIs(t, err, gpbft.ErrStateNotImportable)
+	})
+}
+
 func TestGPBFT_SkipsToRound(t *testing.T) {
 	newInstanceAndDriver := func(t *testing.T) (*emulator.Instance, *emulator.Driver) {
 		driver := emulator.NewDriver(t)
@@ -1697,7 +1825,8 @@ func TestGPBFT_DropOld(t *testing.T) {
 
 	// We define 3 instances, old, last, and new.
 
-	driver := emulator.NewDriver(t)
+	var events eventRecorder
+	driver := emulator.NewDriver(t, gpbft.WithEventSink(&events))
 	oldInstance := emulator.NewInstance(t, 0, participants, tipset0, tipSet1)
 	lastInstance := emulator.NewInstance(t, 1, participants, tipSet1, tipSet2)
 	newInstance := emulator.NewInstance(t, 2, participants, tipSet2)
@@ -1733,6 +1862,11 @@ func TestGPBFT_DropOld(t *testing.T) {
 		Ticket:        emulator.ValidTicket,
 	}
 	driver.RequireDeliverMessage(lastDecide)
+	dropped, ok := events[len(events)-1].(gpbft.MessageDropped)
+	require.True(t, ok)
+	require.Equal(t, gpbft.DropOldInstance, dropped.Reason)
+	require.Equal(t, uint64(1), dropped.Message.Vote.Instance)
+	require.Equal(t, uint64(2), dropped.ID)
 
 	// Everything should be delivered for the new instance.
 
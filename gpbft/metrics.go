@@ -57,6 +57,8 @@ var (
 		validationCache     metric.Int64Counter
 		quorumParticipation metric.Float64Gauge
 		totalPower          metric.Float64Gauge
+		verifyBatchSize     metric.Int64Histogram
+		verifyBatchFallback metric.Int64Counter
 	}{
 		phaseCounter: measurements.Must(meter.Int64Counter("f3_gpbft_phase_counter", metric.WithDescription("Number of times phases change"))),
 		roundHistogram: measurements.Must(meter.Int64Histogram("f3_gpbft_round_histogram",
@@ -79,6 +81,12 @@ var (
 			metric.WithDescription("The current ratio of participation at a given round and phase (converge not tracked)."))),
 		totalPower: measurements.Must(meter.Float64Gauge("f3_gpbft_total_power",
 			metric.WithDescription("The size of the power table as observed by gpbft."))),
+		verifyBatchSize: measurements.Must(meter.Int64Histogram("f3_gpbft_verify_batch_size",
+			metric.WithDescription("The number of signatures verified together in a batch."),
+			metric.WithExplicitBucketBoundaries(1, 2, 4, 8, 16, 32, 64, 128, 256),
+		)),
+		verifyBatchFallback: measurements.Must(meter.Int64Counter("f3_gpbft_verify_batch_fallback",
+			metric.WithDescription("The number of batches whose signatures were verified one by one after the batch failed verification."))),
 	}
 )
 
@@ -14,6 +14,7 @@ const (
 	defaultMaxCachedInstances           = 10
 	defaultMaxCachedMessagesPerInstance = 25_000
 	defaultCommitteeLookback            = 10
+	defaultBatchValidationWorkers       = 4
 )
 
 // Option represents a configurable parameter.
@@ -33,8 +34,15 @@ type options struct {
 	maxCachedInstances           int
 	maxCachedMessagesPerInstance int
 
+	// batchValidationWindow is the time for which signatures are collected before being
+	// verified in a batch, or zero if signatures are verified as messages are validated.
+	batchValidationWindow  time.Duration
+	batchValidationWorkers int
+
 	// tracer traces logic logs for debugging and simulation purposes.
 	tracer Tracer
+	// eventSink receives typed events about logical state changes.
+	eventSink EventSink
 }
 
 func newOptions(o ...Option) (*options, error) {
@@ -106,6 +114,16 @@ func WithTracer(t Tracer) Option {
 	}
 }
 
+// WithEventSink sets the EventSink for this gPBFT instance, which receives typed
+// events about the state mutation, such as phases starting, quorums being reached
+// and messages being dropped. Defaults to no sink if unspecified.
+func WithEventSink(s EventSink) Option {
+	return func(o *options) error {
+		o.eventSink = s
+		return nil
+	}
+}
+
 // WithMaxLookaheadRounds sets the maximum number of rounds ahead of the current
 // round for which messages without justification are buffered. Setting a max
 // value of larger than zero would aid gPBFT to potentially reach consensus in
@@ -156,6 +174,33 @@ func WithRebroadcastImmediatelyAfterRound(round uint64) Option {
 	}
 }
 
+// WithBatchValidation enables the batching mode of message validation, where the signatures
+// of the messages validated concurrently are collected for the given window of time, typically
+// a few milliseconds, and then verified together by a pool of the given number of workers, along
+// with the aggregate signatures of their justifications if the Aggregate implements
+// BatchAggregate. If the Verifier implements BatchVerifier, each batch is verified at once with
+// VerifyBatch, falling back on verifying its signatures one at a time if that fails. Validation
+// of each message blocks until its batch is verified.
+//
+// Defaults to verifying signatures as messages are validated if unset. The number of workers
+// defaults to 4 if zero.
+func WithBatchValidation(window time.Duration, workers int) Option {
+	return func(o *options) error {
+		if window <= 0 {
+			return fmt.Errorf("batch validation window must be greater than zero; got: %s", window)
+		}
+		if workers < 0 {
+			return fmt.Errorf("batch validation workers cannot be negative; got: %d", workers)
+		}
+		if workers == 0 {
+			workers = defaultBatchValidationWorkers
+		}
+		o.batchValidationWindow = window
+		o.batchValidationWorkers = workers
+		return nil
+	}
+}
+
 var defaultRebroadcastAfter = exponentialBackoffer(1.3, 0.1, 3*time.Second, 30*time.Second)
 
 // WithRebroadcastBackoff sets the duration after the gPBFT timeout has elapsed, at
@@ -1,6 +1,7 @@
 package gpbft
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -59,6 +60,10 @@ func NewParticipant(host Host, o ...Option) (*Participant, error) {
 	ccp := newCachedCommitteeProvider(host)
 	messageCache := caching.NewGroupedSet(opts.maxCachedInstances, opts.maxCachedMessagesPerInstance)
 	progression := newAtomicProgression()
+	var verifier Verifier = host
+	if opts.batchValidationWindow > 0 {
+		verifier = newBatchVerifier(host, opts.batchValidationWindow, opts.batchValidationWorkers)
+	}
 	return &Participant{
 		options:           opts,
 		host:              host,
@@ -66,7 +71,7 @@ func NewParticipant(host Host, o ...Option) (*Participant, error) {
 		mqueue:            newMessageQueue(opts.maxLookaheadRounds),
 		messageCache:      messageCache,
 		progression:       progression,
-		validator:         newValidator(host.NetworkName(), host, ccp, progression.Get, messageCache, opts.committeeLookback),
+		validator:         newValidator(host.NetworkName(), verifier, ccp, progression.Get, messageCache, opts.committeeLookback),
 	}, nil
 }
 
@@ -103,6 +108,90 @@ func (p *Participant) Progress() InstanceProgress {
 	return p.progression.Get()
 }
 
+// ExportState exports the state of the instance in progress, encoded as an InstanceState in
+// CBOR. ErrNoInstanceInProgress is returned if there is no such instance, e.g. because the
+// participant is waiting to begin the next one.
+func (p *Participant) ExportState() (_ []byte, err error) {
+	if !p.apiMutex.TryLock() {
+		panic("concurrent API method invocation")
+	}
+	defer p.apiMutex.Unlock()
+	defer func() {
+		if r := recover(); r != nil {
+			err = newPanicError(r)
+		}
+	}()
+
+	if p.gpbft == nil || p.terminated() {
+		return nil, ErrNoInstanceInProgress
+	}
+	var buf bytes.Buffer
+	if err := p.gpbft.snapshot().MarshalCBOR(&buf); err != nil {
+		return nil, fmt.Errorf("encoding instance state: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// ImportState resumes an instance from a state previously returned by ExportState, instead of
+// beginning it afresh. The participant must have been told to start the instance with
+// StartInstanceAt, and not begun it yet; ErrStateNotImportable is returned otherwise.
+//
+// Messages received for the instance before it is resumed are delivered to it once restored.
+func (p *Participant) ImportState(ctx context.Context, state []byte) (err error) {
+	if !p.apiMutex.TryLock() {
+		panic("concurrent API method invocation")
+	}
+	defer p.apiMutex.Unlock()
+	defer func() {
+		if r := recover(); r != nil {
+			err = newPanicError(r)
+		}
+		if err != nil {
+			metrics.errorCounter.Add(ctx, 1, metric.WithAttributes(metricAttributeFromError(err)))
+		}
+	}()
+
+	var snapshot InstanceState
+	if err := snapshot.UnmarshalCBOR(bytes.NewReader(state)); err != nil {
+		return fmt.Errorf("decoding instance state: %w", err)
+	}
+	switch currentInstance := p.Progress().ID; {
+	case snapshot.Instance != currentInstance:
+		return fmt.Errorf("%w: state of instance %d, expected %d", ErrStateNotImportable, snapshot.Instance, currentInstance)
+	case p.gpbft != nil:
+		return fmt.Errorf("%w: instance %d has already begun", ErrStateNotImportable, currentInstance)
+	}
+
+	comt, err := p.committeeProvider.GetCommittee(ctx, snapshot.Instance)
+	if err != nil {
+		return err
+	}
+	instance, err := newInstance(p, snapshot.Instance, snapshot.Input, snapshot.SupplementalData, comt.PowerTable, comt.AggregateVerifier, comt.Beacon)
+	if err != nil {
+		return fmt.Errorf("failed creating gpbft instance: %w", err)
+	}
+	if err := instance.restore(&snapshot); err != nil {
+		return fmt.Errorf("failed restoring gpbft instance: %w", err)
+	}
+	p.gpbft = instance
+	p.progression.NotifyProgress(p.gpbft.current)
+	p.trace("Restored {%d}, round %d, phase %s", snapshot.Instance, snapshot.Round, snapshot.Phase)
+
+	// Try the restored phase once it times out, or right away if it has no timeout, unless the
+	// queued messages make progress first.
+	if p.gpbft.phaseTimeout.IsZero() {
+		p.host.SetAlarm(p.host.Time())
+	} else {
+		p.host.SetAlarm(p.gpbft.phaseTimeout)
+	}
+	queued := p.mqueue.Drain(p.gpbft.current.ID)
+	if err := p.gpbft.ReceiveMany(queued); err != nil {
+		return fmt.Errorf("delivering queued messages: %w", err)
+	}
+	p.handleDecision(ctx)
+	return nil
+}
+
 // ValidateMessage checks if the given message is valid. If invalid, an error is
 // returned. ErrValidationInvalid indicates that the message will never be valid
 // invalid and may be safely dropped.
@@ -164,11 +253,13 @@ func (p *Participant) ReceiveMessage(ctx context.Context, vmsg ValidatedMessage)
 	}()
 	msg := vmsg.Message()
 
-	currentInstance := p.Progress().ID
+	progress := p.Progress()
+	currentInstance := progress.ID
 	// Drop messages for past instances.
 	if msg.Vote.Instance < currentInstance {
 		p.trace("dropping message from old instance %d while received in instance %d",
 			msg.Vote.Instance, currentInstance)
+		p.emit(MessageDropped{Instant: progress.Instant, Message: msg, Reason: DropOldInstance})
 		return nil
 	}
 
@@ -315,6 +406,12 @@ func (p *Participant) trace(format string, args ...any) {
 	}
 }
 
+func (p *Participant) emit(e Event) {
+	if p.eventSink != nil {
+		p.eventSink.ReceiveEvent(e)
+	}
+}
+
 // A collection of messages queued for delivery for a future instance.
 // The queue drops equivocations and unjustified messages beyond some round number.
 type messageQueue struct {
@@ -0,0 +1,244 @@
+package gpbft
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"time"
+)
+
+// InstanceState is a snapshot of the state of the instance a Participant is progressing, as
+// exported by Participant.ExportState. It holds everything the participant learnt from the
+// messages it received in the instance, so that a restarted participant can resume the instance
+// from where it was without waiting for those messages to be rebroadcast.
+type InstanceState struct {
+	Instance uint64
+	Round    uint64
+	Phase    Phase
+	// The time at which the current phase times out, in nanoseconds since the Unix epoch, or
+	// zero if the phase has no timeout.
+	PhaseTimeout int64
+
+	Input            *ECChain
+	SupplementalData *SupplementalData
+	Proposal         *ECChain
+	Value            *ECChain
+	Candidates       []*ECChain
+
+	Quality  QuorumSnapshot
+	Rounds   []RoundSnapshot
+	Decision QuorumSnapshot
+}
+
+// RoundSnapshot is the state of a single round of an InstanceState.
+type RoundSnapshot struct {
+	Round     uint64
+	Converged ConvergeSnapshot
+	Prepared  QuorumSnapshot
+	Committed QuorumSnapshot
+}
+
+// QuorumSnapshot holds the votes received in a phase, and the justifications received along with
+// them.
+type QuorumSnapshot struct {
+	// The votes, one per sender, in ascending order of sender.
+	Votes          []SenderVote
+	Justifications []ReceivedJustification
+}
+
+// SenderVote is the value voted for by a sender, along with its signature if kept. The value of
+// a QUALITY vote is the longest chain voted for, and is nil if the sender voted for the base
+// chain alone.
+type SenderVote struct {
+	Sender    ActorID
+	Value     *ECChain
+	Signature []byte
+}
+
+// ReceivedJustification is a justification received along with a vote for a value.
+type ReceivedJustification struct {
+	Value         *ECChain
+	Justification *Justification
+}
+
+// ConvergeSnapshot holds the CONVERGE messages received in a round.
+type ConvergeSnapshot struct {
+	// The senders of the messages, in ascending order.
+	Senders []ActorID
+	Values  []ConvergeValueSnapshot
+}
+
+// ConvergeValueSnapshot is a ConvergeValue, with its rank encoded as the IEEE 754 binary
+// representation of the float.
+type ConvergeValueSnapshot struct {
+	Chain         *ECChain
+	Justification *Justification
+	Rank          uint64
+}
+
+func (i *instance) snapshot() *InstanceState {
+	state := &InstanceState{
+		Instance:         i.current.ID,
+		Round:            i.current.Round,
+		Phase:            i.current.Phase,
+		Input:            i.input,
+		SupplementalData: i.supplementalData,
+		Proposal:         i.proposal,
+		Value:            i.value,
+		Quality:          i.quality.snapshot(),
+		Decision:         i.decision.snapshot(),
+	}
+	if !i.phaseTimeout.IsZero() {
+		state.PhaseTimeout = i.phaseTimeout.UnixNano()
+	}
+	for _, candidate := range i.candidates {
+		state.Candidates = append(state.Candidates, candidate)
+	}
+	slices.SortFunc(state.Candidates, func(one, other *ECChain) int { return one.Len() - other.Len() })
+	rounds := make([]uint64, 0, len(i.rounds))
+	for r := range i.rounds {
+		rounds = append(rounds, r)
+	}
+	slices.Sort(rounds)
+	for _, r := range rounds {
+		round := i.rounds[r]
+		state.Rounds = append(state.Rounds, RoundSnapshot{
+			Round:     r,
+			Converged: round.converged.snapshot(),
+			Prepared:  round.prepared.snapshot(),
+			Committed: round.committed.snapshot(),
+		})
+	}
+	return state
+}
+
+// restore sets the state of a newly created instance to the given snapshot of the same instance.
+func (i *instance) restore(state *InstanceState) error {
+	if state.Phase < QUALITY_PHASE || state.Phase > DECIDE_PHASE {
+		return fmt.Errorf("cannot restore instance state at phase %s", state.Phase)
+	}
+	if state.Proposal.IsZero() {
+		return fmt.Errorf("cannot restore instance state with empty proposal")
+	}
+	i.current.Round = state.Round
+	i.current.Phase = state.Phase
+	if state.PhaseTimeout != 0 {
+		i.phaseTimeout = time.Unix(0, state.PhaseTimeout)
+	}
+	i.proposal = state.Proposal
+	i.value = state.Value
+	if i.value == nil {
+		i.value = &ECChain{}
+	}
+	for _, candidate := range state.Candidates {
+		i.candidates[candidate.Key()] = candidate
+	}
+	i.quality.restore(&state.Quality, true)
+	i.decision.restore(&state.Decision, false)
+	for _, snapshot := range state.Rounds {
+		round := i.getRound(snapshot.Round)
+		round.converged.restore(&snapshot.Converged, i.powerTable)
+		round.prepared.restore(&snapshot.Prepared, false)
+		round.committed.restore(&snapshot.Committed, false)
+	}
+	return nil
+}
+
+func (q *quorumState) snapshot() QuorumSnapshot {
+	var snapshot QuorumSnapshot
+	// A sender votes for a single chain, except in QUALITY where they vote for each prefix of
+	// it. Either way, the longest chain voted for is the vote of the sender.
+	votes := make(map[ActorID]SenderVote, len(q.senders))
+	for sender := range q.senders {
+		votes[sender] = SenderVote{Sender: sender}
+	}
+	for _, support := range q.chainSupport {
+		for sender, signature := range support.signatures {
+			if vote := votes[sender]; vote.Value == nil || vote.Value.Len() < support.chain.Len() {
+				votes[sender] = SenderVote{Sender: sender, Value: support.chain, Signature: signature}
+			}
+		}
+	}
+	for _, vote := range votes {
+		snapshot.Votes = append(snapshot.Votes, vote)
+	}
+	slices.SortFunc(snapshot.Votes, func(one, other SenderVote) int {
+		switch {
+		case one.Sender < other.Sender:
+			return -1
+		case one.Sender > other.Sender:
+			return 1
+		default:
+			return 0
+		}
+	})
+	for key, justification := range q.receivedJustification {
+		// Justifications are received along with a vote for the value they are keyed by, which
+		// is therefore supported by some sender unless it came from an equivocating one.
+		if support, found := q.chainSupport[key]; found {
+			snapshot.Justifications = append(snapshot.Justifications, ReceivedJustification{
+				Value:         support.chain,
+				Justification: justification,
+			})
+		}
+	}
+	return snapshot
+}
+
+// restore receives the votes and justifications of a snapshot. Votes are received for each prefix
+// of their value if eachPrefix is set, as done in QUALITY.
+func (q *quorumState) restore(snapshot *QuorumSnapshot, eachPrefix bool) {
+	for _, vote := range snapshot.Votes {
+		value := vote.Value
+		if value == nil {
+			value = &ECChain{}
+		}
+		if eachPrefix {
+			q.ReceiveEachPrefix(vote.Sender, value)
+		} else {
+			q.Receive(vote.Sender, value, vote.Signature)
+		}
+	}
+	for _, received := range snapshot.Justifications {
+		if received.Justification != nil {
+			q.ReceiveJustification(received.Value, received.Justification)
+		}
+	}
+}
+
+func (c *convergeState) snapshot() ConvergeSnapshot {
+	var snapshot ConvergeSnapshot
+	for sender := range c.senders {
+		snapshot.Senders = append(snapshot.Senders, sender)
+	}
+	slices.Sort(snapshot.Senders)
+	for _, value := range c.values {
+		snapshot.Values = append(snapshot.Values, ConvergeValueSnapshot{
+			Chain:         value.Chain,
+			Justification: value.Justification,
+			Rank:          math.Float64bits(value.Rank),
+		})
+	}
+	return snapshot
+}
+
+func (c *convergeState) restore(snapshot *ConvergeSnapshot, table *PowerTable) {
+	for _, sender := range snapshot.Senders {
+		if _, found := c.senders[sender]; found {
+			continue
+		}
+		c.senders[sender] = struct{}{}
+		power, _ := table.Get(sender)
+		c.sendersTotalPower += power
+	}
+	for _, value := range snapshot.Values {
+		if value.Chain.IsZero() || value.Justification == nil {
+			continue
+		}
+		c.values[value.Chain.Key()] = ConvergeValue{
+			Chain:         value.Chain,
+			Justification: value.Justification,
+			Rank:          math.Float64frombits(value.Rank),
+		}
+	}
+}
@@ -462,13 +462,22 @@ func (v *cachingValidator) validateJustificationSignature(comt *Committee, justi
 	}
 
 	payload := justif.Vote.MarshalForSigningWithValueKey(v.networkName, expectedVoteValueKey)
-	if err := comt.AggregateVerifier.VerifyAggregate(signers, payload, justif.Signature); err != nil {
+	if err := v.verifyAggregate(comt.AggregateVerifier, signers, payload, justif.Signature); err != nil {
 		return fmt.Errorf("verification of the aggregate failed: %+v: %w", justif, err)
 	}
 
 	return nil
 }
 
+// verifyAggregate verifies the aggregate signature, in a batch along with other signatures if
+// batch validation is enabled.
+func (v *cachingValidator) verifyAggregate(agg Aggregate, signers []int, payload, aggSig []byte) error {
+	if batch, ok := v.verifier.(*batchVerifier); ok {
+		return batch.VerifyAggregate(agg, signers, payload, aggSig)
+	}
+	return agg.VerifyAggregate(signers, payload, aggSig)
+}
+
 func (v *cachingValidator) isAlreadyValidated(group uint64, namespace validatorNamespace, cacheKey []byte) (bool, error) {
 	alreadyValidated, err := v.cache.Contains(group, namespace, cacheKey)
 	if err != nil {
@@ -1,6 +1,7 @@
 package f3
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -18,6 +19,8 @@ import (
 	"github.com/filecoin-project/go-f3/internal/writeaheadlog"
 	"github.com/filecoin-project/go-f3/manifest"
 	"github.com/filecoin-project/go-f3/pmsg"
+	"github.com/filecoin-project/go-f3/recording"
+	"github.com/ipfs/go-datastore"
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
 	"github.com/libp2p/go-libp2p/core/peer"
 	"go.opentelemetry.io/otel/metric"
@@ -37,9 +40,28 @@ type gpbftRunner struct {
 	clock       clock.Clock
 	verifier    gpbft.Verifier
 	wal         *writeaheadlog.WriteAheadLog[walEntry, *walEntry]
+	recorder    *recording.Recorder
+	stateStore  datastore.Datastore
 	outMessages chan<- *gpbft.MessageBuilder
 	equivFilter equivocationFilter
 
+	// localActors, if set, are the actors on behalf of which messages are signed by the runner
+	// rather than sent to outMessages.
+	localActors   *localActors
+	localBuilders chan *gpbft.MessageBuilder
+	// localMessages queues the messages signed on behalf of the local actors until the runner
+	// loop, signalled on localMessagesReady, delivers them to the participant. The queue is
+	// unbounded so that signing never waits on the runner loop, which may itself be waiting to
+	// hand over the next message to sign.
+	localMessagesMu    sync.Mutex
+	localMessages      []*gpbft.GMessage
+	localMessagesReady chan struct{}
+	// locallyVerified holds the signatures made on behalf of the local actors that were verified
+	// at once by aggregating them as they were signed, keyed by locallyVerifiedKey, along with
+	// the instance they were made in. Verifying them again is skipped.
+	locallyVerifiedMu sync.RWMutex
+	locallyVerified   map[[32]byte]uint64
+
 	participant *gpbft.Participant
 	topic       *pubsub.Topic
 
@@ -58,6 +80,14 @@ type gpbftRunner struct {
 	pmm         *pmsg.PartialMessageManager
 }
 
+// persistStateInterval is the interval at which the state of the instance in progress is
+// persisted, to be restored on restart.
+const persistStateInterval = 10 * time.Second
+
+// instanceStateKeyPrefix is the prefix of the key under which the state of the instance in
+// progress is persisted, which is followed by the network name.
+var instanceStateKeyPrefix = datastore.NewKey("/instance-state")
+
 type roundPhase struct {
 	round uint64
 	phase gpbft.Phase
@@ -72,9 +102,13 @@ func newRunner(
 	ps *pubsub.PubSub,
 	verifier gpbft.Verifier,
 	out chan<- *gpbft.MessageBuilder,
+	local *localActors,
 	m manifest.Manifest,
 	wal *writeaheadlog.WriteAheadLog[walEntry, *walEntry],
+	recorder *recording.Recorder,
+	stateStore datastore.Datastore,
 	pID peer.ID,
+	gpbftOpts ...gpbft.Option,
 ) (*gpbftRunner, error) {
 	runningCtx, ctxCancel := context.WithCancel(context.WithoutCancel(ctx))
 	errgrp, runningCtx := errgroup.WithContext(runningCtx)
@@ -87,15 +121,24 @@ func newRunner(
 		clock:        clock.GetClock(ctx),
 		verifier:     verifier,
 		wal:          wal,
+		recorder:     recorder,
+		stateStore:   stateStore,
 		outMessages:  out,
 		runningCtx:   runningCtx,
 		errgrp:       errgrp,
 		ctxCancel:    ctxCancel,
-		equivFilter:  newEquivocationFilter(pID),
+		equivFilter:  newEquivocationFilter(pID, local.actorIDs()...),
+		localActors:  local,
 		selfMessages: make(map[uint64]map[roundPhase][]*gpbft.GMessage),
 		inputs:       newInputs(m, cs, ec, verifier, clock.GetClock(ctx)),
 	}
 
+	if local != nil {
+		runner.localBuilders = make(chan *gpbft.MessageBuilder, cap(out))
+		runner.localMessagesReady = make(chan struct{}, 1)
+		runner.locallyVerified = make(map[[32]byte]uint64)
+	}
+
 	// create a stopped timer to facilitate alerts requested from gpbft
 	runner.alertTimer = runner.clock.Timer(0)
 	if !runner.alertTimer.Stop() {
@@ -132,7 +175,8 @@ func newRunner(
 	}
 
 	log.Infof("Starting gpbft runner")
-	opts := append(m.GpbftOptions(), gpbft.WithTracer(tracer))
+	opts := append(m.GpbftOptions(), gpbftOpts...)
+	opts = append(opts, gpbft.WithTracer(tracer))
 	p, err := gpbft.NewParticipant((*gpbftHost)(runner), opts...)
 	if err != nil {
 		return nil, fmt.Errorf("creating participant: %w", err)
@@ -183,9 +227,18 @@ func (h *gpbftRunner) Start(ctx context.Context) (_err error) {
 			log.Errorf("error when starting instance %d: %+v", h.manifest.InitialInstance, err)
 		}
 	}
+	// Resume the instance from where it was before restart, if its state was persisted.
+	h.restoreState(ctx)
+
+	if h.localActors != nil {
+		h.errgrp.Go(h.signLocally)
+	}
 
 	h.errgrp.Go(func() (_err error) {
+		persistState := h.clock.Ticker(persistStateInterval)
 		defer func() {
+			persistState.Stop()
+			h.persistState(context.WithoutCancel(h.runningCtx))
 			unsubCerts()
 			if _err != nil && h.runningCtx.Err() == nil {
 				log.Errorf("exited GPBFT runner early: %+v", _err)
@@ -200,7 +253,7 @@ func (h *gpbftRunner) Start(ctx context.Context) (_err error) {
 				}
 				continue
 			case <-h.alertTimer.C:
-				if err := h.participant.ReceiveAlarm(h.runningCtx); err != nil {
+				if err := h.receiveAlarm(h.runningCtx); err != nil {
 					// TODO: Probably want to just abort the instance and wait
 					// for a finality certificate at this point?
 					log.Errorf("error when receiving alarm: %+v", err)
@@ -216,7 +269,7 @@ func (h *gpbftRunner) Start(ctx context.Context) (_err error) {
 					log.Errorf("error when recieving certificate: %+v", err)
 				}
 			case <-h.alertTimer.C:
-				if err := h.participant.ReceiveAlarm(h.runningCtx); err != nil {
+				if err := h.receiveAlarm(h.runningCtx); err != nil {
 					// TODO: Probably want to just abort the instance and wait
 					// for a finality certificate at this point?
 					log.Errorf("error when receiving alarm: %+v", err)
@@ -225,7 +278,7 @@ func (h *gpbftRunner) Start(ctx context.Context) (_err error) {
 				if !ok {
 					return fmt.Errorf("incoming message queue closed")
 				}
-				if err := h.participant.ReceiveMessage(h.runningCtx, msg); err != nil {
+				if err := h.receiveMessage(h.runningCtx, msg); err != nil {
 					// We silently drop failed messages because GPBFT will
 					// return errors for, e.g., messages from old instances.
 					// Given the async nature of our pubsub message handling, we
@@ -244,10 +297,14 @@ func (h *gpbftRunner) Start(ctx context.Context) (_err error) {
 					log.Debugw("Invalid partially validated message", "err", err)
 				default:
 					recordValidatedMessage(h.runningCtx, validatedMessage)
-					if err := h.participant.ReceiveMessage(h.runningCtx, validatedMessage); err != nil {
+					if err := h.receiveMessage(h.runningCtx, validatedMessage); err != nil {
 						log.Errorw("error while processing completed message", "err", err)
 					}
 				}
+			case <-h.localMessagesReady:
+				h.receiveLocalMessages(h.runningCtx)
+			case <-persistState.C:
+				h.persistState(h.runningCtx)
 			case <-h.runningCtx.Done():
 				return nil
 			}
@@ -307,6 +364,15 @@ func (h *gpbftRunner) Start(ctx context.Context) (_err error) {
 						log.Errorw("failed to purge messages from WAL", "error", err)
 					}
 				}
+				const keepInstancesInRecording = 20
+				if cert.GPBFTInstance > keepInstancesInRecording {
+					if err := h.recorder.Purge(cert.GPBFTInstance - keepInstancesInRecording); err != nil {
+						log.Errorw("failed to purge gpbft recording", "error", err)
+					}
+				}
+				// The state persisted for the finalized instance, if any, is no longer needed.
+				h.deleteState(h.runningCtx)
+				h.forgetLocallyVerified(cert.GPBFTInstance)
 				h.msgsMutex.Lock()
 				for instance := range h.selfMessages {
 					if instance < cert.GPBFTInstance {
@@ -375,6 +441,7 @@ func (h *gpbftRunner) startInstanceAt(ctx context.Context, instance uint64, at t
 		}
 	})
 
+	h.recorder.RecordStart(h.clock.Now(), instance, at)
 	if err := h.participant.StartInstanceAt(instance, at); err != nil {
 		return fmt.Errorf("starting instance at %d: %w", instance, err)
 	}
@@ -382,13 +449,79 @@ func (h *gpbftRunner) startInstanceAt(ctx context.Context, instance uint64, at t
 	for _, message := range replay {
 		if validated, err := h.participant.ValidateMessage(ctx, message); err != nil {
 			log.Warnw("invalid self message", "message", message, "err", err)
-		} else if err := h.participant.ReceiveMessage(ctx, validated); err != nil {
+		} else if err := h.receiveMessage(ctx, validated); err != nil {
 			log.Warnw("failed to send resumption message", "message", message, "err", err)
 		}
 	}
 	return nil
 }
 
+func (h *gpbftRunner) instanceStateKey() datastore.Key {
+	return instanceStateKeyPrefix.ChildString(string(h.manifest.NetworkName))
+}
+
+// persistState persists the state of the instance in progress, if any, so that restoreState can
+// resume it after a restart. Between instances, or once the instance in progress is finalized,
+// the state persisted earlier is deleted instead.
+func (h *gpbftRunner) persistState(ctx context.Context) {
+	state, err := h.participant.ExportState()
+	switch {
+	case errors.Is(err, gpbft.ErrNoInstanceInProgress):
+		h.deleteState(ctx)
+		return
+	case err != nil:
+		log.Errorw("failed to export gpbft instance state", "err", err)
+		return
+	}
+	if latest := h.certStore.Latest(); latest != nil && latest.GPBFTInstance >= h.participant.Progress().ID {
+		h.deleteState(ctx)
+		return
+	}
+	if err := h.stateStore.Put(ctx, h.instanceStateKey(), state); err != nil {
+		log.Errorw("failed to persist gpbft instance state", "err", err)
+	}
+}
+
+// deleteState deletes the persisted state of the instance, if any, once it is no longer needed.
+func (h *gpbftRunner) deleteState(ctx context.Context) {
+	if err := h.stateStore.Delete(ctx, h.instanceStateKey()); err != nil {
+		log.Errorw("failed to delete gpbft instance state", "err", err)
+	}
+}
+
+// restoreState resumes the instance the participant has just been told to start from its
+// persisted state, if there is one.
+func (h *gpbftRunner) restoreState(ctx context.Context) {
+	state, err := h.stateStore.Get(ctx, h.instanceStateKey())
+	switch {
+	case errors.Is(err, datastore.ErrNotFound):
+		return
+	case err != nil:
+		log.Errorw("failed to load gpbft instance state", "err", err)
+		return
+	}
+	switch err := h.participant.ImportState(ctx, state); {
+	case errors.Is(err, gpbft.ErrStateNotImportable):
+		log.Debugw("not restoring gpbft instance state", "reason", err)
+	case err != nil:
+		log.Errorw("failed to restore gpbft instance state", "err", err)
+	default:
+		log.Infow("restored gpbft instance state", "progress", h.participant.Progress())
+	}
+}
+
+// receiveMessage delivers a validated message to the participant, recording it first if enabled.
+func (h *gpbftRunner) receiveMessage(ctx context.Context, msg gpbft.ValidatedMessage) error {
+	h.recorder.RecordMessage(h.clock.Now(), msg.Message())
+	return h.participant.ReceiveMessage(ctx, msg)
+}
+
+// receiveAlarm delivers an alarm to the participant, recording it first if enabled.
+func (h *gpbftRunner) receiveAlarm(ctx context.Context) error {
+	h.recorder.RecordAlarm(h.clock.Now(), h.participant.Progress().ID)
+	return h.participant.ReceiveAlarm(ctx)
+}
+
 func (h *gpbftRunner) computeNextInstanceStart(cert *certs.FinalityCertificate) (_nextStart time.Time) {
 	ecDelay := time.Duration(h.manifest.EC.DelayMultiplier * float64(h.manifest.EC.Period))
 
@@ -469,21 +602,11 @@ func (h *gpbftRunner) BroadcastMessage(ctx context.Context, msg *gpbft.GMessage)
 		// equivocation filter does its own logging and this error just gets logged
 		return nil
 	}
-	err := h.wal.Append(walEntry{msg})
-	if err != nil {
-		log.Errorw("appending to WAL", "error", err)
-	}
-
-	h.msgsMutex.Lock()
-	if h.selfMessages[msg.Vote.Instance] == nil {
-		h.selfMessages[msg.Vote.Instance] = make(map[roundPhase][]*gpbft.GMessage)
-	}
-	key := roundPhase{
-		round: msg.Vote.Round,
-		phase: msg.Vote.Phase,
+	if h.addSelfMessage(msg) {
+		if err := h.wal.Append(walEntry{msg}); err != nil {
+			log.Errorw("appending to WAL", "error", err)
+		}
 	}
-	h.selfMessages[msg.Vote.Instance][key] = append(h.selfMessages[msg.Vote.Instance][key], msg)
-	h.msgsMutex.Unlock()
 
 	if h.topic == nil {
 		return pubsub.ErrTopicClosed
@@ -510,6 +633,30 @@ func (h *gpbftRunner) BroadcastMessage(ctx context.Context, msg *gpbft.GMessage)
 	return nil
 }
 
+// addSelfMessage adds a message broadcast by the node to the ones rebroadcast on request, and
+// returns whether it is new. A node may broadcast messages from several senders at the same round
+// and phase, and may broadcast the same message twice, e.g. when the participant asks for a
+// message to be signed again after a restart.
+func (h *gpbftRunner) addSelfMessage(msg *gpbft.GMessage) bool {
+	h.msgsMutex.Lock()
+	defer h.msgsMutex.Unlock()
+	if h.selfMessages[msg.Vote.Instance] == nil {
+		h.selfMessages[msg.Vote.Instance] = make(map[roundPhase][]*gpbft.GMessage)
+	}
+	key := roundPhase{
+		round: msg.Vote.Round,
+		phase: msg.Vote.Phase,
+	}
+	messages := h.selfMessages[msg.Vote.Instance][key]
+	if slices.ContainsFunc(messages, func(m *gpbft.GMessage) bool {
+		return m.Sender == msg.Sender && bytes.Equal(m.Signature, msg.Signature)
+	}) {
+		return false
+	}
+	h.selfMessages[msg.Vote.Instance][key] = append(messages, msg)
+	return true
+}
+
 func (h *gpbftRunner) rebroadcastMessage(msg *gpbft.GMessage) error {
 	if !h.equivFilter.ProcessBroadcast(msg) {
 		// equivocation filter does its own logging and this error just gets logged
@@ -709,6 +856,7 @@ func (h *gpbftHost) RequestRebroadcast(instant gpbft.Instant) error {
 func (h *gpbftHost) GetProposal(ctx context.Context, instance uint64) (*gpbft.SupplementalData, *gpbft.ECChain, error) {
 	proposal, chain, err := h.inputs.GetProposal(ctx, instance)
 	if err == nil {
+		h.recorder.RecordProposal(h.clock.Now(), instance, proposal, chain)
 		// Only broadcast the chain if this is a fresh instance started by self to avoid
 		// broadcasting a proposal that otherwise would get filtered by the
 		// self-equivocation filter.
@@ -725,14 +873,20 @@ func (h *gpbftHost) GetProposal(ctx context.Context, instance uint64) (*gpbft.Su
 }
 
 func (h *gpbftHost) GetCommittee(ctx context.Context, instance uint64) (*gpbft.Committee, error) {
-	return h.inputs.GetCommittee(ctx, instance)
+	committee, err := h.inputs.GetCommittee(ctx, instance)
+	if err == nil {
+		h.recorder.RecordCommittee(h.clock.Now(), instance, committee)
+	}
+	return committee, err
 }
 
 func (h *gpbftRunner) Stop(ctx context.Context) error {
 	h.ctxCancel()
+	// Wait for the runner to stop before closing what it writes to.
 	return multierr.Combine(
-		h.wal.Close(),
 		h.errgrp.Wait(),
+		h.wal.Close(),
+		h.recorder.Close(),
 		h.pmm.Shutdown(ctx),
 		h.teardownPubsub(),
 	)
@@ -753,8 +907,12 @@ func (h *gpbftHost) NetworkName() gpbft.NetworkName {
 
 // Sends a message to all other participants.
 func (h *gpbftHost) RequestBroadcast(mb *gpbft.MessageBuilder) error {
+	out := h.outMessages
+	if h.localActors != nil {
+		out = h.localBuilders
+	}
 	select {
-	case h.outMessages <- mb:
+	case out <- mb:
 		return nil
 	case <-h.runningCtx.Done():
 		return h.runningCtx.Err()
@@ -806,17 +964,20 @@ func (h *gpbftHost) ReceiveDecision(ctx context.Context, decision *gpbft.Justifi
 		log.Error(err)
 		return time.Time{}, err
 	}
-	return (*gpbftRunner)(h).computeNextInstanceStart(cert), nil
+	next := (*gpbftRunner)(h).computeNextInstanceStart(cert)
+	h.recorder.RecordDecision(h.clock.Now(), decision, next)
+	return next, nil
 }
 
 func (h *gpbftHost) saveDecision(ctx context.Context, decision *gpbft.Justification) (*certs.FinalityCertificate, error) {
+	// Committees are recorded as the participant asks for them, so bypass the recording here.
 	instance := decision.Vote.Instance
-	current, err := h.GetCommittee(ctx, instance)
+	current, err := h.inputs.GetCommittee(ctx, instance)
 	if err != nil {
 		return nil, fmt.Errorf("getting commitee for current instance %d: %w", instance, err)
 	}
 
-	next, err := h.GetCommittee(ctx, instance+1)
+	next, err := h.inputs.GetCommittee(ctx, instance+1)
 	if err != nil {
 		return nil, fmt.Errorf("getting commitee for next instance %d: %w", instance+1, err)
 	}
@@ -848,9 +1009,45 @@ func (h *gpbftHost) MarshalPayloadForSigning(nn gpbft.NetworkName, p *gpbft.Payl
 // Verifies a signature for the given public key.
 // Implementations must be safe for concurrent use.
 func (h *gpbftHost) Verify(pubKey gpbft.PubKey, msg []byte, sig []byte) error {
+	if (*gpbftRunner)(h).isLocallyVerified(pubKey, msg, sig) {
+		return nil
+	}
 	return h.verifier.Verify(pubKey, msg, sig)
 }
 
+// Verifies a batch of signatures at once if the verifier supports it, or one at a time otherwise.
+// Implementations must be safe for concurrent use.
+func (h *gpbftHost) VerifyBatch(pubKeys []gpbft.PubKey, msgs [][]byte, sigs [][]byte) error {
+	if len(pubKeys) != len(sigs) || len(msgs) != len(sigs) {
+		return errors.New("lengths of public keys, messages and signatures do not match")
+	}
+	if h.locallyVerified != nil {
+		// Leave out the signatures of the local actors that were already verified.
+		var remainingPubKeys []gpbft.PubKey
+		var remainingMsgs, remainingSigs [][]byte
+		for i := range sigs {
+			if !(*gpbftRunner)(h).isLocallyVerified(pubKeys[i], msgs[i], sigs[i]) {
+				remainingPubKeys = append(remainingPubKeys, pubKeys[i])
+				remainingMsgs = append(remainingMsgs, msgs[i])
+				remainingSigs = append(remainingSigs, sigs[i])
+			}
+		}
+		if len(remainingSigs) == 0 {
+			return nil
+		}
+		pubKeys, msgs, sigs = remainingPubKeys, remainingMsgs, remainingSigs
+	}
+	if batch, ok := h.verifier.(gpbft.BatchVerifier); ok {
+		return batch.VerifyBatch(pubKeys, msgs, sigs)
+	}
+	for i := range sigs {
+		if err := h.verifier.Verify(pubKeys[i], msgs[i], sigs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (h *gpbftHost) Aggregate(pubKeys []gpbft.PubKey) (gpbft.Aggregate, error) {
 	return h.verifier.Aggregate(pubKeys)
 }
@@ -62,6 +62,26 @@ func (s Suite) ValidatePairing(p1, p2, p3, p4 kyber.Point) bool {
 	return out
 }
 
+// ValidatePairings checks that the product of the pairings of each G1 point with the G2 point at
+// the same index is the identity of GT, using a single final exponentiation.
+func (s Suite) ValidatePairings(g1s, g2s []kyber.Point) bool {
+	if len(g1s) != len(g2s) {
+		panic(fmt.Errorf("mismatched number of G1 and G2 points: %d != %d", len(g1s), len(g2s)))
+	}
+	g1Affs := make([]bls12381.G1Affine, len(g1s))
+	g2Affs := make([]bls12381.G2Affine, len(g2s))
+	for i := range g1s {
+		g1Affs[i].FromJacobian(&g1s[i].(*G1Elt).inner)
+		g2Affs[i].FromJacobian(&g2s[i].(*G2Elt).inner)
+	}
+
+	out, err := bls12381.PairingCheck(g1Affs, g2Affs)
+	if err != nil {
+		panic(fmt.Errorf("error in gnark pairing: %w", err))
+	}
+	return out
+}
+
 func (s Suite) Read(_ io.Reader, _ ...interface{}) error {
 	panic("Suite.Read(): deprecated in drand")
 }
@@ -0,0 +1,320 @@
+// Package lightclient verifies F3 finality for consumers that do not
+// participate in GPBFT, such as bridges and wallets. Starting from a trusted
+// checkpoint, it validates batches of finality certificates and answers
+// whether a given tipset has been finalized, and by which certificate.
+package lightclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/filecoin-project/go-f3/certexchange"
+	"github.com/filecoin-project/go-f3/certs"
+	"github.com/filecoin-project/go-f3/certstore"
+	"github.com/filecoin-project/go-f3/gpbft"
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// The maximum number of certificates to request from a peer at once.
+const maxRequestLength = 256
+
+var (
+	// ErrNotFinalized is returned when a tipset is not (yet) known to be final.
+	ErrNotFinalized = errors.New("tipset is not finalized")
+	// ErrBeforeCheckpoint is returned when asked about an epoch that precedes the
+	// chain finalized since the trusted checkpoint.
+	ErrBeforeCheckpoint = errors.New("epoch precedes the trusted checkpoint")
+	// ErrInvalidCertificate is returned when a finality certificate fails
+	// validation.
+	ErrInvalidCertificate = errors.New("invalid finality certificate")
+	// ErrUnknownPowerTable is returned when certificates are ingested before the
+	// power table at the checkpoint instance is known.
+	ErrUnknownPowerTable = errors.New("checkpoint power table is not known")
+)
+
+var _ CertificateSource = (*certexchange.Client)(nil)
+
+// CertificateSource fetches finality certificates from a specific peer. It is
+// satisfied by certexchange.Client.
+type CertificateSource interface {
+	Request(ctx context.Context, p peer.ID, req *certexchange.Request) (*certexchange.ResponseHeader, <-chan *certs.FinalityCertificate, error)
+}
+
+// Checkpoint is the trusted starting point of a light client.
+type Checkpoint struct {
+	// The first instance for which finality certificates are accepted.
+	Instance uint64
+	// The CID of the power table used to validate the finality certificate at
+	// Instance.
+	PowerTable cid.Cid
+}
+
+// LightClient tracks the finalized chain from a trusted checkpoint onwards,
+// validating every finality certificate it ingests.
+type LightClient struct {
+	*options
+
+	networkName gpbft.NetworkName
+	verifier    gpbft.Verifier
+	checkpoint  Checkpoint
+
+	// mu serialises certificate ingestion and guards store, which is nil until
+	// the power table at the checkpoint instance is known.
+	mu    sync.RWMutex
+	store *certstore.Store
+}
+
+// New constructs a light client that trusts the given checkpoint. If the
+// configured datastore already holds state from a previous run, that state is
+// resumed as long as it is consistent with the checkpoint.
+func New(ctx context.Context, nn gpbft.NetworkName, verifier gpbft.Verifier, checkpoint Checkpoint, o ...Option) (*LightClient, error) {
+	if !checkpoint.PowerTable.Defined() {
+		return nil, errors.New("checkpoint power table CID must be defined")
+	}
+	opts, err := newOptions(o...)
+	if err != nil {
+		return nil, err
+	}
+	lc := &LightClient{
+		options:     opts,
+		networkName: nn,
+		verifier:    verifier,
+		checkpoint:  checkpoint,
+	}
+
+	store, err := certstore.OpenStore(ctx, opts.ds)
+	switch {
+	case errors.Is(err, certstore.ErrNotInitialized):
+		if len(opts.initialPowerTable) > 0 {
+			if err := lc.initStore(ctx, opts.initialPowerTable); err != nil {
+				return nil, err
+			}
+		}
+	case err != nil:
+		return nil, fmt.Errorf("opening certificate store: %w", err)
+	default:
+		pt, err := store.GetPowerTable(ctx, checkpoint.Instance)
+		if err != nil {
+			return nil, fmt.Errorf("loading power table at checkpoint instance %d: %w", checkpoint.Instance, err)
+		}
+		if ptCid, err := certs.MakePowerTableCID(pt); err != nil {
+			return nil, err
+		} else if ptCid != checkpoint.PowerTable {
+			return nil, fmt.Errorf("persisted power table at checkpoint instance %d does not match checkpoint: %s != %s", checkpoint.Instance, ptCid, checkpoint.PowerTable)
+		}
+		lc.store = store
+	}
+	return lc, nil
+}
+
+// Must be called with the lock held.
+func (lc *LightClient) initStore(ctx context.Context, pt gpbft.PowerEntries) error {
+	ptCid, err := certs.MakePowerTableCID(pt)
+	if err != nil {
+		return err
+	}
+	if ptCid != lc.checkpoint.PowerTable {
+		return fmt.Errorf("power table does not match checkpoint: %s != %s", ptCid, lc.checkpoint.PowerTable)
+	}
+	store, err := certstore.CreateStore(ctx, lc.ds, lc.checkpoint.Instance, pt)
+	if err != nil {
+		return fmt.Errorf("creating certificate store: %w", err)
+	}
+	lc.store = store
+	return nil
+}
+
+func (lc *LightClient) getStore() *certstore.Store {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+	return lc.store
+}
+
+// Checkpoint returns the trusted checkpoint of this light client.
+func (lc *LightClient) Checkpoint() Checkpoint {
+	return lc.checkpoint
+}
+
+// Latest returns the latest verified finality certificate, or nil if none has
+// been verified yet.
+func (lc *LightClient) Latest() *certs.FinalityCertificate {
+	if store := lc.getStore(); store != nil {
+		return store.Latest()
+	}
+	return nil
+}
+
+// NextInstance returns the instance of the next finality certificate the light
+// client expects.
+func (lc *LightClient) NextInstance() uint64 {
+	if latest := lc.Latest(); latest != nil {
+		return latest.GPBFTInstance + 1
+	}
+	return lc.checkpoint.Instance
+}
+
+// Ingest validates the given sequential finality certificates and persists the
+// ones that extend the verified chain. Certificates for already verified
+// instances are ignored. It returns the number of newly accepted certificates.
+//
+// If validation fails part way, the valid prefix is kept and an error wrapping
+// ErrInvalidCertificate is returned.
+func (lc *LightClient) Ingest(ctx context.Context, certificates ...*certs.FinalityCertificate) (uint64, error) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	if lc.store == nil {
+		return 0, ErrUnknownPowerTable
+	}
+
+	var base *gpbft.TipSet
+	next := lc.checkpoint.Instance
+	if latest := lc.store.Latest(); latest != nil {
+		base = latest.ECChain.Head()
+		next = latest.GPBFTInstance + 1
+	}
+	for len(certificates) > 0 && certificates[0].GPBFTInstance < next {
+		certificates = certificates[1:]
+	}
+	if len(certificates) == 0 {
+		return 0, nil
+	}
+
+	pt, err := lc.store.GetPowerTable(ctx, next)
+	if err != nil {
+		return 0, fmt.Errorf("loading power table for instance %d: %w", next, err)
+	}
+	validNext, _, _, validationErr := certs.ValidateFinalityCertificates(lc.verifier, lc.networkName, pt, next, base, certificates...)
+
+	var accepted uint64
+	for _, cert := range certificates[:validNext-next] {
+		if err := lc.store.Put(ctx, cert); err != nil {
+			return accepted, fmt.Errorf("persisting finality certificate for instance %d: %w", cert.GPBFTInstance, err)
+		}
+		accepted++
+	}
+	if validationErr != nil {
+		return accepted, fmt.Errorf("%w: %w", ErrInvalidCertificate, validationErr)
+	}
+	return accepted, nil
+}
+
+// Sync fetches finality certificates from the given peer, starting at the next
+// expected instance, until the peer has nothing more to offer. If the power
+// table at the checkpoint instance is not yet known, it is fetched from the
+// peer first and checked against the checkpoint. It returns the number of newly
+// accepted certificates.
+func (lc *LightClient) Sync(ctx context.Context, src CertificateSource, p peer.ID) (uint64, error) {
+	// Cancel this context on exit in case we exit early before the request finishes.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if err := lc.fetchInitialPowerTable(ctx, src, p); err != nil {
+		return 0, err
+	}
+
+	var accepted uint64
+	for {
+		resp, ch, err := src.Request(ctx, p, &certexchange.Request{
+			FirstInstance: lc.NextInstance(),
+			Limit:         maxRequestLength,
+		})
+		if err != nil {
+			return accepted, err
+		}
+		var received uint64
+		for cert := range ch {
+			n, err := lc.Ingest(ctx, cert)
+			accepted += n
+			if err != nil {
+				return accepted, err
+			}
+			received++
+		}
+		// Keep going only if the peer claims to have more and gave us something.
+		if resp.PendingInstance <= lc.NextInstance() || received == 0 {
+			return accepted, nil
+		}
+	}
+}
+
+func (lc *LightClient) fetchInitialPowerTable(ctx context.Context, src CertificateSource, p peer.ID) error {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	if lc.store != nil {
+		return nil
+	}
+	resp, _, err := src.Request(ctx, p, &certexchange.Request{
+		FirstInstance:     lc.checkpoint.Instance,
+		Limit:             0,
+		IncludePowerTable: true,
+	})
+	if err != nil {
+		return fmt.Errorf("requesting checkpoint power table: %w", err)
+	}
+	if len(resp.PowerTable) == 0 {
+		return fmt.Errorf("peer %s has no power table for checkpoint instance %d", p, lc.checkpoint.Instance)
+	}
+	return lc.initStore(ctx, resp.PowerTable)
+}
+
+// Finalized returns the finality certificate that finalized the tipset with
+// the given key at the given epoch. It returns an error wrapping:
+//
+//   - ErrNotFinalized if the epoch has not been finalized yet, was a null
+//     round, or a different tipset was finalized at that epoch.
+//   - ErrBeforeCheckpoint if the epoch was finalized before the trusted
+//     checkpoint.
+func (lc *LightClient) Finalized(ctx context.Context, epoch int64, key gpbft.TipSetKey) (*certs.FinalityCertificate, error) {
+	store := lc.getStore()
+	if store == nil {
+		return nil, fmt.Errorf("%w: no finality certificates verified yet", ErrNotFinalized)
+	}
+	latest := store.Latest()
+	if latest == nil || latest.GPBFTInstance < lc.checkpoint.Instance {
+		return nil, fmt.Errorf("%w: no finality certificates verified yet", ErrNotFinalized)
+	}
+	if epoch > latest.ECChain.Head().Epoch {
+		return nil, fmt.Errorf("%w: epoch %d is beyond the latest finalized epoch %d", ErrNotFinalized, epoch, latest.ECChain.Head().Epoch)
+	}
+	first, err := store.Get(ctx, lc.checkpoint.Instance)
+	if err != nil {
+		return nil, err
+	}
+	if epoch <= first.ECChain.Base().Epoch {
+		return nil, fmt.Errorf("%w: epoch %d", ErrBeforeCheckpoint, epoch)
+	}
+
+	// Find the first certificate with a head at or after the epoch. Every finalized
+	// chain extends the previous one, so head epochs never decrease.
+	cert := latest
+	lo, hi := lc.checkpoint.Instance, latest.GPBFTInstance
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		candidate, err := store.Get(ctx, mid)
+		if err != nil {
+			return nil, err
+		}
+		if candidate.ECChain.Head().Epoch >= epoch {
+			hi = mid
+			cert = candidate
+		} else {
+			lo = mid + 1
+		}
+	}
+
+	for _, ts := range cert.ECChain.Suffix() {
+		if ts.Epoch != epoch {
+			continue
+		}
+		if !bytes.Equal(ts.Key, key) {
+			return nil, fmt.Errorf("%w: instance %d finalized a different tipset at epoch %d", ErrNotFinalized, cert.GPBFTInstance, epoch)
+		}
+		return cert, nil
+	}
+	return nil, fmt.Errorf("%w: epoch %d is a null round finalized by instance %d", ErrNotFinalized, epoch, cert.GPBFTInstance)
+}
@@ -0,0 +1,267 @@
+package lightclient_test
+
+import (
+	"context"
+	"math/rand"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/go-f3/certchain"
+	"github.com/filecoin-project/go-f3/certexchange"
+	"github.com/filecoin-project/go-f3/certs"
+	"github.com/filecoin-project/go-f3/gpbft"
+	"github.com/filecoin-project/go-f3/internal/clock"
+	"github.com/filecoin-project/go-f3/internal/consensus"
+	"github.com/filecoin-project/go-f3/lightclient"
+	"github.com/filecoin-project/go-f3/manifest"
+	"github.com/filecoin-project/go-f3/sim/signing"
+	"github.com/ipfs/go-datastore"
+	ds_sync "github.com/ipfs/go-datastore/sync"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	seed            = 1413
+	certChainLength = 50
+)
+
+type testChain struct {
+	manifest          manifest.Manifest
+	verifier          gpbft.Verifier
+	initialPowerTable gpbft.PowerEntries
+	checkpoint        lightclient.Checkpoint
+	certificates      []*certs.FinalityCertificate
+}
+
+func generateChain(t *testing.T) *testChain {
+	ctx, clk := clock.WithMockClock(context.Background())
+	m := manifest.LocalDevnetManifest()
+	m.InitialInstance = 100
+	signVerifier := signing.NewFakeBackend()
+	rng := rand.New(rand.NewSource(seed * 23))
+	generatePublicKey := func(id gpbft.ActorID) gpbft.PubKey {
+		// Use allow instead of GenerateKey for a reproducible key generation.
+		return signVerifier.Allow(int(id))
+	}
+	initialPowerTable := generatePowerTable(t, rng, generatePublicKey, nil)
+	ptCid, err := certs.MakePowerTableCID(initialPowerTable)
+	require.NoError(t, err)
+
+	ec := consensus.NewFakeEC(
+		consensus.WithClock(clk),
+		consensus.WithSeed(seed*13),
+		consensus.WithBootstrapEpoch(m.BootstrapEpoch),
+		consensus.WithECPeriod(m.EC.Period),
+		consensus.WithInitialPowerTable(initialPowerTable),
+		consensus.WithEvolvingPowerTable(
+			func(epoch int64, entries gpbft.PowerEntries) gpbft.PowerEntries {
+				if epoch == m.BootstrapEpoch-m.EC.Finality {
+					return initialPowerTable
+				}
+				rng := rand.New(rand.NewSource(epoch * seed))
+				return generatePowerTable(t, rng, generatePublicKey, entries)
+			},
+		),
+	)
+	cc, err := certchain.New(
+		certchain.WithSeed(seed),
+		certchain.WithSignVerifier(signVerifier),
+		certchain.WithManifest(m),
+		certchain.WithEC(ec),
+	)
+	require.NoError(t, err)
+	// Advance the clock sufficiently for fake EC to generate the whole chain.
+	clk.Add(200 * time.Hour)
+
+	certificates, err := cc.Generate(ctx, certChainLength)
+	require.NoError(t, err)
+	return &testChain{
+		manifest:          m,
+		verifier:          signVerifier,
+		initialPowerTable: initialPowerTable,
+		checkpoint:        lightclient.Checkpoint{Instance: m.InitialInstance, PowerTable: ptCid},
+		certificates:      certificates,
+	}
+}
+
+func TestLightClient_IngestAndFinalized(t *testing.T) {
+	ctx := context.Background()
+	chain := generateChain(t)
+
+	subject, err := lightclient.New(ctx, chain.manifest.NetworkName, chain.verifier, chain.checkpoint,
+		lightclient.WithInitialPowerTable(chain.initialPowerTable))
+	require.NoError(t, err)
+	require.Nil(t, subject.Latest())
+	require.Equal(t, chain.checkpoint.Instance, subject.NextInstance())
+
+	accepted, err := subject.Ingest(ctx, chain.certificates[:20]...)
+	require.NoError(t, err)
+	require.EqualValues(t, 20, accepted)
+
+	// Overlapping batches only accept the new certificates.
+	accepted, err = subject.Ingest(ctx, chain.certificates[10:]...)
+	require.NoError(t, err)
+	require.EqualValues(t, certChainLength-20, accepted)
+	require.Equal(t, chain.certificates[certChainLength-1], subject.Latest())
+	require.Equal(t, chain.checkpoint.Instance+certChainLength, subject.NextInstance())
+
+	for _, cert := range chain.certificates {
+		for _, ts := range cert.ECChain.Suffix() {
+			got, err := subject.Finalized(ctx, ts.Epoch, ts.Key)
+			require.NoError(t, err)
+			require.Equal(t, cert.GPBFTInstance, got.GPBFTInstance)
+
+			_, err = subject.Finalized(ctx, ts.Epoch, []byte("fish"))
+			require.ErrorIs(t, err, lightclient.ErrNotFinalized)
+		}
+	}
+
+	head := subject.Latest().ECChain.Head()
+	_, err = subject.Finalized(ctx, head.Epoch+1, head.Key)
+	require.ErrorIs(t, err, lightclient.ErrNotFinalized)
+
+	base := chain.certificates[0].ECChain.Base()
+	_, err = subject.Finalized(ctx, base.Epoch, base.Key)
+	require.ErrorIs(t, err, lightclient.ErrBeforeCheckpoint)
+}
+
+func TestLightClient_RejectsInvalidCertificates(t *testing.T) {
+	ctx := context.Background()
+	chain := generateChain(t)
+
+	subject, err := lightclient.New(ctx, chain.manifest.NetworkName, chain.verifier, chain.checkpoint,
+		lightclient.WithInitialPowerTable(chain.initialPowerTable))
+	require.NoError(t, err)
+
+	tampered := *chain.certificates[5]
+	tampered.Signature = slices.Clone(tampered.Signature)
+	tampered.Signature[0] ^= 0xff
+	batch := slices.Clone(chain.certificates[:10])
+	batch[5] = &tampered
+
+	accepted, err := subject.Ingest(ctx, batch...)
+	require.ErrorIs(t, err, lightclient.ErrInvalidCertificate)
+	require.EqualValues(t, 5, accepted)
+	require.Equal(t, chain.certificates[4], subject.Latest())
+
+	// Gaps are rejected too.
+	_, err = subject.Ingest(ctx, chain.certificates[7:]...)
+	require.ErrorIs(t, err, lightclient.ErrInvalidCertificate)
+
+	_, err = subject.Ingest(ctx, chain.certificates[5:]...)
+	require.NoError(t, err)
+	require.Equal(t, chain.certificates[certChainLength-1], subject.Latest())
+}
+
+func TestLightClient_Persistence(t *testing.T) {
+	ctx := context.Background()
+	chain := generateChain(t)
+	ds := ds_sync.MutexWrap(datastore.NewMapDatastore())
+
+	subject, err := lightclient.New(ctx, chain.manifest.NetworkName, chain.verifier, chain.checkpoint,
+		lightclient.WithDatastore(ds),
+		lightclient.WithInitialPowerTable(chain.initialPowerTable))
+	require.NoError(t, err)
+	_, err = subject.Ingest(ctx, chain.certificates...)
+	require.NoError(t, err)
+
+	// Resume without having to supply the power table again.
+	resumed, err := lightclient.New(ctx, chain.manifest.NetworkName, chain.verifier, chain.checkpoint,
+		lightclient.WithDatastore(ds))
+	require.NoError(t, err)
+	// Compare by CID, as the memoized key of the chain is only set on the side that computed it.
+	wantCid, err := certs.MakeCertificateCID(subject.Latest())
+	require.NoError(t, err)
+	gotCid, err := certs.MakeCertificateCID(resumed.Latest())
+	require.NoError(t, err)
+	require.Equal(t, wantCid, gotCid)
+
+	// Refuse state that is inconsistent with the checkpoint.
+	badCheckpoint := chain.checkpoint
+	badCheckpoint.PowerTable = gpbft.MakeCid([]byte("not the checkpoint power table"))
+	_, err = lightclient.New(ctx, chain.manifest.NetworkName, chain.verifier, badCheckpoint,
+		lightclient.WithDatastore(ds))
+	require.Error(t, err)
+}
+
+func TestLightClient_Sync(t *testing.T) {
+	ctx := context.Background()
+	chain := generateChain(t)
+	src := &fakeSource{powerTable: chain.initialPowerTable, certificates: chain.certificates}
+
+	subject, err := lightclient.New(ctx, chain.manifest.NetworkName, chain.verifier, chain.checkpoint)
+	require.NoError(t, err)
+
+	_, err = subject.Ingest(ctx, chain.certificates...)
+	require.ErrorIs(t, err, lightclient.ErrUnknownPowerTable)
+
+	accepted, err := subject.Sync(ctx, src, "peer")
+	require.NoError(t, err)
+	require.EqualValues(t, certChainLength, accepted)
+	require.Equal(t, chain.certificates[certChainLength-1], subject.Latest())
+
+	// A peer serving the wrong power table is rejected.
+	other, err := lightclient.New(ctx, chain.manifest.NetworkName, chain.verifier, chain.checkpoint)
+	require.NoError(t, err)
+	src.powerTable = src.powerTable[1:]
+	_, err = other.Sync(ctx, src, "peer")
+	require.ErrorContains(t, err, "does not match checkpoint")
+	require.Nil(t, other.Latest())
+}
+
+var _ lightclient.CertificateSource = (*fakeSource)(nil)
+
+// fakeSource serves certificates in small batches to exercise repeated requests.
+type fakeSource struct {
+	powerTable   gpbft.PowerEntries
+	certificates []*certs.FinalityCertificate
+}
+
+func (s *fakeSource) Request(_ context.Context, _ peer.ID, req *certexchange.Request) (*certexchange.ResponseHeader, <-chan *certs.FinalityCertificate, error) {
+	first := s.certificates[0].GPBFTInstance
+	resp := &certexchange.ResponseHeader{PendingInstance: first + uint64(len(s.certificates))}
+	if req.IncludePowerTable && req.FirstInstance == first {
+		resp.PowerTable = s.powerTable
+	}
+	ch := make(chan *certs.FinalityCertificate, 7)
+	for i := req.FirstInstance; i < resp.PendingInstance && i-req.FirstInstance < min(req.Limit, 7); i++ {
+		ch <- s.certificates[i-first]
+	}
+	close(ch)
+	return resp, ch, nil
+}
+
+func generatePowerTable(t *testing.T, rng *rand.Rand, generatePublicKey func(id gpbft.ActorID) gpbft.PubKey, previousEntries gpbft.PowerEntries) gpbft.PowerEntries {
+	const (
+		maxEntries             = 100
+		maxPower               = 1 << 20
+		minPower               = 1
+		actorIDOffset          = 1413
+		powerChangeProbability = 0.2
+	)
+
+	size := rng.Intn(maxEntries) + 1
+	entries := make(gpbft.PowerEntries, 0, size)
+	for i := range size {
+		var entry gpbft.PowerEntry
+		if i < previousEntries.Len() {
+			entry = previousEntries[i]
+			if rng.Float64() > powerChangeProbability {
+				entry.Power = gpbft.NewStoragePower(int64(rng.Intn(maxPower) + minPower))
+			}
+		} else {
+			id := gpbft.ActorID(uint64(actorIDOffset + i))
+			entry = gpbft.PowerEntry{
+				ID:     id,
+				Power:  gpbft.NewStoragePower(int64(rng.Intn(maxPower) + minPower)),
+				PubKey: generatePublicKey(id),
+			}
+		}
+		entries = append(entries, entry)
+	}
+	next := gpbft.NewPowerTable()
+	require.NoError(t, next.Add(entries...))
+	return next.Entries
+}
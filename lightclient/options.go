@@ -0,0 +1,50 @@
+package lightclient
+
+import (
+	"github.com/filecoin-project/go-f3/gpbft"
+	"github.com/ipfs/go-datastore"
+	ds_sync "github.com/ipfs/go-datastore/sync"
+)
+
+// Option represents a configurable parameter.
+type Option func(*options) error
+
+type options struct {
+	ds                datastore.Datastore
+	initialPowerTable gpbft.PowerEntries
+}
+
+func newOptions(o ...Option) (*options, error) {
+	var opts options
+	for _, apply := range o {
+		if err := apply(&opts); err != nil {
+			return nil, err
+		}
+	}
+	if opts.ds == nil {
+		opts.ds = ds_sync.MutexWrap(datastore.NewMapDatastore())
+	}
+	return &opts, nil
+}
+
+// WithDatastore sets the datastore in which the light client persists the
+// verified finality certificates. The datastore must be thread safe. Defaults
+// to an in-memory datastore if unspecified, in which case nothing survives the
+// light client.
+func WithDatastore(ds datastore.Datastore) Option {
+	return func(o *options) error {
+		o.ds = ds
+		return nil
+	}
+}
+
+// WithInitialPowerTable sets the power table used to validate the finality
+// certificate at the checkpoint instance. The table is checked against the
+// checkpoint power table CID. If unspecified, the power table is fetched from
+// the first peer the light client syncs from.
+func WithInitialPowerTable(pt gpbft.PowerEntries) Option {
+	return func(o *options) error {
+		o.initialPowerTable = pt
+		return nil
+	}
+}
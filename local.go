@@ -0,0 +1,198 @@
+package f3
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+
+	"github.com/filecoin-project/go-f3/gpbft"
+)
+
+// localActors are the actors hosted by the node, on behalf of which F3 signs messages itself.
+type localActors struct {
+	signer gpbft.Signer
+	// The IDs of the actors, in ascending order and without duplicates.
+	ids []gpbft.ActorID
+}
+
+// actorIDs returns the IDs of the local actors, or none if there are no local actors.
+func (la *localActors) actorIDs() []gpbft.ActorID {
+	if la == nil {
+		return nil
+	}
+	return la.ids
+}
+
+// sign signs the message built by the given builder for each of the actors that has power in its
+// instance. Actors for which signing fails are skipped, and the error is logged.
+func (la *localActors) sign(ctx context.Context, mb *gpbft.MessageBuilder) []*gpbft.GMessage {
+	msgs := make([]*gpbft.GMessage, 0, len(la.ids))
+	for _, id := range la.ids {
+		msg, err := mb.Build(ctx, la.signer, id)
+		switch {
+		case errors.Is(err, gpbft.ErrNoPower):
+			// The actor is not part of the committee of this instance.
+		case err != nil:
+			log.Errorw("failed to sign message on behalf of local actor", "actor", id,
+				"instance", mb.Payload.Instance, "round", mb.Payload.Round, "phase", mb.Payload.Phase, "err", err)
+		default:
+			msgs = append(msgs, msg)
+		}
+	}
+	return msgs
+}
+
+// signLocally signs the messages requested by the participant on behalf of the local actors, and
+// broadcasts them. The messages signed for each request are then queued together, for the runner
+// loop to deliver them to the participant. Their signatures are verified at once by aggregating
+// them, rather than one at a time as each message is validated.
+func (h *gpbftRunner) signLocally() error {
+	for h.runningCtx.Err() == nil {
+		select {
+		case <-h.runningCtx.Done():
+			return nil
+		case mb := <-h.localBuilders:
+			msgs := h.localActors.sign(h.runningCtx, mb)
+			if len(msgs) == 0 {
+				continue
+			}
+			h.verifyLocally(mb, msgs)
+			for _, msg := range msgs {
+				if err := h.BroadcastMessage(h.runningCtx, msg); err != nil {
+					log.Warnw("failed to broadcast message of local actor", "actor", msg.Sender, "err", err)
+				}
+			}
+			h.localMessagesMu.Lock()
+			h.localMessages = append(h.localMessages, msgs...)
+			h.localMessagesMu.Unlock()
+			select {
+			case h.localMessagesReady <- struct{}{}:
+			default:
+				// The runner loop is already due to deliver the queue.
+			}
+		}
+	}
+	return nil
+}
+
+// verifyLocally verifies the signatures, and tickets if any, of the given messages signed on
+// behalf of the local actors for the same builder by aggregating them, which takes a single
+// verification of each kind instead of one per message. Once verified, validating the messages
+// skips verifying their signatures. Should aggregation fail, the signatures are left to be
+// verified one at a time as usual.
+func (h *gpbftRunner) verifyLocally(mb *gpbft.MessageBuilder, msgs []*gpbft.GMessage) {
+	if len(msgs) < 2 {
+		// Nothing to aggregate.
+		return
+	}
+	inputs, err := mb.PrepareSigningInputs(msgs[0].Sender)
+	if err != nil {
+		log.Warnw("failed to prepare signing inputs of local actors", "err", err)
+		return
+	}
+	pubKeys := make([]gpbft.PubKey, len(msgs))
+	signers := make([]int, len(msgs))
+	sigs := make([][]byte, len(msgs))
+	tickets := make([][]byte, len(msgs))
+	for i, msg := range msgs {
+		_, pubKeys[i] = mb.PowerTable.Get(msg.Sender)
+		signers[i] = i
+		sigs[i] = msg.Signature
+		tickets[i] = msg.Ticket
+	}
+	agg, err := h.verifier.Aggregate(pubKeys)
+	if err != nil {
+		log.Warnw("failed to aggregate public keys of local actors", "err", err)
+		return
+	}
+	if !verifyAggregated(agg, signers, inputs.PayloadToSign, sigs) {
+		log.Warnw("signatures of local actors failed aggregate verification", "instance",
+			mb.Payload.Instance, "round", mb.Payload.Round, "phase", mb.Payload.Phase)
+		return
+	}
+	// Tickets, which are signatures of the same input by each actor, are aggregated likewise.
+	if inputs.VRFToSign != nil && !verifyAggregated(agg, signers, inputs.VRFToSign, tickets) {
+		log.Warnw("tickets of local actors failed aggregate verification", "instance",
+			mb.Payload.Instance, "round", mb.Payload.Round)
+		tickets = nil
+	}
+
+	h.locallyVerifiedMu.Lock()
+	defer h.locallyVerifiedMu.Unlock()
+	for i := range msgs {
+		h.locallyVerified[locallyVerifiedKey(pubKeys[i], inputs.PayloadToSign, sigs[i])] = mb.Payload.Instance
+		if tickets != nil {
+			h.locallyVerified[locallyVerifiedKey(pubKeys[i], inputs.VRFToSign, tickets[i])] = mb.Payload.Instance
+		}
+	}
+}
+
+// verifyAggregated aggregates the given signatures of the same message and verifies the
+// aggregate.
+func verifyAggregated(agg gpbft.Aggregate, signers []int, msg []byte, sigs [][]byte) bool {
+	aggSig, err := agg.Aggregate(signers, sigs)
+	if err != nil {
+		return false
+	}
+	return agg.VerifyAggregate(signers, msg, aggSig) == nil
+}
+
+// isLocallyVerified checks whether the given signature was made on behalf of a local actor and
+// already verified by verifyLocally.
+func (h *gpbftRunner) isLocallyVerified(pubKey gpbft.PubKey, msg, sig []byte) bool {
+	if h.locallyVerified == nil {
+		return false
+	}
+	h.locallyVerifiedMu.RLock()
+	defer h.locallyVerifiedMu.RUnlock()
+	_, found := h.locallyVerified[locallyVerifiedKey(pubKey, msg, sig)]
+	return found
+}
+
+// forgetLocallyVerified forgets the signatures verified by verifyLocally for the given instance
+// and the ones before it.
+func (h *gpbftRunner) forgetLocallyVerified(instance uint64) {
+	if h.locallyVerified == nil {
+		return
+	}
+	h.locallyVerifiedMu.Lock()
+	defer h.locallyVerifiedMu.Unlock()
+	for key, signedAt := range h.locallyVerified {
+		if signedAt <= instance {
+			delete(h.locallyVerified, key)
+		}
+	}
+}
+
+func locallyVerifiedKey(pubKey gpbft.PubKey, msg, sig []byte) [32]byte {
+	hasher := sha256.New()
+	for _, field := range [][]byte{pubKey, msg, sig} {
+		_ = binary.Write(hasher, binary.BigEndian, uint32(len(field)))
+		hasher.Write(field)
+	}
+	var key [32]byte
+	hasher.Sum(key[:0])
+	return key
+}
+
+// receiveLocalMessages delivers the queued messages signed on behalf of the local actors to the
+// participant. Their copies received later over pubsub are ignored by the participant as
+// duplicates.
+func (h *gpbftRunner) receiveLocalMessages(ctx context.Context) {
+	h.localMessagesMu.Lock()
+	msgs := h.localMessages
+	h.localMessages = nil
+	h.localMessagesMu.Unlock()
+
+	for _, msg := range msgs {
+		validated, err := h.participant.ValidateMessage(ctx, msg)
+		if err != nil {
+			log.Debugw("invalid message of local actor", "actor", msg.Sender, "err", err)
+			continue
+		}
+		if err := h.receiveMessage(ctx, validated); err != nil {
+			log.Debugw("error when receiving message of local actor", "actor", msg.Sender, "err", err)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package f3
+
+import (
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/go-f3/gpbft"
+	"github.com/filecoin-project/go-f3/sim/signing"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyLocally(t *testing.T) {
+	ctx := context.Background()
+	backend := signing.NewFakeBackend()
+	pt := gpbft.NewPowerTable()
+	for _, id := range []gpbft.ActorID{1, 2, 3} {
+		require.NoError(t, pt.Add(gpbft.PowerEntry{ID: id, Power: gpbft.NewStoragePower(1), PubKey: backend.Allow(int(id))}))
+	}
+	runner := &gpbftRunner{verifier: backend, locallyVerified: make(map[[32]byte]uint64)}
+	host := (*gpbftHost)(runner)
+	local := &localActors{signer: backend, ids: []gpbft.ActorID{1, 2}}
+
+	mb := &gpbft.MessageBuilder{
+		NetworkName:     "test",
+		PowerTable:      pt,
+		Payload:         gpbft.Payload{Instance: 5, Round: 1, Phase: gpbft.CONVERGE_PHASE},
+		BeaconForTicket: []byte("beacon"),
+	}
+	msgs := local.sign(ctx, mb)
+	require.Len(t, msgs, 2)
+	runner.verifyLocally(mb, msgs)
+
+	inputs, err := mb.PrepareSigningInputs(1)
+	require.NoError(t, err)
+	var (
+		pubKeys        []gpbft.PubKey
+		payloads, sigs [][]byte
+	)
+	for _, msg := range msgs {
+		_, pubKey := pt.Get(msg.Sender)
+		require.True(t, runner.isLocallyVerified(pubKey, inputs.PayloadToSign, msg.Signature))
+		require.True(t, runner.isLocallyVerified(pubKey, inputs.VRFToSign, msg.Ticket))
+		pubKeys = append(pubKeys, pubKey)
+		payloads = append(payloads, inputs.PayloadToSign)
+		sigs = append(sigs, msg.Signature)
+	}
+	require.NoError(t, host.VerifyBatch(pubKeys, payloads, sigs))
+
+	// Other signatures are still verified.
+	_, pubKey := pt.Get(1)
+	require.Error(t, host.Verify(pubKey, inputs.PayloadToSign, []byte("forged")))
+	require.Error(t, host.VerifyBatch(append(pubKeys, pubKey), append(payloads, inputs.PayloadToSign), append(sigs, []byte("forged"))))
+
+	// Invalid signatures are never marked as verified.
+	forged := *msgs[1]
+	forged.Signature = []byte("forged")
+	runner.verifyLocally(mb, []*gpbft.GMessage{msgs[0], &forged})
+	_, pubKey = pt.Get(2)
+	require.False(t, runner.isLocallyVerified(pubKey, inputs.PayloadToSign, forged.Signature))
+
+	// Signatures are forgotten once their instance is finalized.
+	runner.forgetLocallyVerified(4)
+	require.True(t, runner.isLocallyVerified(pubKeys[0], inputs.PayloadToSign, sigs[0]))
+	runner.forgetLocallyVerified(5)
+	require.False(t, runner.isLocallyVerified(pubKeys[0], inputs.PayloadToSign, sigs[0]))
+}
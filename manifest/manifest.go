@@ -11,6 +11,7 @@ import (
 	"github.com/filecoin-project/go-f3/gpbft"
 	"github.com/ipfs/go-cid"
 	"github.com/ipfs/go-datastore"
+	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/multiformats/go-multihash"
 )
 
@@ -83,6 +84,20 @@ var (
 	DefaultCatchUpAlignment = DefaultEcConfig.Period / 2
 )
 
+// PeerDiscovery names a mechanism for finding the certificate exchange peers to poll.
+type PeerDiscovery string
+
+const (
+	// PeerDiscoveryConnected finds the connected peers that support the certificate exchange
+	// protocol.
+	PeerDiscoveryConnected PeerDiscovery = "connected"
+	// PeerDiscoveryStatic uses the peers listed in CxConfig.StaticPeers.
+	PeerDiscoveryStatic PeerDiscovery = "static"
+	// PeerDiscoveryDHT finds the peers that advertise the certificate exchange protocol in the
+	// DHT, and advertises this node as well.
+	PeerDiscoveryDHT PeerDiscovery = "dht"
+)
+
 // Certificate Exchange config
 type CxConfig struct {
 	// Request timeout for the certificate exchange client.
@@ -93,6 +108,11 @@ type CxConfig struct {
 	MinimumPollInterval time.Duration
 	// Maximum CX polling interval.
 	MaximumPollInterval time.Duration
+	// The mechanisms to find the certificate exchange peers to poll. Defaults to
+	// PeerDiscoveryConnected if empty.
+	PeerDiscovery []PeerDiscovery `json:",omitempty"`
+	// The multiaddrs, including peer IDs, of the peers found by PeerDiscoveryStatic.
+	StaticPeers []string `json:",omitempty"`
 }
 
 func (c *CxConfig) Validate() error {
@@ -108,6 +128,22 @@ func (c *CxConfig) Validate() error {
 		return fmt.Errorf("maximum polling interval (%s) must not be less than the minimum (%s)",
 			c.MaximumPollInterval, c.MinimumPollInterval)
 	}
+	for _, d := range c.PeerDiscovery {
+		switch d {
+		case PeerDiscoveryConnected, PeerDiscoveryDHT:
+		case PeerDiscoveryStatic:
+			if len(c.StaticPeers) == 0 {
+				return errors.New("static peer discovery requires static peers")
+			}
+		default:
+			return fmt.Errorf("unknown peer discovery %q", d)
+		}
+	}
+	for _, addr := range c.StaticPeers {
+		if _, err := peer.AddrInfoFromString(addr); err != nil {
+			return fmt.Errorf("invalid static peer %q: %w", addr, err)
+		}
+	}
 	return nil
 }
 
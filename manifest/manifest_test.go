@@ -8,6 +8,8 @@ import (
 
 	"github.com/filecoin-project/go-f3/gpbft"
 	"github.com/filecoin-project/go-f3/manifest"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/stretchr/testify/require"
 )
 
@@ -56,6 +58,20 @@ func TestManifest_Validation(t *testing.T) {
 	cpy = base
 	cpy.CertificateExchange.MinimumPollInterval = time.Nanosecond
 	require.Error(t, cpy.Validate())
+
+	cpy = base
+	cpy.CertificateExchange.PeerDiscovery = []manifest.PeerDiscovery{manifest.PeerDiscoveryConnected, manifest.PeerDiscoveryStatic}
+	require.Error(t, cpy.Validate())
+	cpy.CertificateExchange.StaticPeers = []string{"/ip4/127.0.0.1/tcp/1234"}
+	require.Error(t, cpy.Validate())
+	_, pub, err := crypto.GenerateEd25519Key(nil)
+	require.NoError(t, err)
+	id, err := peer.IDFromPublicKey(pub)
+	require.NoError(t, err)
+	cpy.CertificateExchange.StaticPeers = []string{"/ip4/127.0.0.1/tcp/1234/p2p/" + id.String()}
+	require.NoError(t, cpy.Validate())
+	cpy.CertificateExchange.PeerDiscovery = []manifest.PeerDiscovery{"carrier-pigeon"}
+	require.Error(t, cpy.Validate())
 }
 
 func TestManifest_Serialization(t *testing.T) {
@@ -0,0 +1,269 @@
+package f3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/filecoin-project/go-f3/certexchange"
+	"github.com/filecoin-project/go-f3/certstore"
+	"github.com/filecoin-project/go-f3/gpbft"
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/routing"
+)
+
+// Option represents a configurable parameter of F3.
+type Option func(*options) error
+
+type options struct {
+	snapshot            *snapshotBootstrap
+	retention           uint64
+	certificateSegments bool
+	announcements       bool
+	persistPeers        bool
+	backfill            bool
+	recording           bool
+	contentRouting      routing.ContentRouting
+	localActors         *localActors
+	batchValidation     gpbft.Option
+	serverLimits        ServerLimits
+}
+
+// SnapshotSource opens a stream of an F3 snapshot, in the format written by
+// certstore.ExportSnapshot.
+type SnapshotSource func(ctx context.Context) (io.ReadCloser, error)
+
+// PeerSnapshotSource returns a SnapshotSource that downloads the latest snapshot from the given
+// certificate exchange peer, resuming the download if it is interrupted.
+func PeerSnapshotSource(client *certexchange.Client, p peer.ID) SnapshotSource {
+	return func(ctx context.Context) (io.ReadCloser, error) {
+		_, snapshot, err := client.OpenSnapshot(ctx, p, certexchange.LatestSnapshot)
+		return snapshot, err
+	}
+}
+
+type snapshotBootstrap struct {
+	source     SnapshotSource
+	trustedCid cid.Cid
+}
+
+func newOptions(o ...Option) (*options, error) {
+	var opts options
+	for _, apply := range o {
+		if err := apply(&opts); err != nil {
+			return nil, err
+		}
+	}
+	if opts.backfill && opts.retention > 0 {
+		return nil, errors.New("certificate backfill cannot be combined with certificate retention")
+	}
+	return &opts, nil
+}
+
+// WithSnapshotBootstrap bootstraps the certificate store of a fresh node from the snapshot
+// opened by the given source, rather than catching up one finality certificate at a time from
+// the manifest initial instance. Polling for certificates then continues from the snapshot head.
+//
+// If the trusted CID is defined, the snapshot is accepted only if its content matches it.
+// Otherwise, every finality certificate in the snapshot is validated starting from the manifest
+// initial power table, which must then be specified.
+//
+// Bootstrapping from the snapshot is skipped if the certificate store already exists. If it
+// fails, F3 falls back to catching up from the manifest initial instance.
+func WithSnapshotBootstrap(source SnapshotSource, trustedCid cid.Cid) Option {
+	return func(o *options) error {
+		if source == nil {
+			return errors.New("snapshot source must not be nil")
+		}
+		o.snapshot = &snapshotBootstrap{
+			source:     source,
+			trustedCid: trustedCid,
+		}
+		return nil
+	}
+}
+
+// WithCertificateRetention limits the certificate store to roughly the last given number of
+// finality certificates, pruning older ones in the background. Nodes that retain fewer
+// certificates serve fewer of them to their peers.
+//
+// Defaults to 0, which retains all certificates.
+func WithCertificateRetention(certificates uint64) Option {
+	return func(o *options) error {
+		o.retention = certificates
+		return nil
+	}
+}
+
+// WithCertificateSegments stores finality certificates in append-only segment files under the
+// disk path of F3, rather than in the datastore. This makes reading large ranges of
+// certificates considerably faster, e.g. on archive nodes that serve them to their peers.
+//
+// Certificates already stored in the datastore are not migrated, so this should only be
+// enabled on nodes with a fresh certificate store, e.g. one bootstrapped from a snapshot. F3
+// fails to start if enabled on a store with certificates in the datastore, with an error wrapping
+// certstore.ErrBackendMismatch.
+func WithCertificateSegments() Option {
+	return func(o *options) error {
+		o.certificateSegments = true
+		return nil
+	}
+}
+
+// WithCertificateAnnouncements announces finality certificates over pubsub as soon as they are
+// stored, and fetches the certificates announced by peers right away, rather than waiting to poll
+// for them. Polling continues as a fallback when no certificates are announced.
+func WithCertificateAnnouncements() Option {
+	return func(o *options) error {
+		o.announcements = true
+		return nil
+	}
+}
+
+// WithPersistentPeerReputation saves what F3 learns about the usefulness of its certificate
+// exchange peers to the datastore, and restores it on start, rather than learning it all over
+// again. Peers not seen for a week are forgotten.
+func WithPersistentPeerReputation() Option {
+	return func(o *options) error {
+		o.persistPeers = true
+		return nil
+	}
+}
+
+// WithCertificateBackfill fetches the finality certificates missing below the first instance of
+// the certificate store from peers. This is meant for archive nodes bootstrapped from a snapshot
+// that want the full history.
+//
+// Peers are not trusted: backfilling walks forwards from the manifest initial instance, starting
+// from the initial power table committed to by the manifest, and validates every certificate
+// against the power table before it. The certificates only become part of the store once they
+// lead up to its first instance, matching the power table and finalized chain already trusted
+// there. Backfilling is therefore skipped if the manifest doesn't specify the initial power table,
+// and starts over from the initial instance if F3 restarts before it completes.
+//
+// Backfill cannot be combined with WithCertificateRetention.
+func WithCertificateBackfill() Option {
+	return func(o *options) error {
+		o.backfill = true
+		return nil
+	}
+}
+
+// WithRecording records every input given to the GPBFT participant, i.e. validated messages,
+// alarms, committees, proposals and instance starts, along with the decisions it reaches, under
+// the disk path of F3. The recording of the last few instances can then be replayed with
+// recording.Replayer to reproduce an incident.
+//
+// Every input is synced to disk as it is recorded, so this is best enabled only while
+// investigating a problem.
+func WithRecording() Option {
+	return func(o *options) error {
+		o.recording = true
+		return nil
+	}
+}
+
+// WithContentRouting sets the content routing system, typically the DHT, through which F3 finds
+// certificate exchange peers, and advertises itself as one, when the manifest selects the DHT peer
+// discovery.
+func WithContentRouting(r routing.ContentRouting) Option {
+	return func(o *options) error {
+		if r == nil {
+			return errors.New("content routing must not be nil")
+		}
+		o.contentRouting = r
+		return nil
+	}
+}
+
+// WithLocalActors makes F3 vote on behalf of the given actors hosted by the node, signing each
+// message of the participant for every one of them that has power in the instance with the given
+// signer. The messages signed locally are delivered to the participant straight away, so that
+// their signatures are aggregated into its justifications without a round trip through pubsub.
+// The signatures of the messages signed for the same vote are verified at once by aggregating
+// them, rather than once per actor as each message is validated.
+//
+// This replaces signing the messages from MessagesToSign, which hands each message to a single
+// reader only and is therefore unfit for nodes hosting several actors. Nothing is sent to
+// MessagesToSign when this option is set.
+func WithLocalActors(signer gpbft.Signer, actors ...gpbft.ActorID) Option {
+	return func(o *options) error {
+		if signer == nil {
+			return errors.New("signer must not be nil")
+		}
+		if len(actors) == 0 {
+			return errors.New("at least one local actor must be specified")
+		}
+		ids := slices.Clone(actors)
+		slices.Sort(ids)
+		o.localActors = &localActors{
+			signer: signer,
+			ids:    slices.Compact(ids),
+		}
+		return nil
+	}
+}
+
+// WithBatchValidation verifies the signatures of the messages received over pubsub in batches,
+// collected for the given window of time and verified by a pool of the given number of workers,
+// rather than one at a time as each message is validated. See gpbft.WithBatchValidation.
+func WithBatchValidation(window time.Duration, workers int) Option {
+	return func(o *options) error {
+		o.batchValidation = gpbft.WithBatchValidation(window, workers)
+		return nil
+	}
+}
+
+// ServerLimits are the limits on the requests the certificate exchange server of a node serves
+// to its peers. Zero values impose no limit, unless stated otherwise.
+type ServerLimits struct {
+	// The maximum number of requests served at once.
+	MaxConcurrentRequests int
+	// The rate of requests per second served to each peer, allowing for bursts of up to
+	// PeerRequestBurst requests, which must then be at least 1.
+	PeerRequestRate  float64
+	PeerRequestBurst int
+	// The maximum number of certificates served per minute, across all peers.
+	MaxCertificatesPerMinute uint64
+	// The maximum number of snapshots served at once. Defaults to 2 if zero.
+	MaxConcurrentSnapshots int
+}
+
+// WithServerLimits limits the requests the certificate exchange server serves to its peers, so
+// that serving them doesn't overwhelm the node. Requests over the limits are rejected, and peers
+// asking for them are told they're over quota.
+//
+// Defaults to no limits, other than on concurrent snapshots.
+func WithServerLimits(limits ServerLimits) Option {
+	return func(o *options) error {
+		switch {
+		case limits.MaxConcurrentRequests < 0:
+			return fmt.Errorf("max concurrent requests must be non-negative, was %d", limits.MaxConcurrentRequests)
+		case limits.PeerRequestRate < 0:
+			return fmt.Errorf("peer request rate must be non-negative, was %f", limits.PeerRequestRate)
+		case limits.PeerRequestBurst < 0:
+			return fmt.Errorf("peer request burst must be non-negative, was %d", limits.PeerRequestBurst)
+		case limits.PeerRequestRate > 0 && limits.PeerRequestBurst < 1:
+			return fmt.Errorf("peer request burst must be at least 1 when rate limiting, was %d", limits.PeerRequestBurst)
+		case limits.MaxConcurrentSnapshots < 0:
+			return fmt.Errorf("max concurrent snapshots must be non-negative, was %d", limits.MaxConcurrentSnapshots)
+		}
+		o.serverLimits = limits
+		return nil
+	}
+}
+
+func (o *options) gpbftOptions() []gpbft.Option {
+	if o.batchValidation == nil {
+		return nil
+	}
+	return []gpbft.Option{o.batchValidation}
+}
+
+func (o *options) certstoreOptions() []certstore.Option {
+	return []certstore.Option{certstore.WithRetention(o.retention)}
+}
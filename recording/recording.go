@@ -0,0 +1,205 @@
+// Package recording records every input given to a gpbft.Participant, and replays a recording
+// to reproduce what the participant did.
+package recording
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/filecoin-project/go-f3/gpbft"
+	"github.com/filecoin-project/go-f3/internal/writeaheadlog"
+	logging "github.com/ipfs/go-log/v2"
+)
+
+var log = logging.Logger("f3/recording")
+
+// Kind identifies the input to a gpbft.Participant recorded by an Entry.
+type Kind uint64
+
+const (
+	// KindMessage records a validated message received by the participant.
+	KindMessage Kind = iota + 1
+	// KindAlarm records an alarm delivered to the participant.
+	KindAlarm
+	// KindCommittee records the committee of an instance provided to the participant.
+	KindCommittee
+	// KindProposal records the proposal for an instance provided to the participant.
+	KindProposal
+	// KindStart records the participant being told to start an instance at a given time.
+	KindStart
+	// KindDecision records a decision reached by the participant, along with the time at which
+	// it was told to start the next instance.
+	KindDecision
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindMessage:
+		return "message"
+	case KindAlarm:
+		return "alarm"
+	case KindCommittee:
+		return "committee"
+	case KindProposal:
+		return "proposal"
+	case KindStart:
+		return "start"
+	case KindDecision:
+		return "decision"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint64(k))
+	}
+}
+
+var _ writeaheadlog.Entry = (*Entry)(nil)
+
+// Entry is a single input to a gpbft.Participant, in the order in which it was given. Only the
+// fields relevant to its kind are set.
+type Entry struct {
+	Kind Kind
+	// The time at which the input was given, in nanoseconds since the Unix epoch.
+	Timestamp int64
+	// The instance of the input, or the current instance of the participant for alarms.
+	Instance uint64
+	// The time at which the instance starts, in nanoseconds since the Unix epoch. Set for start
+	// entries, and for decision entries to the start of the next instance.
+	StartAt int64
+
+	Message          *gpbft.GMessage
+	PowerTable       gpbft.PowerEntries
+	Beacon           []byte
+	SupplementalData *gpbft.SupplementalData
+	Proposal         *gpbft.ECChain
+	Decision         *gpbft.Justification
+}
+
+// Time returns the time at which the input was given.
+func (e *Entry) Time() time.Time {
+	return time.Unix(0, e.Timestamp)
+}
+
+// WALEpoch returns the instance of the entry, used to purge recordings of old instances.
+func (e *Entry) WALEpoch() uint64 {
+	return e.Instance
+}
+
+// Recorder appends the inputs of a gpbft.Participant to a recording on disk. Failures to record
+// are logged rather than returned, so that recording never gets in the way of the participant.
+//
+// A nil Recorder records nothing, and all its methods are safe for concurrent use.
+type Recorder struct {
+	wal *writeaheadlog.WriteAheadLog[Entry, *Entry]
+}
+
+// Open opens the recording in the given directory for appending, creating it if it does not
+// exist.
+func Open(directory string) (*Recorder, error) {
+	wal, err := writeaheadlog.Open[Entry](directory)
+	if err != nil {
+		return nil, fmt.Errorf("opening recording: %w", err)
+	}
+	return &Recorder{wal: wal}, nil
+}
+
+// Read reads all the entries of the recording in the given directory, in the order in which
+// they were recorded.
+func Read(directory string) ([]Entry, error) {
+	wal, err := writeaheadlog.Open[Entry](directory)
+	if err != nil {
+		return nil, fmt.Errorf("opening recording: %w", err)
+	}
+	entries, err := wal.All()
+	if err != nil {
+		return nil, fmt.Errorf("reading recording: %w", err)
+	}
+	return entries, nil
+}
+
+// RecordMessage records a validated message about to be received by the participant.
+func (r *Recorder) RecordMessage(at time.Time, msg *gpbft.GMessage) {
+	r.append(Entry{
+		Kind:      KindMessage,
+		Timestamp: at.UnixNano(),
+		Instance:  msg.Vote.Instance,
+		Message:   msg,
+	})
+}
+
+// RecordAlarm records an alarm about to be delivered to the participant, which is currently
+// progressing the given instance.
+func (r *Recorder) RecordAlarm(at time.Time, instance uint64) {
+	r.append(Entry{
+		Kind:      KindAlarm,
+		Timestamp: at.UnixNano(),
+		Instance:  instance,
+	})
+}
+
+// RecordCommittee records the committee of the given instance provided to the participant.
+func (r *Recorder) RecordCommittee(at time.Time, instance uint64, committee *gpbft.Committee) {
+	r.append(Entry{
+		Kind:       KindCommittee,
+		Timestamp:  at.UnixNano(),
+		Instance:   instance,
+		PowerTable: committee.PowerTable.Entries,
+		Beacon:     committee.Beacon,
+	})
+}
+
+// RecordProposal records the proposal for the given instance provided to the participant.
+func (r *Recorder) RecordProposal(at time.Time, instance uint64, data *gpbft.SupplementalData, proposal *gpbft.ECChain) {
+	r.append(Entry{
+		Kind:             KindProposal,
+		Timestamp:        at.UnixNano(),
+		Instance:         instance,
+		SupplementalData: data,
+		Proposal:         proposal,
+	})
+}
+
+// RecordStart records the participant being told to start the given instance at the given time.
+func (r *Recorder) RecordStart(at time.Time, instance uint64, startAt time.Time) {
+	r.append(Entry{
+		Kind:      KindStart,
+		Timestamp: at.UnixNano(),
+		Instance:  instance,
+		StartAt:   startAt.UnixNano(),
+	})
+}
+
+// RecordDecision records a decision reached by the participant, and the time at which the next
+// instance starts as a result.
+func (r *Recorder) RecordDecision(at time.Time, decision *gpbft.Justification, next time.Time) {
+	r.append(Entry{
+		Kind:      KindDecision,
+		Timestamp: at.UnixNano(),
+		Instance:  decision.Vote.Instance,
+		StartAt:   next.UnixNano(),
+		Decision:  decision,
+	})
+}
+
+func (r *Recorder) append(e Entry) {
+	if r == nil {
+		return
+	}
+	if err := r.wal.Append(e); err != nil {
+		log.Errorw("failed to record gpbft input", "kind", e.Kind, "instance", e.Instance, "err", err)
+	}
+}
+
+// Purge removes the recorded files containing only entries for instances before the given one.
+func (r *Recorder) Purge(keepInstance uint64) error {
+	if r == nil {
+		return nil
+	}
+	return r.wal.Purge(keepInstance)
+}
+
+// Close flushes the recording to disk.
+func (r *Recorder) Close() error {
+	if r == nil {
+		return nil
+	}
+	return r.wal.Close()
+}
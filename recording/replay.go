@@ -0,0 +1,243 @@
+package recording
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/filecoin-project/go-f3/gpbft"
+)
+
+// Replayer feeds a recording back into a fresh gpbft.Participant, and reports where the decisions
+// of the replayed participant diverge from the recorded ones.
+//
+// The participant is given each recorded input as is, at the recorded time, which is what its
+// host reports as the current time. Recorded messages are not validated again, and the messages
+// broadcast by the replayed participant are discarded, since the recording contains them as they
+// were received. Likewise, the alarms set by the participant are ignored in favour of the
+// recorded ones.
+type Replayer struct {
+	// The name of the network the recording was made on.
+	NetworkName gpbft.NetworkName
+	// The verifier used to aggregate the signatures of the recorded committees, which must
+	// match the one of the recorded participant.
+	Verifier gpbft.Verifier
+	// The options of the replayed participant, which should match the ones of the recorded
+	// participant, e.g. manifest.Manifest.GpbftOptions.
+	Options []gpbft.Option
+}
+
+// Report summarises the replay of a recording.
+type Report struct {
+	// The number of entries replayed, excluding the ones skipped before the first instance the
+	// participant was told to start.
+	Replayed int
+	// The instances decided by the replayed participant, in order.
+	Decided []uint64
+	// The divergences found, in the order of the recording.
+	Divergences []Divergence
+}
+
+// OK checks whether the replay found no divergences.
+func (r *Report) OK() bool {
+	return len(r.Divergences) == 0
+}
+
+// Divergence describes an instance for which the replayed participant did not decide the same
+// value as the recorded one, at the same point of the recording.
+type Divergence struct {
+	Instance uint64
+	// The time of the recorded entry at which the divergence was found.
+	At time.Time
+	// The progress of the replayed participant when the divergence was found.
+	Progress gpbft.InstanceProgress
+	// The recorded and replayed decisions, either of which is nil if there was none.
+	Recorded, Replayed *gpbft.Justification
+}
+
+// Replay replays the given entries, as returned by Read. Errors returned by the participant for
+// individual inputs are logged and otherwise ignored, in the same way as when recording.
+func (r *Replayer) Replay(ctx context.Context, entries []Entry) (*Report, error) {
+	host := &replayHost{
+		Verifier:    r.Verifier,
+		networkName: r.NetworkName,
+		committees:  make(map[uint64]*gpbft.Committee),
+		proposals:   make(map[uint64]*Entry),
+		recorded:    make(map[uint64]*Entry),
+		replayed:    make(map[uint64]*gpbft.Justification),
+	}
+	// Committees and proposals are recorded as the participant asks for them, i.e. after the
+	// input that made it ask, so they are needed ahead of time. So are the recorded decisions, to
+	// tell when the participant should start the next instance once it decides.
+	for i := range entries {
+		switch entry := &entries[i]; entry.Kind {
+		case KindCommittee:
+			if err := host.putCommittee(entry); err != nil {
+				return nil, err
+			}
+		case KindProposal:
+			host.proposals[entry.Instance] = entry
+		case KindDecision:
+			host.recorded[entry.Instance] = entry
+		}
+	}
+	participant, err := gpbft.NewParticipant(host, r.Options...)
+	if err != nil {
+		return nil, fmt.Errorf("creating participant: %w", err)
+	}
+
+	var (
+		report  Report
+		started bool
+		checked = make(map[uint64]struct{})
+	)
+	for i := range entries {
+		entry := &entries[i]
+		// Inputs recorded concurrently may be slightly out of order in time. Never go back.
+		if at := entry.Time(); at.After(host.now) {
+			host.now = at
+		}
+
+		switch entry.Kind {
+		case KindCommittee, KindProposal:
+			continue
+		case KindStart:
+			started = true
+			if err := participant.StartInstanceAt(entry.Instance, time.Unix(0, entry.StartAt)); err != nil {
+				return nil, fmt.Errorf("starting instance %d: %w", entry.Instance, err)
+			}
+		case KindMessage:
+			if !started {
+				continue
+			}
+			if err := participant.ReceiveMessage(ctx, replayedMessage{entry.Message}); err != nil {
+				log.Debugw("error when replaying message", "message", entry.Message, "err", err)
+			}
+		case KindAlarm:
+			if !started {
+				continue
+			}
+			if err := participant.ReceiveAlarm(ctx); err != nil {
+				log.Debugw("error when replaying alarm", "instance", entry.Instance, "err", err)
+			}
+		case KindDecision:
+			if !started {
+				// The recording starts part way through an instance, which cannot be replayed.
+				// Resume from the start of the next one instead.
+				started = true
+				if err := participant.StartInstanceAt(entry.Instance+1, time.Unix(0, entry.StartAt)); err != nil {
+					return nil, fmt.Errorf("starting instance %d: %w", entry.Instance+1, err)
+				}
+				break
+			}
+			checked[entry.Instance] = struct{}{}
+			replayed := host.replayed[entry.Instance]
+			if replayed == nil || !replayed.Vote.Value.Eq(entry.Decision.Vote.Value) {
+				report.Divergences = append(report.Divergences, Divergence{
+					Instance: entry.Instance,
+					At:       entry.Time(),
+					Progress: participant.Progress(),
+					Recorded: entry.Decision,
+					Replayed: replayed,
+				})
+			}
+		default:
+			return nil, fmt.Errorf("unknown kind of recorded entry: %s", entry.Kind)
+		}
+		report.Replayed++
+	}
+
+	// Report the decisions that were replayed but never recorded.
+	for _, instance := range host.decided {
+		if _, found := checked[instance]; !found {
+			report.Divergences = append(report.Divergences, Divergence{
+				Instance: instance,
+				At:       host.now,
+				Progress: participant.Progress(),
+				Replayed: host.replayed[instance],
+			})
+		}
+	}
+	report.Decided = host.decided
+	return &report, nil
+}
+
+var _ gpbft.ValidatedMessage = replayedMessage{}
+
+type replayedMessage struct {
+	msg *gpbft.GMessage
+}
+
+func (m replayedMessage) Message() *gpbft.GMessage { return m.msg }
+
+var _ gpbft.Host = (*replayHost)(nil)
+
+// replayHost provides the replayed participant with the recorded committees and proposals, and
+// collects its decisions.
+type replayHost struct {
+	gpbft.Verifier
+	networkName gpbft.NetworkName
+
+	now        time.Time
+	committees map[uint64]*gpbft.Committee
+	proposals  map[uint64]*Entry
+	// The recorded and replayed decisions by instance.
+	recorded map[uint64]*Entry
+	replayed map[uint64]*gpbft.Justification
+	decided  []uint64
+}
+
+func (h *replayHost) putCommittee(entry *Entry) error {
+	table := gpbft.NewPowerTable()
+	if err := table.Add(entry.PowerTable...); err != nil {
+		return fmt.Errorf("adding recorded power table entries of instance %d: %w", entry.Instance, err)
+	}
+	agg, err := h.Aggregate(table.Entries.PublicKeys())
+	if err != nil {
+		return fmt.Errorf("aggregating recorded committee of instance %d: %w", entry.Instance, err)
+	}
+	h.committees[entry.Instance] = &gpbft.Committee{
+		PowerTable:        table,
+		Beacon:            entry.Beacon,
+		AggregateVerifier: agg,
+	}
+	return nil
+}
+
+func (h *replayHost) GetProposal(_ context.Context, instance uint64) (*gpbft.SupplementalData, *gpbft.ECChain, error) {
+	entry, found := h.proposals[instance]
+	if !found {
+		return nil, nil, fmt.Errorf("no proposal recorded for instance %d", instance)
+	}
+	return entry.SupplementalData, entry.Proposal, nil
+}
+
+func (h *replayHost) GetCommittee(_ context.Context, instance uint64) (*gpbft.Committee, error) {
+	committee, found := h.committees[instance]
+	if !found {
+		return nil, fmt.Errorf("no committee recorded for instance %d", instance)
+	}
+	return committee, nil
+}
+
+func (h *replayHost) NetworkName() gpbft.NetworkName { return h.networkName }
+
+func (h *replayHost) RequestBroadcast(*gpbft.MessageBuilder) error { return nil }
+
+func (h *replayHost) RequestRebroadcast(gpbft.Instant) error { return nil }
+
+func (h *replayHost) Time() time.Time { return h.now }
+
+func (h *replayHost) SetAlarm(time.Time) {}
+
+func (h *replayHost) ReceiveDecision(_ context.Context, decision *gpbft.Justification) (time.Time, error) {
+	instance := decision.Vote.Instance
+	if _, found := h.replayed[instance]; !found {
+		h.decided = append(h.decided, instance)
+	}
+	h.replayed[instance] = decision
+	if recorded, found := h.recorded[instance]; found {
+		return time.Unix(0, recorded.StartAt), nil
+	}
+	return h.now, nil
+}
@@ -0,0 +1,174 @@
+package recording_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/go-f3/certs"
+	"github.com/filecoin-project/go-f3/emulator"
+	"github.com/filecoin-project/go-f3/gpbft"
+	"github.com/filecoin-project/go-f3/recording"
+	"github.com/stretchr/testify/require"
+)
+
+const networkName gpbft.NetworkName = "recording-test"
+
+var _ gpbft.Host = (*recordingHost)(nil)
+
+// recordingHost hosts a participant that is the sole member of its committee, recording its
+// inputs the way the F3 runner does.
+type recordingHost struct {
+	emulator.Signing
+	recorder *recording.Recorder
+
+	now        time.Time
+	alarm      time.Time
+	committee  *gpbft.Committee
+	data       *gpbft.SupplementalData
+	proposal   *gpbft.ECChain
+	broadcasts []*gpbft.MessageBuilder
+	decision   *gpbft.Justification
+}
+
+func newRecordingHost(t *testing.T, recorder *recording.Recorder) *recordingHost {
+	entries := gpbft.PowerEntries{{ID: 0, PubKey: []byte("🪪0"), Power: gpbft.NewStoragePower(1)}}
+	ptCid, err := certs.MakePowerTableCID(entries)
+	require.NoError(t, err)
+	table := gpbft.NewPowerTable()
+	require.NoError(t, table.Add(entries...))
+	signing := emulator.AdhocSigning()
+	agg, err := signing.Aggregate(table.Entries.PublicKeys())
+	require.NoError(t, err)
+	proposal, err := gpbft.NewChain(
+		&gpbft.TipSet{Epoch: 0, Key: []byte("genesis"), PowerTable: ptCid},
+		&gpbft.TipSet{Epoch: 1, Key: []byte("tipset"), PowerTable: ptCid},
+	)
+	require.NoError(t, err)
+	return &recordingHost{
+		Signing:   signing,
+		recorder:  recorder,
+		now:       time.Unix(1_700_000_000, 0),
+		committee: &gpbft.Committee{PowerTable: table, Beacon: []byte("beacon"), AggregateVerifier: agg},
+		data:      &gpbft.SupplementalData{PowerTable: ptCid},
+		proposal:  proposal,
+	}
+}
+
+func (h *recordingHost) GetProposal(_ context.Context, instance uint64) (*gpbft.SupplementalData, *gpbft.ECChain, error) {
+	h.recorder.RecordProposal(h.now, instance, h.data, h.proposal)
+	return h.data, h.proposal, nil
+}
+
+func (h *recordingHost) GetCommittee(_ context.Context, instance uint64) (*gpbft.Committee, error) {
+	h.recorder.RecordCommittee(h.now, instance, h.committee)
+	return h.committee, nil
+}
+
+func (h *recordingHost) NetworkName() gpbft.NetworkName { return networkName }
+
+func (h *recordingHost) RequestBroadcast(mb *gpbft.MessageBuilder) error {
+	h.broadcasts = append(h.broadcasts, mb)
+	return nil
+}
+
+func (h *recordingHost) RequestRebroadcast(gpbft.Instant) error { return nil }
+func (h *recordingHost) Time() time.Time                        { return h.now }
+func (h *recordingHost) SetAlarm(at time.Time)                  { h.alarm = at }
+
+func (h *recordingHost) ReceiveDecision(_ context.Context, decision *gpbft.Justification) (time.Time, error) {
+	next := h.now.Add(time.Minute)
+	h.recorder.RecordDecision(h.now, decision, next)
+	h.decision = decision
+	return next, nil
+}
+
+// record runs a single instance to a decision, and returns the recording of it.
+func record(t *testing.T) (*recordingHost, []recording.Entry) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	recorder, err := recording.Open(dir)
+	require.NoError(t, err)
+	host := newRecordingHost(t, recorder)
+
+	participant, err := gpbft.NewParticipant(host)
+	require.NoError(t, err)
+	recorder.RecordStart(host.now, 0, host.now)
+	require.NoError(t, participant.StartInstanceAt(0, host.now))
+	for host.decision == nil {
+		if len(host.broadcasts) > 0 {
+			mb := host.broadcasts[0]
+			host.broadcasts = host.broadcasts[1:]
+			msg, err := mb.Build(ctx, host, 0)
+			require.NoError(t, err)
+			validated, err := participant.ValidateMessage(ctx, msg)
+			require.NoError(t, err)
+			recorder.RecordMessage(host.now, msg)
+			require.NoError(t, participant.ReceiveMessage(ctx, validated))
+		} else {
+			require.False(t, host.alarm.IsZero(), "participant stalled")
+			host.now = host.alarm
+			host.alarm = time.Time{}
+			recorder.RecordAlarm(host.now, participant.Progress().ID)
+			require.NoError(t, participant.ReceiveAlarm(ctx))
+		}
+	}
+	require.NoError(t, recorder.Close())
+
+	entries, err := recording.Read(dir)
+	require.NoError(t, err)
+	return host, entries
+}
+
+func TestReplay(t *testing.T) {
+	host, entries := record(t)
+	require.True(t, host.proposal.Eq(host.decision.Vote.Value))
+
+	kinds := make(map[recording.Kind]int)
+	for _, entry := range entries {
+		kinds[entry.Kind]++
+	}
+	require.Equal(t, 1, kinds[recording.KindStart])
+	require.Equal(t, 1, kinds[recording.KindDecision])
+	require.Equal(t, 1, kinds[recording.KindProposal])
+	require.NotZero(t, kinds[recording.KindCommittee])
+	require.NotZero(t, kinds[recording.KindAlarm])
+	// QUALITY, PREPARE, COMMIT and DECIDE
+	require.Equal(t, 4, kinds[recording.KindMessage])
+
+	replayer := recording.Replayer{
+		NetworkName: networkName,
+		Verifier:    emulator.AdhocSigning(),
+	}
+	report, err := replayer.Replay(context.Background(), entries)
+	require.NoError(t, err)
+	require.True(t, report.OK(), "divergences: %v", report.Divergences)
+	require.Equal(t, []uint64{0}, report.Decided)
+	require.Equal(t, len(entries)-kinds[recording.KindProposal]-kinds[recording.KindCommittee], report.Replayed)
+}
+
+func TestReplay_Divergence(t *testing.T) {
+	host, entries := record(t)
+
+	// Pretend the recorded participant decided on the base alone.
+	for i := range entries {
+		if entries[i].Kind == recording.KindDecision {
+			decision := *entries[i].Decision
+			decision.Vote.Value = host.proposal.BaseChain()
+			entries[i].Decision = &decision
+		}
+	}
+
+	replayer := recording.Replayer{
+		NetworkName: networkName,
+		Verifier:    emulator.AdhocSigning(),
+	}
+	report, err := replayer.Replay(context.Background(), entries)
+	require.NoError(t, err)
+	require.False(t, report.OK())
+	require.Len(t, report.Divergences, 1)
+	divergence := report.Divergences[0]
+	require.Equal(t, uint64(0), divergence.Instance)
+	require.True(t, host.proposal.BaseChain().Eq(divergence.Recorded.Vote.Value))
+	require.True(t, host.proposal.Eq(divergence.Replayed.Vote.Value))
+}
@@ -76,6 +76,18 @@ func (s *FakeBackend) Verify(signer gpbft.PubKey, msg, sig []byte) error {
 	}
 }
 
+func (s *FakeBackend) VerifyBatch(signers []gpbft.PubKey, msgs [][]byte, sigs [][]byte) error {
+	if len(signers) != len(sigs) || len(msgs) != len(sigs) {
+		return errors.New("public keys, messages and signatures length mismatch")
+	}
+	for i := range sigs {
+		if err := s.Verify(signers[i], msgs[i], sigs[i]); err != nil {
+			return fmt.Errorf("signature %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
 func (s *FakeBackend) Aggregate(keys []gpbft.PubKey) (gpbft.Aggregate, error) {
 	for i, signer := range keys {
 		if len(signer) != 16 {
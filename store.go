@@ -16,25 +16,44 @@ import (
 	"github.com/ipfs/go-datastore/namespace"
 )
 
+// The maximum number of finality certificates validated at once when bootstrapping from an
+// untrusted snapshot.
+const snapshotValidationBatchSize = 256
+
 // openCertstore opens the certificate store for the specific manifest (namespaced by the network
-// name).
+// name). If the store does not exist yet and a snapshot is specified, the store is bootstrapped
+// from the snapshot.
 func openCertstore(ctx context.Context, ec ec.Backend, ds datastore.Datastore,
-	m manifest.Manifest, certClient certexchange.Client) (*certstore.Store, error) {
+	m manifest.Manifest, certClient certexchange.Client, verifier gpbft.Verifier,
+	opts *options, backend certstore.Backend) (*certstore.Store, error) {
 	ds = namespace.Wrap(ds, m.DatastorePrefix())
+	csOpts := opts.certstoreOptions()
+	if backend != nil {
+		csOpts = append(csOpts, certstore.WithBackend(backend))
+	}
 
-	if cs, err := certstore.OpenStore(ctx, ds); err == nil {
+	if cs, err := certstore.OpenStore(ctx, ds, csOpts...); err == nil {
 		return cs, nil
 	} else if !errors.Is(err, certstore.ErrNotInitialized) {
 		return nil, err
 	}
 
+	if opts.snapshot != nil {
+		cs, err := importSnapshot(ctx, ds, m, verifier, opts.snapshot, csOpts)
+		if err == nil {
+			log.Infow("bootstrapped F3 from snapshot", "latestInstance", cs.Latest().GPBFTInstance)
+			return cs, nil
+		}
+		log.Warnw("failed to bootstrap F3 from snapshot, catching up from the initial instance", "error", err)
+	}
+
 	var initialPowerTable gpbft.PowerEntries
 	initialPowerTable, err := loadInitialPowerTable(ctx, ec, m, certClient)
 	if err != nil {
 		return nil, fmt.Errorf("getting initial power table: %w", err)
 	}
 
-	return certstore.CreateStore(ctx, ds, m.InitialInstance, initialPowerTable)
+	return certstore.CreateStore(ctx, ds, m.InitialInstance, initialPowerTable, csOpts...)
 }
 
 func loadInitialPowerTable(ctx context.Context, ec ec.Backend, m manifest.Manifest, certClient certexchange.Client) (gpbft.PowerEntries, error) {
@@ -66,3 +85,100 @@ func loadInitialPowerTable(ctx context.Context, ec ec.Backend, m manifest.Manife
 	}
 	return pt, nil
 }
+
+// importSnapshot bootstraps a fresh certificate store from the given snapshot. Anything imported
+// is discarded if the snapshot does not match its trusted CID or, when no CID is trusted, if any
+// of its finality certificates is invalid.
+func importSnapshot(ctx context.Context, ds datastore.Datastore, m manifest.Manifest, verifier gpbft.Verifier,
+	snapshot *snapshotBootstrap, csOpts []certstore.Option) (_ *certstore.Store, _err error) {
+	if !snapshot.trustedCid.Defined() && !m.InitialPowerTable.Defined() {
+		return nil, errors.New("cannot validate an untrusted snapshot without an initial power table in the manifest")
+	}
+
+	reader, err := snapshot.source(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("opening snapshot: %w", err)
+	}
+	defer func() { _ = reader.Close() }()
+
+	defer func() {
+		if _err != nil {
+			discardCertstore(ctx, ds, csOpts)
+		}
+	}()
+
+	snapshotCid, err := certstore.ImportSnapshotToDatastoreWithCID(ctx, reader, basicBatching{ds}, &m, csOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("importing snapshot: %w", err)
+	}
+	if snapshot.trustedCid.Defined() && snapshot.trustedCid != snapshotCid {
+		return nil, fmt.Errorf("snapshot CID %s does not match the trusted CID %s", snapshotCid, snapshot.trustedCid)
+	}
+
+	cs, err := certstore.OpenStore(ctx, ds, csOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("opening imported certstore: %w", err)
+	}
+	// Stop the background tasks of the store before it is discarded on error.
+	defer func() {
+		if _err != nil {
+			_ = cs.Close()
+		}
+	}()
+	if !snapshot.trustedCid.Defined() {
+		if err := validateCertstore(ctx, cs, m, verifier); err != nil {
+			return nil, fmt.Errorf("validating snapshot %s: %w", snapshotCid, err)
+		}
+	}
+	return cs, nil
+}
+
+// validateCertstore validates every finality certificate in the given store, starting from the
+// manifest initial instance.
+func validateCertstore(ctx context.Context, cs *certstore.Store, m manifest.Manifest, verifier gpbft.Verifier) error {
+	latest := cs.Latest()
+	if latest == nil {
+		return nil
+	}
+	next := m.InitialInstance
+	powerTable, err := cs.GetPowerTable(ctx, next)
+	if err != nil {
+		return err
+	}
+	var base *gpbft.TipSet
+	for next <= latest.GPBFTInstance {
+		end := min(next+snapshotValidationBatchSize-1, latest.GPBFTInstance)
+		batch, err := cs.GetRange(ctx, next, end)
+		if err != nil {
+			return err
+		}
+		certificates := make([]*certs.FinalityCertificate, len(batch))
+		for i := range batch {
+			certificates[i] = &batch[i]
+		}
+		next, _, powerTable, err = certs.ValidateFinalityCertificates(verifier, m.NetworkName, powerTable, next, base, certificates...)
+		if err != nil {
+			return err
+		}
+		base = certificates[len(certificates)-1].ECChain.Head()
+	}
+	return nil
+}
+
+// discardCertstore removes any partially bootstrapped certificate store, including one that
+// failed before being initialized.
+func discardCertstore(ctx context.Context, ds datastore.Datastore, csOpts []certstore.Option) {
+	if err := certstore.Discard(ctx, ds, csOpts...); err != nil {
+		log.Errorw("failed to delete partially bootstrapped certstore", "error", err)
+	}
+}
+
+// basicBatching adapts a datastore that may not support batching to datastore.Batching, by
+// applying batched operations one at a time.
+type basicBatching struct {
+	datastore.Datastore
+}
+
+func (b basicBatching) Batch(context.Context) (datastore.Batch, error) {
+	return datastore.NewBasicBatch(b.Datastore), nil
+}
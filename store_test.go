@@ -0,0 +1,80 @@
+package f3
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/filecoin-project/go-f3/certs"
+	"github.com/filecoin-project/go-f3/certstore"
+	"github.com/filecoin-project/go-f3/gpbft"
+	"github.com/filecoin-project/go-f3/manifest"
+	"github.com/filecoin-project/go-f3/sim/signing"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportSnapshot_DiscardsFailedImport(t *testing.T) {
+	ctx := context.Background()
+	m := manifest.LocalDevnetManifest()
+
+	// Export a snapshot of a store holding two certificates.
+	powerTable := gpbft.PowerEntries{{ID: 1, PubKey: []byte("🔑"), Power: gpbft.NewStoragePower(1)}}
+	ptCid, err := certs.MakePowerTableCID(powerTable)
+	require.NoError(t, err)
+	source, err := certstore.CreateStore(ctx, datastore.NewMapDatastore(), m.InitialInstance, powerTable)
+	require.NoError(t, err)
+	for i := range uint64(2) {
+		chain, err := gpbft.NewChain(&gpbft.TipSet{Epoch: int64(i), Key: []byte{byte(i)}, PowerTable: ptCid})
+		require.NoError(t, err)
+		require.NoError(t, source.Put(ctx, &certs.FinalityCertificate{
+			GPBFTInstance:    m.InitialInstance + i,
+			ECChain:          chain,
+			SupplementalData: gpbft.SupplementalData{PowerTable: ptCid},
+		}))
+	}
+	var snapshot bytes.Buffer
+	snapshotCid, _, err := source.ExportLatestSnapshot(ctx, &snapshot)
+	require.NoError(t, err)
+
+	for _, test := range []struct {
+		name       string
+		snapshot   []byte
+		trustedCid cid.Cid
+	}{
+		{
+			name:       "mismatching CID",
+			snapshot:   snapshot.Bytes(),
+			trustedCid: ptCid,
+		},
+		{
+			// The snapshot ends part-way through the second certificate, after the first one has
+			// been imported.
+			name:       "truncated",
+			snapshot:   snapshot.Bytes()[:snapshot.Len()-1],
+			trustedCid: snapshotCid,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ds := datastore.NewMapDatastore()
+			_, err := importSnapshot(ctx, ds, m, signing.NewFakeBackend(), &snapshotBootstrap{
+				source: func(context.Context) (io.ReadCloser, error) {
+					return io.NopCloser(bytes.NewReader(test.snapshot)), nil
+				},
+				trustedCid: test.trustedCid,
+			}, nil)
+			require.Error(t, err)
+
+			_, err = certstore.OpenStore(ctx, ds)
+			require.ErrorIs(t, err, certstore.ErrNotInitialized)
+			results, err := ds.Query(ctx, query.Query{KeysOnly: true})
+			require.NoError(t, err)
+			entries, err := results.Rest()
+			require.NoError(t, err)
+			require.Empty(t, entries)
+		})
+	}
+}
@@ -19,9 +19,13 @@ type (
 	SigningTestSuite struct {
 		suite.Suite
 		signerTestSubject SignerTestSubject
-		verifier          gpbft.Verifier
+		verifier          SigningTestVerifier
+	}
+	SignerTestSubject   func(*testing.T) (gpbft.PubKey, gpbft.Signer)
+	SigningTestVerifier interface {
+		gpbft.Verifier
+		gpbft.BatchVerifier
 	}
-	SignerTestSubject func(*testing.T) (gpbft.PubKey, gpbft.Signer)
 )
 
 func TestBLSSigning(t *testing.T) {
@@ -47,7 +51,7 @@ func TestFakeSigning(t *testing.T) {
 	}, fakeSigning))
 }
 
-func NewSigningSuite(signer SignerTestSubject, verifier gpbft.Verifier) *SigningTestSuite {
+func NewSigningSuite(signer SignerTestSubject, verifier SigningTestVerifier) *SigningTestSuite {
 	return &SigningTestSuite{
 		signerTestSubject: signer,
 		verifier:          verifier,
@@ -82,6 +86,39 @@ func (s *SigningTestSuite) TestSignAndVerify() {
 	require.Error(t, err)
 }
 
+func (s *SigningTestSuite) TestSignAndVerifyBatch() {
+	ctx := context.Background()
+	t := s.Suite.T()
+	msg1 := []byte("test message")
+	msg2 := []byte("other test message")
+	pubKey1, signer1 := s.signerTestSubject(s.T())
+	pubKey2, signer2 := s.signerTestSubject(s.T())
+	pubKey3, signer3 := s.signerTestSubject(s.T())
+
+	sig1, err := signer1.Sign(ctx, pubKey1, msg1)
+	require.NoError(t, err)
+	sig2, err := signer2.Sign(ctx, pubKey2, msg1)
+	require.NoError(t, err)
+	sig3, err := signer3.Sign(ctx, pubKey3, msg2)
+	require.NoError(t, err)
+
+	pubKeys := []gpbft.PubKey{pubKey1, pubKey2, pubKey3}
+	err = s.verifier.VerifyBatch(pubKeys, [][]byte{msg1, msg1, msg2}, [][]byte{sig1, sig2, sig3})
+	require.NoError(t, err)
+
+	err = s.verifier.VerifyBatch(pubKeys, [][]byte{msg1, msg1, msg1}, [][]byte{sig1, sig2, sig3})
+	require.Error(t, err)
+
+	err = s.verifier.VerifyBatch(pubKeys, [][]byte{msg1, msg1, msg2}, [][]byte{sig2, sig1, sig3})
+	require.Error(t, err)
+
+	err = s.verifier.VerifyBatch(pubKeys, [][]byte{msg1, msg1, msg2}, [][]byte{sig1, sig2, nil})
+	require.Error(t, err)
+
+	err = s.verifier.VerifyBatch(pubKeys, [][]byte{msg1, msg1}, [][]byte{sig1, sig2, sig3})
+	require.Error(t, err)
+}
+
 func (s *SigningTestSuite) TestAggregateAndVerify() {
 	ctx := context.Background()
 	t := s.Suite.T()
@@ -147,3 +184,44 @@ func (s *SigningTestSuite) TestAggregateAndVerify() {
 		require.NoError(t, err)
 	})
 }
+
+func (s *SigningTestSuite) TestAggregatePublicKey() {
+	ctx := context.Background()
+	t := s.Suite.T()
+	msg := []byte("test message")
+	pubKey1, signer1 := s.signerTestSubject(s.T())
+	pubKey2, signer2 := s.signerTestSubject(s.T())
+	pubKey3, signer3 := s.signerTestSubject(s.T())
+
+	aggregator, err := s.verifier.Aggregate([]gpbft.PubKey{pubKey1, pubKey2})
+	require.NoError(t, err)
+	batchAggregator, ok := aggregator.(gpbft.BatchAggregate)
+	if !ok {
+		t.Skip("aggregate does not support batch verification")
+	}
+
+	mask := []int{0, 1}
+	sigs := make([][]byte, len(mask))
+	sigs[0], err = signer1.Sign(ctx, pubKey1, msg)
+	require.NoError(t, err)
+	sigs[1], err = signer2.Sign(ctx, pubKey2, msg)
+	require.NoError(t, err)
+	aggSig, err := aggregator.Aggregate(mask, sigs)
+	require.NoError(t, err)
+
+	aggPubKey, err := batchAggregator.AggregatePublicKey(mask)
+	require.NoError(t, err)
+	require.NoError(t, s.verifier.Verify(aggPubKey, msg, aggSig))
+
+	// The aggregate signature can be verified in a batch along with other signatures.
+	otherMsg := []byte("other test message")
+	sig3, err := signer3.Sign(ctx, pubKey3, otherMsg)
+	require.NoError(t, err)
+	err = s.verifier.VerifyBatch([]gpbft.PubKey{aggPubKey, pubKey3}, [][]byte{msg, otherMsg}, [][]byte{aggSig, sig3})
+	require.NoError(t, err)
+
+	// The aggregate public key of other signers does not verify it.
+	aggPubKey, err = batchAggregator.AggregatePublicKey(mask[:1])
+	require.NoError(t, err)
+	require.Error(t, s.verifier.Verify(aggPubKey, msg, aggSig))
+}